@@ -1,22 +1,34 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rihow/FamilyDashboard/internal/cache"
 	"github.com/rihow/FamilyDashboard/internal/config"
 	httproutes "github.com/rihow/FamilyDashboard/internal/http"
+	"github.com/rihow/FamilyDashboard/internal/scheduler"
+	"github.com/rihow/FamilyDashboard/internal/services/jma"
 	"github.com/rihow/FamilyDashboard/internal/services/nextcloud"
 	"github.com/rihow/FamilyDashboard/internal/services/weather"
 	"github.com/rihow/FamilyDashboard/internal/status"
 )
 
 // main はGinサーバーのエントリーポイントなのです。
-// 設定読み込み → APIルーティング → 静的ファイル配信 → サーバー起動 の順で処理するます。
+// 設定読み込み → バックグラウンド更新 → APIルーティング → 静的ファイル配信 → サーバー起動 の順で処理するます。
+// SIGINT/SIGTERM を受けたらバックグラウンド更新とサーバーを順に止めるのです。
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// 設定ファイルを読み込むます。
 	configFilePath := "./data/settings.json"
 	cfg, err := config.LoadConfig(configFilePath)
@@ -28,6 +40,8 @@ func main() {
 	fmt.Printf("   天気更新間隔: %v\n", cfg.GetRefreshInterval("weather"))
 	fmt.Printf("   カレンダー更新間隔: %v\n", cfg.GetRefreshInterval("calendar"))
 	fmt.Printf("   タスク更新間隔: %v\n", cfg.GetRefreshInterval("tasks"))
+	fmt.Printf("   大気質・花粉更新間隔: %v\n", cfg.GetRefreshInterval("airQuality"))
+	fmt.Printf("   天気の履歴の保持日数: %d日\n", cfg.History.GetRetentionDays())
 
 	// キャッシュを初期化するます
 	fc := cache.New("./data/cache")
@@ -35,18 +49,56 @@ func main() {
 	// エラー状態ストアを初期化するます
 	errorStore := status.NewErrorStore()
 
-	// 天気APIクライアントを初期化するます
-	weatherClient := weather.NewClient(fc, "http://localhost:8080")
+	// 天気APIクライアントを初期化するます（キャッシュの有効期限は更新間隔に合わせるのです）
+	weatherClient := weather.NewClient(fc, "http://localhost:8080", weather.Options{
+		WeatherTTL:    cfg.GetRefreshInterval("weather"),
+		AirQualityTTL: cfg.GetRefreshInterval("airQuality"),
+	})
+	weatherProviders, err := weather.NewProviders(cfg.Weather)
+	if err != nil {
+		log.Fatalf("天気プロバイダの設定が不正です: %v", err)
+	}
+	weatherClient.SetProviders(weatherProviders...)
+	weatherClient.SetHistory(weather.NewHistoryStore("./data/history", cfg.History.GetRetentionDays()))
+	for i, provider := range weatherProviders {
+		fmt.Printf("   天気プロバイダ %d: %s\n", i+1, provider.Name())
+	}
+	for _, location := range cfg.GetLocations() {
+		fmt.Printf("   地点 %s: %s（%s, %s）\n", location.ID, location.Name, location.CityName, location.Country)
+		if lat, lon, ok := location.Coordinates(); ok {
+			// 明示された緯度経度はジオコーディングより優先するます
+			weatherClient.SetCoordinates(location.CityName, location.Country, lat, lon)
+			fmt.Printf("     座標（設定値）: %.4f, %.4f\n", lat, lon)
+		}
+	}
+
+	// 気象庁の注意報・警報クライアントを初期化するます（区域コード未設定なら使わないのです）
+	alertsClient := jma.NewClient(fc)
+	if cfg.Alerts.Enabled() {
+		fmt.Printf("   注意報・警報の区域: %s（府県予報区 %s）\n", cfg.Alerts.AreaCode, cfg.Alerts.GetOfficeCode())
+	}
 
-	// Nextcloud CalDAV/WebDAV クライアントを初期化するます
+	// Nextcloud と caldavAccounts の CalDAV クライアントを初期化するます
 	nextcloudClient, err := nextcloud.NewClient(fc, cfg)
 	if err != nil {
-		fmt.Printf("⚠️ Nextcloud クライアント初期化エラー: %v\n", err)
+		fmt.Printf("⚠️ CalDAV クライアント初期化エラー: %v\n", err)
 		// エラーでも継続する（設定不足の場合はダミーデータで動作）
 	} else {
-		fmt.Printf("✨ Nextcloud クライアントの初期化成功\n")
+		// 前回までに取得したリソースを残して、差分だけを同期するます
+		nextcloudClient.SetObjectStore(nextcloud.NewObjectStore("./data/caldav"))
+		// カレンダー・タスクリストごとの取得エラーを /api/status に出すます
+		nextcloudClient.SetErrorStore(errorStore)
+		fmt.Printf("✨ CalDAV クライアントの初期化成功\n")
 	}
 
+	// バックグラウンド更新を開始するます（ハンドラーはキャッシュを即座に返せるようになるのです）
+	refresher := newRefresher(cfg, errorStore, weatherClient, alertsClient, nextcloudClient)
+	refresher.Start(ctx)
+
+	// キャッシュの変更を /api/events で配信するハブを起動するます
+	eventHub := httproutes.NewEventHub(fc, cfg, errorStore)
+	go eventHub.Run(ctx)
+
 	// Ginルーターを初期化
 	router := gin.Default()
 
@@ -55,8 +107,10 @@ func main() {
 		ctx.Set("config", cfg)
 		ctx.Set("cache", fc)
 		ctx.Set("weather", weatherClient)
+		ctx.Set("alerts", alertsClient)
 		ctx.Set("nextcloud", nextcloudClient)
 		ctx.Set("errorStore", errorStore)
+		ctx.Set("events", eventHub)
 		ctx.Next()
 	})
 
@@ -91,8 +145,110 @@ func main() {
 
 	// 既定ポート8080で起動するます。
 	port := ":8080"
-	fmt.Printf("🚀 サーバー起動するます！ http://localhost%s\n", port)
-	if err := router.Run(port); err != nil {
+	server := &http.Server{
+		Addr:    port,
+		Handler: router,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		fmt.Printf("🚀 サーバー起動するます！ http://localhost%s\n", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		refresher.Stop()
 		log.Fatalf("サーバー起動に失敗しました: %v", err)
+	case <-ctx.Done():
+	}
+
+	fmt.Println("🛑 シャットダウンするます...")
+	refresher.Stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("サーバー停止に失敗しました: %v", err)
 	}
 }
+
+// newRefresher は天気・大気質・注意報・カレンダー・タスクの定期更新ジョブを登録したスケジューラーを作るます。
+// 天気・大気質は地点ごとにジョブを分けて、地点ごとに別々のキャッシュを更新するのです。
+// 区域コードが未設定なら注意報・警報の更新を、Nextcloud クライアントが無い場合はカレンダー・タスクの更新を登録しないのです。
+func newRefresher(cfg *config.Config, errorStore *status.ErrorStore, weatherClient *weather.Client, alertsClient *jma.Client, nextcloudClient *nextcloud.Client) *scheduler.Scheduler {
+	refresher := scheduler.New(errorStore)
+
+	locations := cfg.GetLocations()
+	for i, location := range locations {
+		cityName := location.CityName
+		if cityName == "" {
+			cityName = "姫路市" // デフォルト都市
+		}
+		country := location.Country
+		if country == "" {
+			country = "JP" // デフォルト国コード
+		}
+
+		// 既定の地点（先頭）は従来どおりのソース名、それ以外は "weather:<地点ID>" なのです
+		suffix := ""
+		if i > 0 {
+			suffix = ":" + location.ID
+		}
+
+		refresher.Add(scheduler.Job{
+			Source:   "weather" + suffix,
+			Interval: cfg.GetRefreshInterval("weather"),
+			Refresh: func(ctx context.Context) error {
+				_, err := weatherClient.RefreshWeather(ctx, cityName, country)
+				return err
+			},
+		})
+
+		refresher.Add(scheduler.Job{
+			Source:   "airQuality" + suffix,
+			Interval: cfg.GetRefreshInterval("airQuality"),
+			Refresh: func(ctx context.Context) error {
+				_, err := weatherClient.RefreshAirQuality(ctx, cityName, country)
+				return err
+			},
+		})
+	}
+
+	if cfg.Alerts.Enabled() {
+		refresher.Add(scheduler.Job{
+			Source:   "alerts",
+			Interval: cfg.GetRefreshInterval("weather"),
+			Refresh: func(ctx context.Context) error {
+				_, err := alertsClient.RefreshAlerts(ctx, cfg.Alerts.GetOfficeCode(), cfg.Alerts.AreaCode)
+				return err
+			},
+		})
+	}
+
+	if nextcloudClient == nil {
+		return refresher
+	}
+
+	refresher.Add(scheduler.Job{
+		Source:   "calendar",
+		Interval: cfg.GetRefreshInterval("calendar"),
+		Refresh: func(ctx context.Context) error {
+			// 既定の表示範囲（今日から defaultDays 日分）を更新するます
+			_, err := nextcloudClient.RefreshCalendarEvents(ctx, time.Now(), cfg.GetCalendarDays())
+			return err
+		},
+	})
+	refresher.Add(scheduler.Job{
+		Source:   "tasks",
+		Interval: cfg.GetRefreshInterval("tasks"),
+		Refresh: func(ctx context.Context) error {
+			_, err := nextcloudClient.RefreshTaskItems(ctx)
+			return err
+		},
+	})
+
+	return refresher
+}
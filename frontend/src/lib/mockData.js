@@ -233,18 +233,29 @@ function buildWeather() {
   }
 
   return {
+    lang: 'ja-hiragana',
+    units: { temperature: '℃', windSpeed: 'm/s', precipitation: 'mm' },
     location: '姫路市',
     current: {
       temperature: 12.3,
+      feelsLike: 10.8,
       condition: 'くもり',
       icon: '03d',
+      isDay: true,
       humidity: 62,
       windSpeed: 2.4,
+      windDirection: 315,
+      windGust: 5.1,
+      pressure: 1016.2,
+      uvIndex: 2.1,
     },
     today: {
       maxTemp: 14.0,
       minTemp: 7.2,
       summary: 'くもりときどきはれ',
+      sunrise: `${formatTokyoYmd(base)}T06:12:00+09:00`,
+      sunset: `${formatTokyoYmd(base)}T17:21:00+09:00`,
+      uvIndexMax: 4.3,
     },
     precipSlots: [
       { time: '06:00', probability: 10, icon: '01d' },
@@ -263,6 +274,17 @@ function buildWeather() {
         title: '大雨特別警報',
       },
     ],
+    airQuality: {
+      time: new Date().toISOString(),
+      pm25: 14.2,
+      pm10: 22.5,
+      aqi: 55,
+      category: 'ふつう',
+      pollen: [],
+    },
+    advice: [
+      { type: 'umbrella', message: 'かさをもっていこう', reason: '18じごろ あめのかくりつ60%', level: 'info' },
+    ],
   };
 }
 
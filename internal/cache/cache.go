@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"os"
@@ -31,6 +33,12 @@ func New(dir string) *FileCache {
 	}
 }
 
+// SetClock はキャッシュの時計を差し替えるのです。
+// 保存時刻（FetchedAt）と期限切れの判定の両方に使うので、テストで時間を進めるために使うのです。
+func (fc *FileCache) SetClock(clock func() time.Time) {
+	fc.clock = clock
+}
+
 // Write はペイロードを保存して、保存したEntryを返すのです。
 func (fc *FileCache) Write(key string, payload any, meta map[string]string) (Entry, error) {
 	if fc == nil {
@@ -78,6 +86,7 @@ func (fc *FileCache) Write(key string, payload any, meta map[string]string) (Ent
 		_ = os.Remove(tmpFile.Name())
 		return Entry{}, err
 	}
+	fc.removeLegacyFile(key)
 
 	return entry, nil
 }
@@ -90,6 +99,9 @@ func (fc *FileCache) Read(key string, ttl time.Duration) (Entry, bool, bool, err
 
 	path := fc.filePath(key)
 	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && fc.migrateLegacyFile(key) {
+		data, err = os.ReadFile(path)
+	}
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return Entry{}, false, false, nil
@@ -115,6 +127,17 @@ func (fc *FileCache) Read(key string, ttl time.Duration) (Entry, bool, bool, err
 	return entry, true, stale, nil
 }
 
+// Age はエントリを保存してからの経過時間を返すのです。
+// 期限切れのあとも古いキャッシュを返してよい時間（stale-while-revalidate）の判定に使うのです。
+// 保存時刻が読めない場合は ok=false なのです。
+func (fc *FileCache) Age(entry Entry) (time.Duration, bool) {
+	fetchedAt, err := time.Parse(time.RFC3339, entry.FetchedAt)
+	if err != nil {
+		return 0, false
+	}
+	return fc.clock().Sub(fetchedAt), true
+}
+
 // ReadPayload はキャッシュを読み取り、payloadを型に詰めるのです。
 func (fc *FileCache) ReadPayload(key string, ttl time.Duration, out any) (Entry, bool, bool, error) {
 	entry, ok, stale, err := fc.Read(key, ttl)
@@ -143,6 +166,29 @@ func (fc *FileCache) Delete(key string) error {
 	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
+	fc.removeLegacyFile(key)
+
+	return nil
+}
+
+// DeletePrefix は指定した接頭辞で始まるキーのキャッシュをまとめて削除するのです。
+// 表示範囲ごとにキーが分かれているキャッシュを一括で無効化するときに使うのです。
+// 接頭辞は英数字と - _ だけで指定する必要があるのです。
+func (fc *FileCache) DeletePrefix(prefix string) error {
+	if fc == nil {
+		return errors.New("cache is nil")
+	}
+
+	matches, err := filepath.Glob(filepath.Join(fc.dir, safeFileName(prefix)+"*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range matches {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -151,17 +197,66 @@ func (fc *FileCache) filePath(key string) string {
 	return filepath.Join(fc.dir, safeFileName(key)+".json")
 }
 
+// legacyFilePath は以前のファイル名（ハッシュを付けていない名前）のパスを返すのです。
+// 置き換えの無いキーは今と同じ名前なので ok=false なのです。
+func (fc *FileCache) legacyFilePath(key string) (string, bool) {
+	clean, replaced := replaceUnsafeRunes(key)
+	if !replaced {
+		return "", false
+	}
+	return filepath.Join(fc.dir, clean+".json"), true
+}
+
+// migrateLegacyFile は以前のファイル名で保存されたキャッシュを今のファイル名に移すのです。
+// 更新前に保存したキャッシュ（weather:JP:姫路市 など）を捨てずに使い続けるためなのです。
+// 以前の名前は複数のキーで同じになることがあったけれど、そのころは1つのファイルを共有していたので、最初に読んだキーが引き継ぐのです。
+func (fc *FileCache) migrateLegacyFile(key string) bool {
+	legacyPath, ok := fc.legacyFilePath(key)
+	if !ok {
+		return false
+	}
+	return os.Rename(legacyPath, fc.filePath(key)) == nil
+}
+
+// removeLegacyFile は以前のファイル名のキャッシュを消すのです（書き込み・削除のときに残さないため）。
+func (fc *FileCache) removeLegacyFile(key string) {
+	if legacyPath, ok := fc.legacyFilePath(key); ok {
+		_ = os.Remove(legacyPath)
+	}
+}
+
 func safeFilePrefix(key string) string {
 	return safeFileName(key) + "-*.tmp"
 }
 
+// SafeFileName はキーをファイル名に使える文字だけに置き換えるのです（拡張子なし）。
+// キャッシュ以外の保存先（天気の履歴など）でも同じ規則のファイル名にするために使うのです。
+func SafeFileName(key string) string {
+	return safeFileName(key)
+}
+
+// safeFileName はキーをファイル名に使える文字だけに置き換えるのです。
+// 置き換えが発生したキー（日本語の都市名など）は、別のキーと同じ名前にならないように
+// 元のキーのハッシュを末尾に付けるのです。
 func safeFileName(key string) string {
+	clean, replaced := replaceUnsafeRunes(key)
+	if replaced {
+		sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+		clean += "_" + hex.EncodeToString(sum[:4])
+	}
+	return clean
+}
+
+// replaceUnsafeRunes は英数字と - _ 以外を _ に置き換えるのです。
+// ハッシュを付ける前のこの名前は、以前のキャッシュのファイル名でもあるのです。
+func replaceUnsafeRunes(key string) (string, bool) {
 	key = strings.TrimSpace(key)
 	if key == "" {
-		return "cache"
+		return "cache", false
 	}
 
 	var builder strings.Builder
+	replaced := false
 	for _, r := range key {
 		if r >= 'a' && r <= 'z' {
 			builder.WriteRune(r)
@@ -180,13 +275,10 @@ func safeFileName(key string) string {
 			continue
 		}
 		builder.WriteRune('_')
+		replaced = true
 	}
 
-	clean := builder.String()
-	if clean == "" {
-		return "cache"
-	}
-	return clean
+	return builder.String(), replaced
 }
 
 var tokyoLocationOnce sync.Once
@@ -78,6 +78,33 @@ func TestReadStaleByTTL(t *testing.T) {
 	}
 }
 
+func TestReadStaleWithClock(t *testing.T) {
+	fc := New(t.TempDir())
+	now := time.Date(2025, 7, 15, 9, 0, 0, 0, time.UTC)
+	fc.SetClock(func() time.Time { return now })
+
+	if _, err := fc.Write("clock-key", samplePayload{Name: "gamma", Val: 1}, nil); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	now = now.Add(5 * time.Minute)
+	entry, ok, stale, err := fc.Read("clock-key", 5*time.Minute)
+	if err != nil || !ok || stale {
+		t.Fatalf("ttl ちょうどは期限内: ok=%v stale=%v err=%v", ok, stale, err)
+	}
+	if age, ok := fc.Age(entry); !ok || age != 5*time.Minute {
+		t.Fatalf("age = %v (ok=%v)", age, ok)
+	}
+
+	now = now.Add(time.Second)
+	if _, _, stale, _ := fc.Read("clock-key", 5*time.Minute); !stale {
+		t.Fatalf("ttl を過ぎたら stale")
+	}
+	if _, ok := fc.Age(Entry{FetchedAt: "broken"}); ok {
+		t.Fatalf("壊れた保存時刻で ok")
+	}
+}
+
 func TestDelete(t *testing.T) {
 	dir := t.TempDir()
 	fc := New(dir)
@@ -99,6 +126,55 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestSafeFileNameDoesNotCollide(t *testing.T) {
+	dir := t.TempDir()
+	fc := New(dir)
+
+	// 日本語は置き換え対象なので、同じ文字数の都市名でも別ファイルになる必要があるのです
+	if _, err := fc.Write("geocode_姫路市_JP", samplePayload{Name: "himeji", Val: 1}, nil); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := fc.Write("geocode_松江市_JP", samplePayload{Name: "matsue", Val: 2}, nil); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var got samplePayload
+	if _, _, _, err := fc.ReadPayload("geocode_姫路市_JP", time.Minute, &got); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got.Name != "himeji" {
+		t.Fatalf("payload name = %s, want himeji", got.Name)
+	}
+
+	if safeFileName("weather_JP_7d") != "weather_JP_7d" {
+		t.Fatalf("安全なキーは変換しないのです: %s", safeFileName("weather_JP_7d"))
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	dir := t.TempDir()
+	fc := New(dir)
+
+	for _, key := range []string{"calendar_20260301_7d", "calendar_20260301_3d", "tasks_all"} {
+		if _, err := fc.Write(key, samplePayload{Name: key, Val: 1}, nil); err != nil {
+			t.Fatalf("write %s: %v", key, err)
+		}
+	}
+
+	if err := fc.DeletePrefix("calendar_"); err != nil {
+		t.Fatalf("delete prefix: %v", err)
+	}
+
+	for _, key := range []string{"calendar_20260301_7d", "calendar_20260301_3d"} {
+		if _, ok, _, _ := fc.Read(key, time.Minute); ok {
+			t.Fatalf("cache %s still exists", key)
+		}
+	}
+	if _, ok, _, _ := fc.Read("tasks_all", time.Minute); !ok {
+		t.Fatalf("unrelated cache was deleted")
+	}
+}
+
 func TestWriteCreatesSafeFileName(t *testing.T) {
 	dir := t.TempDir()
 	fc := New(dir)
@@ -121,3 +197,46 @@ func TestWriteCreatesSafeFileName(t *testing.T) {
 		t.Fatalf("unexpected file extension: %s", name)
 	}
 }
+
+func TestSafeFileNameIsStable(t *testing.T) {
+	cases := map[string]string{
+		"weather:JP:姫路市": "weather_JP_____711a5b90",
+		"sample-key":     "sample-key",
+	}
+	for key, want := range cases {
+		if got := safeFileName(key); got != want {
+			t.Fatalf("safeFileName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestReadMigratesLegacyFileName(t *testing.T) {
+	dir := t.TempDir()
+	fc := New(dir)
+
+	// ハッシュを付ける前の名前で保存されたキャッシュなのです
+	key := "weather:JP:姫路市"
+	legacyPath := filepath.Join(dir, "weather_JP____.json")
+	legacy := `{"fetchedAt":"2024-01-01T00:00:00Z","payload":{"name":"legacy","val":7}}`
+	if err := os.WriteFile(legacyPath, []byte(legacy), 0o644); err != nil {
+		t.Fatalf("write legacy: %v", err)
+	}
+
+	var out samplePayload
+	_, ok, _, err := fc.ReadPayload(key, 0, &out)
+	if err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	if !ok {
+		t.Fatalf("legacy cache not found")
+	}
+	if out.Name != "legacy" || out.Val != 7 {
+		t.Fatalf("unexpected payload: %+v", out)
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Fatalf("legacy file still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "weather_JP_____711a5b90.json")); err != nil {
+		t.Fatalf("migrated file not found: %v", err)
+	}
+}
@@ -3,23 +3,48 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // RefreshIntervals はデータソース別の更新間隔を定義する構造体なのです。
 type RefreshIntervals struct {
-	WeatherSec  int `json:"weatherSec"`  // 天気APIの更新間隔（秒）
-	CalendarSec int `json:"calendarSec"` // カレンダーの更新間隔（秒）
-	TasksSec    int `json:"tasksSec"`    // タスクの更新間隔（秒）
+	WeatherSec    int `json:"weatherSec"`    // 天気APIの更新間隔（秒）
+	CalendarSec   int `json:"calendarSec"`   // カレンダーの更新間隔（秒）
+	TasksSec      int `json:"tasksSec"`      // タスクの更新間隔（秒）
+	AirQualitySec int `json:"airQualitySec"` // 大気質・花粉の更新間隔（秒、省略時1時間）
 }
 
+// DefaultAirQualitySec は大気質・花粉の更新間隔の既定値なのです。
+// Open-Meteo の大気質データは1時間ごとなので、それより短くしても変わらないのです。
+const DefaultAirQualitySec = 3600
+
 // Location はジオグラフィック位置情報を定義する構造体なのです。
+// Latitude/Longitude を両方指定した場合はジオコーディングより優先するのです。
 type Location struct {
-	CityName string `json:"cityName"` // 都市名（例：姫路市）
-	Country  string `json:"country"`  // 国コード（例：JP）
+	ID        string   `json:"id,omitempty"`        // 地点ID（/api/weather?location=<id> で指定、locations では必須）
+	Name      string   `json:"name,omitempty"`      // 表示名（例：おうち、省略時は都市名）
+	CityName  string   `json:"cityName"`            // 都市名（例：姫路市）
+	Country   string   `json:"country"`             // 国コード（例：JP）
+	Latitude  *float64 `json:"latitude,omitempty"`  // 緯度（省略可）
+	Longitude *float64 `json:"longitude,omitempty"` // 経度（省略可）
+}
+
+// Coordinates は明示指定された緯度経度を返すます。
+// 両方が指定されていない場合は ok=false なのです。
+func (l Location) Coordinates() (lat, lon float64, ok bool) {
+	if l.Latitude == nil || l.Longitude == nil {
+		return 0, 0, false
+	}
+	return *l.Latitude, *l.Longitude, true
 }
 
+// DefaultLocationID は location（1地点のみの設定）の地点IDの既定値なのです。
+const DefaultLocationID = "home"
+
 // Nextcloud はNextcloud CalDAV/WebDAVの認証・設定を定義する構造体なのです。
 type Nextcloud struct {
 	ServerURL     string   `json:"serverUrl"`     // NextcloudサーバーURL（例: https://nextcloud.example.com）
@@ -27,28 +52,427 @@ type Nextcloud struct {
 	Password      string   `json:"password"`      // パスワード または アプリパスワード
 	CalendarNames []string `json:"calendarNames"` // カレンダー名のリスト（複数カレンダー対応）
 	TaskListNames []string `json:"taskListNames"` // タスクリスト名のリスト（複数タスクリスト対応）
+
+	MaxConcurrency       int `json:"maxConcurrency"`       // 同時に取得するカレンダー・タスクリストの数（省略時4、最大 MaxNextcloudConcurrency）
+	CollectionTimeoutSec int `json:"collectionTimeoutSec"` // カレンダー・タスクリスト1つあたりの取得のタイムアウト秒数（省略時20秒）
+}
+
+// DefaultNextcloudConcurrency は同時に取得するカレンダー・タスクリストの数の既定値なのです。
+const DefaultNextcloudConcurrency = 4
+
+// MaxNextcloudConcurrency は同時に取得するカレンダー・タスクリストの数の上限なのです（サーバーに負荷をかけすぎないように）。
+const MaxNextcloudConcurrency = 16
+
+// DefaultCollectionTimeout はカレンダー・タスクリスト1つあたりの取得のタイムアウトの既定値なのです。
+const DefaultCollectionTimeout = 20 * time.Second
+
+// GetMaxConcurrency は同時に取得するカレンダー・タスクリストの数を返すます。
+func (n Nextcloud) GetMaxConcurrency() int {
+	if n.MaxConcurrency <= 0 {
+		return DefaultNextcloudConcurrency
+	}
+	return n.MaxConcurrency
+}
+
+// GetCollectionTimeout はカレンダー・タスクリスト1つあたりの取得のタイムアウトを返すます。
+func (n Nextcloud) GetCollectionTimeout() time.Duration {
+	if n.CollectionTimeoutSec <= 0 {
+		return DefaultCollectionTimeout
+	}
+	return time.Duration(n.CollectionTimeoutSec) * time.Second
+}
+
+// CalDAV のプリセットなのです。
+const (
+	CalDAVPresetGeneric   = "caldav"    // .well-known/caldav（RFC 6764）からカレンダーを検出するます
+	CalDAVPresetNextcloud = "nextcloud" // /remote.php/dav/ から検出し、見つからない名前は /remote.php/dav/calendars/USER/NAME/ にするます
+)
+
+// NextcloudAccountID は nextcloud の設定から作る CalDAV アカウントのIDなのです。
+const NextcloudAccountID = "nextcloud"
+
+// CalDAVAccount は CalDAV アカウント1件の設定を定義する構造体なのです。
+// Radicale・Baïkal・Fastmail・iCloud など Nextcloud 以外のサーバーにも接続できるのです。
+type CalDAVAccount struct {
+	ID            string   `json:"id"`            // アカウントID（英小文字・数字・- _、必須・重複不可）。カレンダー名は "ID/名前" になるのです
+	Preset        string   `json:"preset"`        // caldav（既定）/ nextcloud
+	ServerURL     string   `json:"serverUrl"`     // サーバーURL（例: https://caldav.fastmail.com）。DAV のルートやコレクションのURLでもよいのです
+	Username      string   `json:"username"`      // ユーザー名（空なら認証しないのです）
+	Password      string   `json:"password"`      // パスワード または アプリパスワード
+	CalendarNames []string `json:"calendarNames"` // カレンダー（名前・表示名・URL）のリスト
+	TaskListNames []string `json:"taskListNames"` // タスクリスト（名前・表示名・URL）のリスト
+	TrustedHosts  []string `json:"trustedHosts"`  // serverUrl のほかに認証情報を送ってよいホスト（例: p01-caldav.icloud.com、省略時はなし）
+}
+
+// GetPreset はプリセットを返すます（省略時は caldav）。
+func (a CalDAVAccount) GetPreset() string {
+	if a.Preset == "" {
+		return CalDAVPresetGeneric
+	}
+	return a.Preset
+}
+
+// Calendar はカレンダー表示範囲の設定を定義する構造体なのです。
+type Calendar struct {
+	DefaultDays   int                    `json:"defaultDays"`   // /api/calendar の既定表示日数（省略時7日）
+	Subscriptions []CalendarSubscription `json:"subscriptions"` // 読み取り専用の ICS 購読カレンダー（祝日・学校行事・ごみ収集日など）
+}
+
+// CalendarSubscription は ICS 購読カレンダー1件の設定を定義する構造体なのです。
+// URL の .ics を定期的にダウンロードして、CalDAV のカレンダーと一緒に /api/calendar に表示するます。
+type CalendarSubscription struct {
+	Name       string `json:"name"`       // カレンダー名（必須・重複不可。イベントの calendar とエラーのソース名に使うのです）
+	URL        string `json:"url"`        // ICS の URL（http(s):// か webcal://）
+	Color      string `json:"color"`      // 色（#RRGGBB、省略時は ICS の X-APPLE-CALENDAR-COLOR）
+	RefreshSec int    `json:"refreshSec"` // ダウンロードの間隔秒数（省略時 DefaultSubscriptionRefreshSec）
+}
+
+// DefaultSubscriptionRefreshSec は ICS 購読カレンダーをダウンロードする間隔の既定値（秒）なのです。
+// 祝日やごみ収集日はめったに変わらないので、カレンダーの更新間隔より長くしているのです。
+const DefaultSubscriptionRefreshSec = 3600
+
+// GetRefreshInterval は ICS をダウンロードする間隔を返すます。
+func (s CalendarSubscription) GetRefreshInterval() time.Duration {
+	if s.RefreshSec <= 0 {
+		return DefaultSubscriptionRefreshSec * time.Second
+	}
+	return time.Duration(s.RefreshSec) * time.Second
+}
+
+// GetURL はダウンロードに使う URL を返すます（webcal:// は https:// にするのです）。
+func (s CalendarSubscription) GetURL() string {
+	if rest, ok := strings.CutPrefix(s.URL, "webcal://"); ok {
+		return "https://" + rest
+	}
+	return s.URL
+}
+
+// DefaultCalendarDays はカレンダー表示日数の既定値なのです。
+const DefaultCalendarDays = 7
+
+// MaxCalendarDays はカレンダー表示日数の上限なのです。
+const MaxCalendarDays = 31
+
+// WeatherProvider は天気プロバイダ1件の設定を定義する構造体なのです。
+type WeatherProvider struct {
+	Provider string `json:"provider"` // 天気プロバイダ（openmeteo / openweathermap / metno、省略時 openmeteo）
+	ApiKey   string `json:"apiKey"`   // APIキー（openweathermap のみ必須）
+	BaseUrl  string `json:"baseUrl"`  // ベースURL（省略時は各プロバイダの既定値）
 }
 
 // Weather は天気APIの設定を定義する構造体なのです。
+// providers を指定した場合は先頭から順に試し、失敗したら次のプロバイダに切り替えるのです。
+// 指定しない場合は provider / apiKey / baseUrl の1件だけを使うます。
 type Weather struct {
-	Provider string `json:"provider"` // 天気プロバイダ（例：openweathermap）
-	ApiKey   string `json:"apiKey"`   // APIキー
-	BaseUrl  string `json:"baseUrl"`  // ベースURL
+	WeatherProvider
+	Providers []WeatherProvider `json:"providers"` // フェイルオーバー順のプロバイダ一覧（省略可）
+	Locale    string            `json:"locale"`    // 天候などの表示言語（ja-hiragana / ja-kanji / en、省略時 ja-hiragana）
+	Units     Units             `json:"units"`     // 表示する単位
+}
+
+// 表示言語（weather.locale や ?lang= に指定する値）なのです。
+const (
+	LocaleJaHiragana = "ja-hiragana" // ひらがな（子ども向け）
+	LocaleJaKanji    = "ja-kanji"    // 漢字かな交じり
+	LocaleEn         = "en"          // 英語
+)
+
+// Locales は対応している表示言語の一覧なのです。
+var Locales = []string{LocaleJaHiragana, LocaleJaKanji, LocaleEn}
+
+// GetLocale は表示言語を返すます（省略時は ja-hiragana）。
+func (w Weather) GetLocale() string {
+	if w.Locale == "" {
+		return LocaleJaHiragana
+	}
+	return w.Locale
+}
+
+// IsLocale は対応している表示言語かを判定するます。
+func IsLocale(locale string) bool {
+	for _, known := range Locales {
+		if locale == known {
+			return true
+		}
+	}
+	return false
+}
+
+// 単位系と風速の単位（weather.units に指定する値）なのです。
+const (
+	UnitsMetric   = "metric"   // ℃・mm
+	UnitsImperial = "imperial" // ℉・inch
+	WindSpeedMS   = "ms"       // m/s
+	WindSpeedKMH  = "kmh"      // km/h
+	WindSpeedMPH  = "mph"      // mph
+)
+
+// Units は表示する単位を定義する構造体なのです。
+// プロバイダからは常に℃・m/s・mm で取得してキャッシュし、レスポンスを返すときに変換するのです。
+// アドバイスのしきい値（advice.heatTemp など）は単位の設定にかかわらず℃・m/s なのです。
+type Units struct {
+	System    string `json:"system"`    // 単位系（metric / imperial、省略時 metric）
+	WindSpeed string `json:"windSpeed"` // 風速の単位（ms / kmh / mph、省略時は metric なら ms、imperial なら mph）
+}
+
+// GetSystem は単位系を返すます。
+func (u Units) GetSystem() string {
+	if u.System == "" {
+		return UnitsMetric
+	}
+	return u.System
+}
+
+// GetWindSpeed は風速の単位を返すます。
+func (u Units) GetWindSpeed() string {
+	if u.WindSpeed != "" {
+		return u.WindSpeed
+	}
+	if u.GetSystem() == UnitsImperial {
+		return WindSpeedMPH
+	}
+	return WindSpeedMS
+}
+
+// GetProviders は試す順番に並んだ天気プロバイダの設定を返すます。
+func (w Weather) GetProviders() []WeatherProvider {
+	if len(w.Providers) > 0 {
+		return w.Providers
+	}
+	return []WeatherProvider{w.WeatherProvider}
+}
+
+// Alerts は気象庁の注意報・警報の取得設定を定義する構造体なのです。
+// AreaCode が空の場合は注意報・警報を取得しないのです。
+type Alerts struct {
+	AreaCode   string `json:"areaCode"`   // 気象庁の区域コード（一次細分区域 6桁 または 市町村 7桁、例：2820100）
+	OfficeCode string `json:"officeCode"` // 府県予報区コード（省略時は AreaCode の先頭2桁 + "0000"）
+}
+
+// Enabled は注意報・警報の取得が有効かを返すます。
+func (a Alerts) Enabled() bool {
+	return a.AreaCode != ""
+}
+
+// GetOfficeCode は府県予報区コードを返すます。
+// 未指定の場合は区域コードの都道府県部分から求めるのです（北海道・沖縄など一部の地域は明示が必要なのです）。
+func (a Alerts) GetOfficeCode() string {
+	if a.OfficeCode != "" {
+		return a.OfficeCode
+	}
+	if len(a.AreaCode) < 2 {
+		return ""
+	}
+	return a.AreaCode[:2] + "0000"
+}
+
+// History は天気の履歴（観測値の記録）の設定を定義する構造体なのです。
+type History struct {
+	RetentionDays int `json:"retentionDays"` // 記録を残す日数（省略時90日、最大 MaxHistoryRetentionDays 日）
+}
+
+// DefaultHistoryRetentionDays は天気の履歴を残す日数の既定値なのです。
+const DefaultHistoryRetentionDays = 90
+
+// MaxHistoryRetentionDays は天気の履歴を残す日数の上限なのです（ファイルが大きくなりすぎないように）。
+const MaxHistoryRetentionDays = 366
+
+// GetRetentionDays は天気の履歴を残す日数を返すます。
+func (h History) GetRetentionDays() int {
+	if h.RetentionDays <= 0 {
+		return DefaultHistoryRetentionDays
+	}
+	return h.RetentionDays
+}
+
+// アドバイスのルール名（advice.disabled に指定する値）なのです。
+const (
+	AdviceUmbrella = "umbrella" // 傘（通勤・通学の時間帯の降水確率）
+	AdviceLaundry  = "laundry"  // 洗濯物（日中の降水確率・降水量）
+	AdviceHeat     = "heat"     // 熱中症（最高気温・体感温度）
+	AdviceCold     = "cold"     // 防寒（最低気温）
+	AdviceUV       = "uv"       // 日焼け（最大UVインデックス）
+	AdviceWind     = "wind"     // 強風（風速・最大瞬間風速）
+)
+
+// AdviceRules はアドバイスのルール名の一覧なのです（表示順）。
+var AdviceRules = []string{AdviceUmbrella, AdviceLaundry, AdviceHeat, AdviceCold, AdviceUV, AdviceWind}
+
+// アドバイスの判定条件の既定値なのです。
+const (
+	DefaultUmbrellaPrecipProb = 50   // 傘の降水確率（%）
+	DefaultLaundryPrecipProb  = 30   // 部屋干しの降水確率（%）
+	DefaultHeatTemp           = 31.0 // 熱中症注意の気温（℃、気象庁の真夏日＋1℃）
+	DefaultColdTemp           = 5.0  // 防寒の最低気温（℃）
+	DefaultUVIndex            = 6.0  // 日焼け止めのUVインデックス（WHO の「強い」）
+	DefaultWindSpeed          = 10.0 // 強風の風速（m/s、気象庁の「やや強い風」）
+)
+
+// TimeWindow は1日の中の時間帯（"HH:MM"〜"HH:MM"、終了は含まない）なのです。
+type TimeWindow struct {
+	Start string `json:"start"` // 開始時刻（例："07:00"）
+	End   string `json:"end"`   // 終了時刻（例："09:00"）
+}
+
+// Minutes は時間帯を0時からの分で返すます。
+func (w TimeWindow) Minutes() (start, end int, err error) {
+	if start, err = parseClock(w.Start); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parseClock(w.End); err != nil {
+		return 0, 0, err
+	}
+	if start >= end {
+		return 0, 0, fmt.Errorf("開始 %s は終了 %s より前にしてください", w.Start, w.End)
+	}
+	return start, end, nil
+}
+
+// Advice は天気のアドバイス（傘・洗濯物・熱中症など）の判定条件を定義する構造体なのです。
+// 省略した項目（0 や空）は既定値を使うのです。
+type Advice struct {
+	Disabled           []string     `json:"disabled"`           // 使わないルール（"umbrella" "laundry" "heat" "cold" "uv" "wind"）
+	CommuteHours       []TimeWindow `json:"commuteHours"`       // 通勤・通学の時間帯（省略時 07:00〜09:00 と 17:00〜19:00）
+	UmbrellaPrecipProb int          `json:"umbrellaPrecipProb"` // 傘をすすめる降水確率（%、省略時50）
+	LaundryHours       *TimeWindow  `json:"laundryHours"`       // 洗濯物を外に干す時間帯（省略時 09:00〜15:00）
+	LaundryPrecipProb  int          `json:"laundryPrecipProb"`  // 部屋干しをすすめる降水確率（%、省略時30）
+	HeatTemp           float64      `json:"heatTemp"`           // 熱中症に注意する最高気温・体感温度（℃、省略時31）
+	ColdTemp           *float64     `json:"coldTemp"`           // 防寒をすすめる最低気温（℃、省略時5。0℃も指定できるようにポインタなのです）
+	UVIndex            float64      `json:"uvIndex"`            // 日焼け止めをすすめるUVインデックス（省略時6）
+	WindSpeed          float64      `json:"windSpeed"`          // 強風に注意する風速（m/s、省略時10）
+}
+
+// RuleEnabled はルールが有効かを返すます。
+func (a Advice) RuleEnabled(rule string) bool {
+	for _, disabled := range a.Disabled {
+		if disabled == rule {
+			return false
+		}
+	}
+	return true
+}
+
+// GetCommuteHours は通勤・通学の時間帯を返すます。
+func (a Advice) GetCommuteHours() []TimeWindow {
+	if len(a.CommuteHours) == 0 {
+		return []TimeWindow{{Start: "07:00", End: "09:00"}, {Start: "17:00", End: "19:00"}}
+	}
+	return a.CommuteHours
+}
+
+// GetUmbrellaPrecipProb は傘をすすめる降水確率を返すます。
+func (a Advice) GetUmbrellaPrecipProb() int {
+	if a.UmbrellaPrecipProb <= 0 {
+		return DefaultUmbrellaPrecipProb
+	}
+	return a.UmbrellaPrecipProb
+}
+
+// GetLaundryHours は洗濯物を外に干す時間帯を返すます。
+func (a Advice) GetLaundryHours() TimeWindow {
+	if a.LaundryHours == nil {
+		return TimeWindow{Start: "09:00", End: "15:00"}
+	}
+	return *a.LaundryHours
+}
+
+// GetLaundryPrecipProb は部屋干しをすすめる降水確率を返すます。
+func (a Advice) GetLaundryPrecipProb() int {
+	if a.LaundryPrecipProb <= 0 {
+		return DefaultLaundryPrecipProb
+	}
+	return a.LaundryPrecipProb
+}
+
+// GetHeatTemp は熱中症に注意する気温を返すます。
+func (a Advice) GetHeatTemp() float64 {
+	if a.HeatTemp == 0 {
+		return DefaultHeatTemp
+	}
+	return a.HeatTemp
+}
+
+// GetColdTemp は防寒をすすめる最低気温を返すます。
+func (a Advice) GetColdTemp() float64 {
+	if a.ColdTemp == nil {
+		return DefaultColdTemp
+	}
+	return *a.ColdTemp
+}
+
+// GetUVIndex は日焼け止めをすすめるUVインデックスを返すます。
+func (a Advice) GetUVIndex() float64 {
+	if a.UVIndex <= 0 {
+		return DefaultUVIndex
+	}
+	return a.UVIndex
+}
+
+// GetWindSpeed は強風に注意する風速を返すます。
+func (a Advice) GetWindSpeed() float64 {
+	if a.WindSpeed <= 0 {
+		return DefaultWindSpeed
+	}
+	return a.WindSpeed
+}
+
+// validate はアドバイスの設定を検証するます。
+func (a Advice) validate() error {
+	for _, rule := range a.Disabled {
+		known := false
+		for _, name := range AdviceRules {
+			known = known || rule == name
+		}
+		if !known {
+			return fmt.Errorf("advice.disabled に未対応のルールがあります: %s", rule)
+		}
+	}
+	for i, window := range a.CommuteHours {
+		if _, _, err := window.Minutes(); err != nil {
+			return fmt.Errorf("advice.commuteHours[%d]: %w", i, err)
+		}
+	}
+	if a.LaundryHours != nil {
+		if _, _, err := a.LaundryHours.Minutes(); err != nil {
+			return fmt.Errorf("advice.laundryHours: %w", err)
+		}
+	}
+	if a.UmbrellaPrecipProb < 0 || a.UmbrellaPrecipProb > 100 {
+		return fmt.Errorf("advice.umbrellaPrecipProb は 0〜100 の範囲で指定してください")
+	}
+	if a.LaundryPrecipProb < 0 || a.LaundryPrecipProb > 100 {
+		return fmt.Errorf("advice.laundryPrecipProb は 0〜100 の範囲で指定してください")
+	}
+	if a.UVIndex < 0 || a.WindSpeed < 0 {
+		return fmt.Errorf("advice.uvIndex と advice.windSpeed は0（既定値）以上で指定してください")
+	}
+	return nil
 }
 
 // Config はアプリケーション全体の設定を定義する構造体なのです。
 type Config struct {
 	RefreshIntervals RefreshIntervals `json:"refreshIntervals"` // 更新間隔設定
-	Location         Location         `json:"location"`         // ロケーション設定
+	Location         Location         `json:"location"`         // ロケーション設定（1地点のみの場合）
+	Locations        []Location       `json:"locations"`        // 複数地点の設定（指定時は location より優先、先頭が既定の地点）
 	Nextcloud        Nextcloud        `json:"nextcloud"`        // Nextcloud CalDAV/WebDAV設定
+	CalDAVAccounts   []CalDAVAccount  `json:"caldavAccounts"`   // Nextcloud 以外の CalDAV アカウント（nextcloud と合わせて使えるのです）
+	Calendar         Calendar         `json:"calendar"`         // カレンダー表示範囲設定
 	Weather          Weather          `json:"weather"`          // 天気API設定
+	Alerts           Alerts           `json:"alerts"`           // 注意報・警報設定
+	Advice           Advice           `json:"advice"`           // 天気のアドバイスの判定条件
+	History          History          `json:"history"`          // 天気の履歴の設定
 	loadedAt         time.Time        // 設定の読み込み時刻（内部用）
 }
 
 // GetRefreshInterval はデータソースに応じた更新間隔をDurationで返すます。
-// sourceは "weather", "calendar", "tasks" など。
+// sourceは "weather", "calendar", "tasks", "airQuality" など。
 func (c *Config) GetRefreshInterval(source string) time.Duration {
 	switch source {
+	case "airQuality":
+		if c.RefreshIntervals.AirQualitySec <= 0 {
+			return DefaultAirQualitySec * time.Second
+		}
+		return time.Duration(c.RefreshIntervals.AirQualitySec) * time.Second
 	case "weather":
 		return time.Duration(c.RefreshIntervals.WeatherSec) * time.Second
 	case "calendar":
@@ -61,12 +485,52 @@ func (c *Config) GetRefreshInterval(source string) time.Duration {
 	}
 }
 
-// GetLocationString はロケーション情報を文字列で返すます。
+// GetLocationString は既定の地点のロケーション情報を文字列で返すます。
 func (c *Config) GetLocationString() string {
-	if c.Location.CityName == "" {
+	location := c.PrimaryLocation()
+	if location.CityName == "" {
 		return "Unknown"
 	}
-	return fmt.Sprintf("%s, %s", c.Location.CityName, c.Location.Country)
+	return fmt.Sprintf("%s, %s", location.CityName, location.Country)
+}
+
+// GetLocations は天気を表示する地点の一覧を返すます。
+// locations が空の場合は location の1地点（ID は省略時 DefaultLocationID）を返すのです。
+// 表示名が空の地点は都市名を表示名にするます。
+func (c *Config) GetLocations() []Location {
+	locations := c.Locations
+	if len(locations) == 0 {
+		location := c.Location
+		if location.ID == "" {
+			location.ID = DefaultLocationID
+		}
+		locations = []Location{location}
+	}
+
+	result := make([]Location, 0, len(locations))
+	for _, location := range locations {
+		if location.Name == "" {
+			location.Name = location.CityName
+		}
+		result = append(result, location)
+	}
+	return result
+}
+
+// PrimaryLocation は既定の地点（地点一覧の先頭）を返すます。
+// 地点を指定しない /api/weather や注意報・警報はこの地点を使うのです。
+func (c *Config) PrimaryLocation() Location {
+	return c.GetLocations()[0]
+}
+
+// FindLocation は地点IDに一致する地点を返すます。
+func (c *Config) FindLocation(id string) (Location, bool) {
+	for _, location := range c.GetLocations() {
+		if location.ID == id {
+			return location, true
+		}
+	}
+	return Location{}, false
 }
 
 // GetCalendarNames はNextcloudカレンダー名のリストを返すます。
@@ -79,6 +543,33 @@ func (c *Config) GetTaskListNames() []string {
 	return c.Nextcloud.TaskListNames
 }
 
+// GetCalDAVAccounts は接続する CalDAV アカウントの一覧を返すます。
+// nextcloud の serverUrl が設定されていれば、ID "nextcloud"・プリセット nextcloud のアカウントとして先頭にするのです。
+func (c *Config) GetCalDAVAccounts() []CalDAVAccount {
+	accounts := []CalDAVAccount{}
+	if c.Nextcloud.ServerURL != "" {
+		accounts = append(accounts, CalDAVAccount{
+			ID:            NextcloudAccountID,
+			Preset:        CalDAVPresetNextcloud,
+			ServerURL:     c.Nextcloud.ServerURL,
+			Username:      c.Nextcloud.Username,
+			Password:      c.Nextcloud.Password,
+			CalendarNames: c.Nextcloud.CalendarNames,
+			TaskListNames: c.Nextcloud.TaskListNames,
+		})
+	}
+	return append(accounts, c.CalDAVAccounts...)
+}
+
+// GetCalendarDays はカレンダーの既定表示日数を返すます。
+// 未設定の場合は DefaultCalendarDays を返すのです。
+func (c *Config) GetCalendarDays() int {
+	if c.Calendar.DefaultDays <= 0 {
+		return DefaultCalendarDays
+	}
+	return c.Calendar.DefaultDays
+}
+
 // LoadedAt は設定の読み込み時刻を返すます。
 func (c *Config) LoadedAt() time.Time {
 	return c.loadedAt
@@ -121,13 +612,32 @@ func (c *Config) Validate() error {
 	if c.RefreshIntervals.TasksSec <= 0 {
 		return fmt.Errorf("tasksSec は正の数である必要があります")
 	}
+	if c.RefreshIntervals.AirQualitySec < 0 {
+		return fmt.Errorf("airQualitySec は0（既定値）以上である必要があります")
+	}
 
-	// ロケーション情報の妥当性チェック
-	if c.Location.CityName == "" {
-		return fmt.Errorf("location.cityName は必須フィールドです")
+	// ロケーション情報の妥当性チェック（locations を指定した場合は location は使わないのです）
+	if len(c.Locations) == 0 {
+		if err := validateLocation("location", c.Location); err != nil {
+			return err
+		}
+		if c.Location.ID != "" && !isLocationID(c.Location.ID) {
+			return fmt.Errorf("location.id は英小文字・数字・- _ で指定してください: %q", c.Location.ID)
+		}
 	}
-	if c.Location.Country == "" {
-		return fmt.Errorf("location.country は必須フィールドです")
+	seenIDs := map[string]bool{}
+	for i, location := range c.Locations {
+		field := fmt.Sprintf("locations[%d]", i)
+		if !isLocationID(location.ID) {
+			return fmt.Errorf("%s.id は英小文字・数字・- _ で指定してください: %q", field, location.ID)
+		}
+		if seenIDs[location.ID] {
+			return fmt.Errorf("%s.id が重複しています: %s", field, location.ID)
+		}
+		seenIDs[location.ID] = true
+		if err := validateLocation(field, location); err != nil {
+			return err
+		}
 	}
 
 	// Nextcloud 設定の妥当性チェック＆デフォルト値設定
@@ -142,8 +652,189 @@ func (c *Config) Validate() error {
 		fmt.Println("⚠️ TaskListNames が空のため、デフォルト値 ['tasks'] を設定しました")
 	}
 
+	// 同時取得数・タイムアウトの妥当性チェック（0 は既定値扱い）
+	if c.Nextcloud.MaxConcurrency < 0 || c.Nextcloud.MaxConcurrency > MaxNextcloudConcurrency {
+		return fmt.Errorf("nextcloud.maxConcurrency は 0〜%d の範囲で指定してください", MaxNextcloudConcurrency)
+	}
+	if c.Nextcloud.CollectionTimeoutSec < 0 {
+		return fmt.Errorf("nextcloud.collectionTimeoutSec は0（既定値）以上である必要があります")
+	}
+
+	// CalDAV アカウントの妥当性チェック（ID "nextcloud" は nextcloud の設定で使うのです）
+	seenAccountIDs := map[string]bool{NextcloudAccountID: c.Nextcloud.ServerURL != ""}
+	for i, account := range c.CalDAVAccounts {
+		field := fmt.Sprintf("caldavAccounts[%d]", i)
+		if !isLocationID(account.ID) {
+			return fmt.Errorf("%s.id は英小文字・数字・- _ で指定してください: %q", field, account.ID)
+		}
+		if seenAccountIDs[account.ID] {
+			return fmt.Errorf("%s.id が重複しています: %s", field, account.ID)
+		}
+		seenAccountIDs[account.ID] = true
+		switch account.GetPreset() {
+		case CalDAVPresetGeneric, CalDAVPresetNextcloud:
+		default:
+			return fmt.Errorf("%s.preset は %s / %s のいずれかを指定してください: %s", field, CalDAVPresetGeneric, CalDAVPresetNextcloud, account.Preset)
+		}
+		if u, err := url.Parse(account.ServerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s.serverUrl は http(s):// で始まるURLで指定してください: %q", field, account.ServerURL)
+		}
+		if len(account.CalendarNames) == 0 && len(account.TaskListNames) == 0 {
+			return fmt.Errorf("%s.calendarNames か %s.taskListNames を指定してください", field, field)
+		}
+		for _, host := range account.TrustedHosts {
+			if u, err := url.Parse("https://" + host); err != nil || host == "" || u.Host != host {
+				return fmt.Errorf("%s.trustedHosts はホスト名（host または host:port）で指定してください: %q", field, host)
+			}
+		}
+	}
+
+	// カレンダー表示日数の妥当性チェック（0 は既定値扱い）
+	if c.Calendar.DefaultDays < 0 || c.Calendar.DefaultDays > MaxCalendarDays {
+		return fmt.Errorf("calendar.defaultDays は 0〜%d の範囲で指定してください", MaxCalendarDays)
+	}
+
+	// ICS 購読カレンダーの妥当性チェック（名前は CalDAV のカレンダー名とも重ならないようにするのです）
+	calendarNames := map[string]bool{}
+	for _, account := range c.GetCalDAVAccounts() {
+		for _, name := range account.CalendarNames {
+			if account.ID == NextcloudAccountID && account.GetPreset() == CalDAVPresetNextcloud {
+				calendarNames[name] = true
+			} else {
+				calendarNames[account.ID+"/"+name] = true
+			}
+		}
+	}
+	for i, subscription := range c.Calendar.Subscriptions {
+		field := fmt.Sprintf("calendar.subscriptions[%d]", i)
+		if strings.TrimSpace(subscription.Name) == "" {
+			return fmt.Errorf("%s.name は必須フィールドです", field)
+		}
+		if calendarNames[subscription.Name] {
+			return fmt.Errorf("%s.name がほかのカレンダー名と重複しています: %s", field, subscription.Name)
+		}
+		calendarNames[subscription.Name] = true
+		if u, err := url.Parse(subscription.GetURL()); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s.url は http(s):// か webcal:// で始まるURLで指定してください: %q", field, subscription.URL)
+		}
+		if subscription.Color != "" && !isHexColor(subscription.Color) {
+			return fmt.Errorf("%s.color は #RRGGBB で指定してください: %q", field, subscription.Color)
+		}
+		if subscription.RefreshSec < 0 {
+			return fmt.Errorf("%s.refreshSec は0（既定値）以上である必要があります", field)
+		}
+	}
+
+	// 注意報・警報の区域コードの妥当性チェック（空は無効扱い）
+	if c.Alerts.AreaCode != "" && !isDigits(c.Alerts.AreaCode, 6, 7) {
+		return fmt.Errorf("alerts.areaCode は6桁または7桁の数字で指定してください: %s", c.Alerts.AreaCode)
+	}
+	if c.Alerts.OfficeCode != "" && !isDigits(c.Alerts.OfficeCode, 6, 6) {
+		return fmt.Errorf("alerts.officeCode は6桁の数字で指定してください: %s", c.Alerts.OfficeCode)
+	}
+
+	// 表示言語と単位の妥当性チェック（省略時は ja-hiragana・metric）
+	if c.Weather.Locale != "" && !IsLocale(c.Weather.Locale) {
+		return fmt.Errorf("weather.locale は %s / %s / %s のいずれかを指定してください: %s", LocaleJaHiragana, LocaleJaKanji, LocaleEn, c.Weather.Locale)
+	}
+	switch c.Weather.Units.System {
+	case "", UnitsMetric, UnitsImperial:
+	default:
+		return fmt.Errorf("weather.units.system は %s / %s のいずれかを指定してください: %s", UnitsMetric, UnitsImperial, c.Weather.Units.System)
+	}
+	switch c.Weather.Units.WindSpeed {
+	case "", WindSpeedMS, WindSpeedKMH, WindSpeedMPH:
+	default:
+		return fmt.Errorf("weather.units.windSpeed は %s / %s / %s のいずれかを指定してください: %s", WindSpeedMS, WindSpeedKMH, WindSpeedMPH, c.Weather.Units.WindSpeed)
+	}
+
+	// 天気の履歴を残す日数の妥当性チェック（0 は既定値扱い）
+	if c.History.RetentionDays < 0 || c.History.RetentionDays > MaxHistoryRetentionDays {
+		return fmt.Errorf("history.retentionDays は 0〜%d の範囲で指定してください", MaxHistoryRetentionDays)
+	}
+
+	// アドバイスの判定条件の妥当性チェック（省略時は既定値）
+	if err := c.Advice.validate(); err != nil {
+		return err
+	}
+
 	// 注記: 天気API設定は空の場合がある（後で埋める可能性があるため）
 	// ここではスキップするます。
 
 	return nil
 }
+
+// validateLocation は地点1件の設定を検証するます。field はエラーメッセージ用の項目名なのです。
+func validateLocation(field string, location Location) error {
+	if location.CityName == "" {
+		return fmt.Errorf("%s.cityName は必須フィールドです", field)
+	}
+	if location.Country == "" {
+		return fmt.Errorf("%s.country は必須フィールドです", field)
+	}
+	if (location.Latitude == nil) != (location.Longitude == nil) {
+		return fmt.Errorf("%s.latitude と %s.longitude は両方指定してください", field, field)
+	}
+	if lat, lon, ok := location.Coordinates(); ok {
+		if lat < -90 || lat > 90 {
+			return fmt.Errorf("%s.latitude は -90〜90 の範囲で指定してください", field)
+		}
+		if lon < -180 || lon > 180 {
+			return fmt.Errorf("%s.longitude は -180〜180 の範囲で指定してください", field)
+		}
+	}
+	return nil
+}
+
+// isLocationID は地点IDとして使える文字列（英小文字・数字・- _、1〜32文字）かを判定するます。
+// クエリやエラーソース名（"weather:<id>"）にそのまま使うためなのです。
+func isLocationID(value string) bool {
+	if len(value) == 0 || len(value) > 32 {
+		return false
+	}
+	for _, r := range value {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// isHexColor は "#RRGGBB" 形式の色かを判定するます。
+func isHexColor(value string) bool {
+	if len(value) != 7 || value[0] != '#' {
+		return false
+	}
+	for _, r := range value[1:] {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') && (r < 'A' || r > 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseClock は "HH:MM" を0時からの分に変換するます（"24:00" も可）。
+func parseClock(value string) (int, error) {
+	if len(value) != 5 || value[2] != ':' || !isDigits(value[:2], 2, 2) || !isDigits(value[3:], 2, 2) {
+		return 0, fmt.Errorf("時刻は HH:MM で指定してください: %q", value)
+	}
+	hour, _ := strconv.Atoi(value[:2])
+	minute, _ := strconv.Atoi(value[3:])
+	if minute > 59 || hour > 24 || (hour == 24 && minute > 0) {
+		return 0, fmt.Errorf("時刻は 00:00〜24:00 で指定してください: %q", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// isDigits は value が minLen〜maxLen 桁の数字だけかを判定するます。
+func isDigits(value string, minLen, maxLen int) bool {
+	if len(value) < minLen || len(value) > maxLen {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -123,6 +124,14 @@ func TestValidateRefreshIntervalsInvalid(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "airQualitySec が負数",
+			config: &Config{
+				RefreshIntervals: RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300, AirQualitySec: -1},
+				Location:         Location{CityName: "姫路市", Country: "JP"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -158,6 +167,30 @@ func TestValidateLocationInvalid(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "latitude のみ指定",
+			config: &Config{
+				RefreshIntervals: RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300},
+				Location:         Location{CityName: "松江市", Country: "JP", Latitude: floatPtr(35.47)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "latitude が範囲外",
+			config: &Config{
+				RefreshIntervals: RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300},
+				Location:         Location{CityName: "松江市", Country: "JP", Latitude: floatPtr(95), Longitude: floatPtr(133.05)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "緯度経度を指定",
+			config: &Config{
+				RefreshIntervals: RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300},
+				Location:         Location{CityName: "松江市", Country: "JP", Latitude: floatPtr(35.47), Longitude: floatPtr(133.05)},
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -174,9 +207,10 @@ func TestValidateLocationInvalid(t *testing.T) {
 func TestGetRefreshInterval(t *testing.T) {
 	cfg := &Config{
 		RefreshIntervals: RefreshIntervals{
-			WeatherSec:  300,
-			CalendarSec: 600,
-			TasksSec:    450,
+			WeatherSec:    300,
+			CalendarSec:   600,
+			TasksSec:      450,
+			AirQualitySec: 1800,
 		},
 	}
 
@@ -187,6 +221,7 @@ func TestGetRefreshInterval(t *testing.T) {
 		{"weather", 300 * time.Second},
 		{"calendar", 600 * time.Second},
 		{"tasks", 450 * time.Second},
+		{"airQuality", 1800 * time.Second},
 		{"unknown", 5 * time.Minute}, // 既定値
 	}
 
@@ -196,6 +231,12 @@ func TestGetRefreshInterval(t *testing.T) {
 			t.Errorf("GetRefreshInterval(%q) = %v、期待値：%v", tt.source, got, tt.expected)
 		}
 	}
+
+	// airQualitySec は省略すると1時間
+	cfg.RefreshIntervals.AirQualitySec = 0
+	if got := cfg.GetRefreshInterval("airQuality"); got != time.Hour {
+		t.Errorf("GetRefreshInterval(airQuality) 省略時 = %v、期待値：1h", got)
+	}
 }
 
 // TestGetLocationString は GetLocationString メソッドのテストです。
@@ -226,3 +267,383 @@ func TestGetLocationString(t *testing.T) {
 		})
 	}
 }
+
+// TestCalendarDays は GetCalendarDays とカレンダー表示日数のバリデーションテストです。
+func TestCalendarDays(t *testing.T) {
+	tests := []struct {
+		name     string
+		days     int
+		wantErr  bool
+		expected int
+	}{
+		{name: "未設定は既定値", days: 0, wantErr: false, expected: DefaultCalendarDays},
+		{name: "14日", days: 14, wantErr: false, expected: 14},
+		{name: "上限", days: MaxCalendarDays, wantErr: false, expected: MaxCalendarDays},
+		{name: "上限超過", days: MaxCalendarDays + 1, wantErr: true},
+		{name: "負数", days: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				RefreshIntervals: RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300},
+				Location:         Location{CityName: "姫路市", Country: "JP"},
+				Calendar:         Calendar{DefaultDays: tt.days},
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("バリデーション結果が一致しません。期待エラー：%v、実際エラー：%v", tt.wantErr, err)
+			}
+			if !tt.wantErr && cfg.GetCalendarDays() != tt.expected {
+				t.Errorf("GetCalendarDays() = %d、期待値：%d", cfg.GetCalendarDays(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestAlerts(t *testing.T) {
+	tests := []struct {
+		name       string
+		alerts     Alerts
+		wantErr    bool
+		enabled    bool
+		officeCode string
+	}{
+		{name: "未設定は無効", alerts: Alerts{}, enabled: false, officeCode: ""},
+		{name: "市町村コードから府県予報区を求める", alerts: Alerts{AreaCode: "2820100"}, enabled: true, officeCode: "280000"},
+		{name: "府県予報区を明示", alerts: Alerts{AreaCode: "016010", OfficeCode: "016000"}, enabled: true, officeCode: "016000"},
+		{name: "桁数不正", alerts: Alerts{AreaCode: "2820"}, wantErr: true},
+		{name: "数字以外", alerts: Alerts{AreaCode: "28201a0"}, wantErr: true},
+		{name: "府県予報区コード不正", alerts: Alerts{AreaCode: "2820100", OfficeCode: "28"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				RefreshIntervals: RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300},
+				Location:         Location{CityName: "姫路市", Country: "JP"},
+				Alerts:           tt.alerts,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("バリデーション結果が一致しません。期待エラー：%v、実際エラー：%v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cfg.Alerts.Enabled() != tt.enabled {
+				t.Errorf("Enabled() = %v、期待値：%v", cfg.Alerts.Enabled(), tt.enabled)
+			}
+			if cfg.Alerts.GetOfficeCode() != tt.officeCode {
+				t.Errorf("GetOfficeCode() = %q、期待値：%q", cfg.Alerts.GetOfficeCode(), tt.officeCode)
+			}
+		})
+	}
+}
+
+func TestWeatherProviders(t *testing.T) {
+	data := []byte(`{"provider": "openweathermap", "apiKey": "key"}`)
+	var single Weather
+	if err := json.Unmarshal(data, &single); err != nil {
+		t.Fatalf("JSONパース失敗: %v", err)
+	}
+	if got := single.GetProviders(); len(got) != 1 || got[0].Provider != "openweathermap" || got[0].ApiKey != "key" {
+		t.Errorf("単一設定の GetProviders() = %+v", got)
+	}
+
+	data = []byte(`{"provider": "openmeteo", "providers": [{"provider": "metno"}, {"provider": "openmeteo"}]}`)
+	var list Weather
+	if err := json.Unmarshal(data, &list); err != nil {
+		t.Fatalf("JSONパース失敗: %v", err)
+	}
+	if got := list.GetProviders(); len(got) != 2 || got[0].Provider != "metno" || got[1].Provider != "openmeteo" {
+		t.Errorf("一覧設定の GetProviders() = %+v", got)
+	}
+}
+
+func TestLocations(t *testing.T) {
+	intervals := RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300}
+
+	// location だけなら ID は home、表示名は都市名
+	single := &Config{RefreshIntervals: intervals, Location: Location{CityName: "姫路市", Country: "JP"}}
+	if err := single.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if got := single.GetLocations(); len(got) != 1 || got[0].ID != DefaultLocationID || got[0].Name != "姫路市" {
+		t.Errorf("単一設定の GetLocations() = %+v", got)
+	}
+
+	// locations を指定したら location は省略でき、先頭が既定の地点
+	list := &Config{
+		RefreshIntervals: intervals,
+		Locations: []Location{
+			{ID: "home", Name: "おうち", CityName: "姫路市", Country: "JP"},
+			{ID: "grandma", CityName: "松江市", Country: "JP", Latitude: floatPtr(35.47), Longitude: floatPtr(133.05)},
+		},
+	}
+	if err := list.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if got := list.PrimaryLocation(); got.ID != "home" || got.Name != "おうち" {
+		t.Errorf("PrimaryLocation() = %+v", got)
+	}
+	if got, ok := list.FindLocation("grandma"); !ok || got.CityName != "松江市" || got.Name != "松江市" {
+		t.Errorf("FindLocation(grandma) = %+v, %v", got, ok)
+	}
+	if _, ok := list.FindLocation("trip"); ok {
+		t.Errorf("FindLocation(trip) が見つかってしまいます")
+	}
+	if got := list.GetLocationString(); got != "姫路市, JP" {
+		t.Errorf("GetLocationString() = %q", got)
+	}
+
+	tests := []struct {
+		name      string
+		locations []Location
+	}{
+		{name: "id が空", locations: []Location{{CityName: "姫路市", Country: "JP"}}},
+		{name: "id に使えない文字", locations: []Location{{ID: "Home Town", CityName: "姫路市", Country: "JP"}}},
+		{name: "id が重複", locations: []Location{{ID: "home", CityName: "姫路市", Country: "JP"}, {ID: "home", CityName: "松江市", Country: "JP"}}},
+		{name: "cityName が空", locations: []Location{{ID: "home", Country: "JP"}}},
+		{name: "latitude のみ指定", locations: []Location{{ID: "home", CityName: "松江市", Country: "JP", Latitude: floatPtr(35.47)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{RefreshIntervals: intervals, Locations: tt.locations}
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("エラーになりません: %+v", tt.locations)
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	if got := (History{}).GetRetentionDays(); got != DefaultHistoryRetentionDays {
+		t.Errorf("既定の保持日数 = %d", got)
+	}
+	if got := (History{RetentionDays: 30}).GetRetentionDays(); got != 30 {
+		t.Errorf("保持日数 = %d", got)
+	}
+
+	intervals := RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300}
+	location := Location{CityName: "姫路市", Country: "JP"}
+	for _, days := range []int{-1, MaxHistoryRetentionDays + 1} {
+		cfg := &Config{RefreshIntervals: intervals, Location: location, History: History{RetentionDays: days}}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("retentionDays=%d がエラーになりません", days)
+		}
+	}
+}
+
+func TestNextcloudConcurrency(t *testing.T) {
+	var nc Nextcloud
+	if nc.GetMaxConcurrency() != DefaultNextcloudConcurrency || nc.GetCollectionTimeout() != DefaultCollectionTimeout {
+		t.Errorf("既定値が違います: %d, %v", nc.GetMaxConcurrency(), nc.GetCollectionTimeout())
+	}
+	nc = Nextcloud{MaxConcurrency: 2, CollectionTimeoutSec: 5}
+	if nc.GetMaxConcurrency() != 2 || nc.GetCollectionTimeout() != 5*time.Second {
+		t.Errorf("設定値が違います: %d, %v", nc.GetMaxConcurrency(), nc.GetCollectionTimeout())
+	}
+
+	intervals := RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300}
+	location := Location{CityName: "姫路市", Country: "JP"}
+	for _, nc := range []Nextcloud{{MaxConcurrency: -1}, {MaxConcurrency: MaxNextcloudConcurrency + 1}, {CollectionTimeoutSec: -1}} {
+		cfg := &Config{RefreshIntervals: intervals, Location: location, Nextcloud: nc}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%+v がエラーになりません", nc)
+		}
+	}
+}
+
+func TestAdvice(t *testing.T) {
+	// 省略時は既定値
+	var advice Advice
+	if !advice.RuleEnabled(AdviceUmbrella) || advice.GetUmbrellaPrecipProb() != DefaultUmbrellaPrecipProb || advice.GetColdTemp() != DefaultColdTemp {
+		t.Errorf("既定値が違います: %+v", advice)
+	}
+	if got := advice.GetCommuteHours(); len(got) != 2 || got[0].Start != "07:00" || got[1].End != "19:00" {
+		t.Errorf("GetCommuteHours() = %+v", got)
+	}
+	if start, end, err := advice.GetLaundryHours().Minutes(); err != nil || start != 9*60 || end != 15*60 {
+		t.Errorf("GetLaundryHours().Minutes() = %d, %d, %v", start, end, err)
+	}
+
+	advice = Advice{Disabled: []string{AdviceLaundry}, ColdTemp: floatPtr(0), HeatTemp: 28}
+	if advice.RuleEnabled(AdviceLaundry) || advice.GetColdTemp() != 0 || advice.GetHeatTemp() != 28 {
+		t.Errorf("設定値が反映されません: %+v", advice)
+	}
+
+	intervals := RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300}
+	location := Location{CityName: "姫路市", Country: "JP"}
+	valid := &Config{RefreshIntervals: intervals, Location: location, Advice: Advice{
+		CommuteHours: []TimeWindow{{Start: "06:30", End: "08:00"}},
+		LaundryHours: &TimeWindow{Start: "10:00", End: "24:00"},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		advice Advice
+	}{
+		{name: "未対応のルール", advice: Advice{Disabled: []string{"snow"}}},
+		{name: "時刻の形式が違う", advice: Advice{CommuteHours: []TimeWindow{{Start: "7:00", End: "09:00"}}}},
+		{name: "24時を超える", advice: Advice{LaundryHours: &TimeWindow{Start: "09:00", End: "25:00"}}},
+		{name: "開始が終了より後", advice: Advice{CommuteHours: []TimeWindow{{Start: "19:00", End: "17:00"}}}},
+		{name: "降水確率が100を超える", advice: Advice{UmbrellaPrecipProb: 120}},
+		{name: "風速が負", advice: Advice{WindSpeed: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{RefreshIntervals: intervals, Location: location, Advice: tt.advice}
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("エラーになりません: %+v", tt.advice)
+			}
+		})
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestWeatherLocaleAndUnits(t *testing.T) {
+	var w Weather
+	if w.GetLocale() != LocaleJaHiragana || w.Units.GetSystem() != UnitsMetric || w.Units.GetWindSpeed() != WindSpeedMS {
+		t.Errorf("既定値が違います: %s %s %s", w.GetLocale(), w.Units.GetSystem(), w.Units.GetWindSpeed())
+	}
+	// imperial の風速の既定は mph、個別に指定もできる
+	if got := (Units{System: UnitsImperial}).GetWindSpeed(); got != WindSpeedMPH {
+		t.Errorf("imperial の風速 = %s", got)
+	}
+	if got := (Units{WindSpeed: WindSpeedKMH}).GetWindSpeed(); got != WindSpeedKMH {
+		t.Errorf("風速 = %s", got)
+	}
+
+	intervals := RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300}
+	location := Location{CityName: "姫路市", Country: "JP"}
+	valid := &Config{RefreshIntervals: intervals, Location: location, Weather: Weather{
+		Locale: LocaleEn,
+		Units:  Units{System: UnitsImperial, WindSpeed: WindSpeedMS},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("正しい設定がエラーになります: %v", err)
+	}
+	for _, weather := range []Weather{
+		{Locale: "fr"},
+		{Units: Units{System: "si"}},
+		{Units: Units{WindSpeed: "knots"}},
+	} {
+		cfg := &Config{RefreshIntervals: intervals, Location: location, Weather: weather}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%+v がエラーになりません", weather)
+		}
+	}
+}
+
+func TestCalDAVAccounts(t *testing.T) {
+	intervals := RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300}
+	location := Location{CityName: "姫路市", Country: "JP"}
+
+	// nextcloud の設定は先頭のアカウントになる
+	cfg := &Config{
+		RefreshIntervals: intervals,
+		Location:         location,
+		Nextcloud:        Nextcloud{ServerURL: "https://nextcloud.example.com", Username: "user", Password: "pass"},
+		CalDAVAccounts: []CalDAVAccount{
+			{ID: "grandma", ServerURL: "https://caldav.fastmail.com", Username: "grandma", CalendarNames: []string{"Family"}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate エラー: %v", err)
+	}
+	accounts := cfg.GetCalDAVAccounts()
+	if len(accounts) != 2 || accounts[0].ID != NextcloudAccountID || accounts[0].GetPreset() != CalDAVPresetNextcloud ||
+		accounts[0].CalendarNames[0] != "family" || accounts[1].GetPreset() != CalDAVPresetGeneric {
+		t.Fatalf("GetCalDAVAccounts() = %+v", accounts)
+	}
+
+	// nextcloud が無ければ caldavAccounts だけ
+	cfg.Nextcloud = Nextcloud{}
+	if accounts := cfg.GetCalDAVAccounts(); len(accounts) != 1 || accounts[0].ID != "grandma" {
+		t.Fatalf("nextcloud なし: %+v", accounts)
+	}
+
+	for _, account := range []CalDAVAccount{
+		{ID: "", ServerURL: "https://example.com", CalendarNames: []string{"a"}},
+		{ID: "Grandma", ServerURL: "https://example.com", CalendarNames: []string{"a"}},
+		{ID: "nextcloud", ServerURL: "https://example.com", CalendarNames: []string{"a"}},
+		{ID: "a", Preset: "google", ServerURL: "https://example.com", CalendarNames: []string{"a"}},
+		{ID: "a", ServerURL: "caldav.example.com", CalendarNames: []string{"a"}},
+		{ID: "a", ServerURL: "https://example.com"},
+		{ID: "a", ServerURL: "https://example.com", CalendarNames: []string{"a"}, TrustedHosts: []string{"https://p01-caldav.icloud.com"}},
+	} {
+		cfg := &Config{
+			RefreshIntervals: intervals,
+			Location:         location,
+			Nextcloud:        Nextcloud{ServerURL: "https://nextcloud.example.com"},
+			CalDAVAccounts:   []CalDAVAccount{account},
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%+v がエラーになりません", account)
+		}
+	}
+	duplicated := &Config{RefreshIntervals: intervals, Location: location, CalDAVAccounts: []CalDAVAccount{
+		{ID: "a", ServerURL: "https://a.example.com", CalendarNames: []string{"a"}},
+		{ID: "a", ServerURL: "https://b.example.com", CalendarNames: []string{"b"}},
+	}}
+	if err := duplicated.Validate(); err == nil {
+		t.Error("重複したIDがエラーになりません")
+	}
+}
+
+func TestCalendarSubscriptions(t *testing.T) {
+	intervals := RefreshIntervals{WeatherSec: 300, CalendarSec: 300, TasksSec: 300}
+	location := Location{CityName: "姫路市", Country: "JP"}
+
+	subscription := CalendarSubscription{Name: "祝日", URL: "webcal://example.com/holidays.ics", Color: "#D50000"}
+	cfg := &Config{
+		RefreshIntervals: intervals,
+		Location:         location,
+		Calendar:         Calendar{Subscriptions: []CalendarSubscription{subscription}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate エラー: %v", err)
+	}
+	if got := subscription.GetURL(); got != "https://example.com/holidays.ics" {
+		t.Errorf("GetURL() = %s", got)
+	}
+	if got := subscription.GetRefreshInterval(); got != DefaultSubscriptionRefreshSec*time.Second {
+		t.Errorf("GetRefreshInterval() = %v", got)
+	}
+
+	for _, subscription := range []CalendarSubscription{
+		{Name: "", URL: "https://example.com/a.ics"},
+		{Name: "family", URL: "https://example.com/a.ics"},
+		{Name: "grandma/Family", URL: "https://example.com/a.ics"},
+		{Name: "a", URL: "ftp://example.com/a.ics"},
+		{Name: "a", URL: "https://example.com/a.ics", Color: "red"},
+		{Name: "a", URL: "https://example.com/a.ics", RefreshSec: -1},
+	} {
+		cfg := &Config{
+			RefreshIntervals: intervals,
+			Location:         location,
+			Nextcloud:        Nextcloud{ServerURL: "https://nextcloud.example.com", CalendarNames: []string{"family"}},
+			CalDAVAccounts: []CalDAVAccount{
+				{ID: "grandma", ServerURL: "https://caldav.fastmail.com", CalendarNames: []string{"Family"}},
+			},
+			Calendar: Calendar{Subscriptions: []CalendarSubscription{subscription}},
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%+v がエラーになりません", subscription)
+		}
+	}
+	duplicated := &Config{RefreshIntervals: intervals, Location: location, Calendar: Calendar{Subscriptions: []CalendarSubscription{
+		{Name: "祝日", URL: "https://a.example.com/a.ics"},
+		{Name: "祝日", URL: "https://b.example.com/b.ics"},
+	}}}
+	if err := duplicated.Validate(); err == nil {
+		t.Error("重複した名前がエラーになりません")
+	}
+}
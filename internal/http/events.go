@@ -0,0 +1,342 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rihow/FamilyDashboard/internal/cache"
+	"github.com/rihow/FamilyDashboard/internal/config"
+	"github.com/rihow/FamilyDashboard/internal/models"
+	"github.com/rihow/FamilyDashboard/internal/services/jma"
+	"github.com/rihow/FamilyDashboard/internal/services/nextcloud"
+	"github.com/rihow/FamilyDashboard/internal/services/weather"
+	"github.com/rihow/FamilyDashboard/internal/status"
+)
+
+const (
+	// eventPollInterval はキャッシュの変更を確認する間隔なのです。
+	eventPollInterval = 2 * time.Second
+	// eventHeartbeatInterval は接続維持用のコメントを送る間隔なのです。
+	eventHeartbeatInterval = 20 * time.Second
+	// eventHistorySize は Last-Event-ID での再開用に保持するメッセージ数なのです。
+	eventHistorySize = 64
+	// eventSubscriberBuffer は購読者ごとの送信待ちメッセージ数の上限なのです。
+	eventSubscriberBuffer = 16
+	// eventRetryMillis は切断時にブラウザが再接続するまでの待ち時間なのです。
+	eventRetryMillis = 3000
+)
+
+// eventTypes は /api/events で送るメッセージの種類なのです。
+var eventTypes = []string{"weather", "calendar", "tasks", "status"}
+
+// sseEvent は SSE で送るメッセージ1件なのです。
+type sseEvent struct {
+	ID   uint64
+	Type string
+	Data []byte
+}
+
+// EventHub はキャッシュの変更を検出して /api/events の購読者に配信するのです。
+// ペイロードのハッシュが変わったときだけメッセージを作るので、変化のない間は何も送らないます。
+// 購読者がいない間はキャッシュの確認もしないのです。
+type EventHub struct {
+	fc         *cache.FileCache
+	cfg        *config.Config
+	errorStore *status.ErrorStore
+
+	pollInterval      time.Duration
+	heartbeatInterval time.Duration
+
+	mu          sync.Mutex
+	nextID      uint64
+	hashes      map[string]string
+	latest      map[string]sseEvent
+	history     []sseEvent
+	subscribers map[chan sseEvent]struct{}
+}
+
+// NewEventHub はイベント配信ハブを作成するます。
+func NewEventHub(fc *cache.FileCache, cfg *config.Config, errorStore *status.ErrorStore) *EventHub {
+	return &EventHub{
+		fc:                fc,
+		cfg:               cfg,
+		errorStore:        errorStore,
+		pollInterval:      eventPollInterval,
+		heartbeatInterval: eventHeartbeatInterval,
+		hashes:            map[string]string{},
+		latest:            map[string]sseEvent{},
+		subscribers:       map[chan sseEvent]struct{}{},
+	}
+}
+
+// Run は ctx が終わるまでキャッシュの変更を監視するます。
+func (h *EventHub) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.closeSubscribers()
+			return
+		case <-ticker.C:
+			if h.subscriberCount() > 0 {
+				h.poll()
+			}
+		}
+	}
+}
+
+// poll は各ソースの最新ペイロードを読み、変化があればメッセージを配信するます。
+func (h *EventHub) poll() {
+	for _, source := range []struct {
+		eventType string
+		cacheKey  string
+	}{
+		{eventType: "weather", cacheKey: weatherCacheKey(primaryLocation(h.cfg))},
+		{eventType: "calendar", cacheKey: defaultCalendarCacheKey(h.cfg)},
+		{eventType: "tasks", cacheKey: nextcloud.TasksCacheKey},
+	} {
+		entry, exists, _, err := h.fc.Read(source.cacheKey, 0)
+		if err != nil || !exists {
+			continue
+		}
+		data := []byte(entry.Payload)
+		if source.eventType == "weather" {
+			data = h.withCachedSections(data)
+		}
+		h.publish(source.eventType, data, data)
+	}
+
+	// status は取得のたびに lastUpdated が変わるので、エラー状態と天気の提供元の変化だけを検出するます
+	statusResp := buildStatusResponse(h.fc, h.cfg, h.errorStore)
+	data, err := json.Marshal(statusResp)
+	if err != nil {
+		return
+	}
+	hashInput, _ := json.Marshal([]any{statusResp.Errors, statusResp.WeatherProvider})
+	h.publish("status", data, hashInput)
+}
+
+// withCachedSections は天気ペイロードにキャッシュ済みの注意報・警報と大気質・花粉、アドバイスを合成するます。
+// /api/weather と同じ内容を送るためなのです（警報や大気質だけが変わった場合も weather として送るます）。
+// weather メッセージは既定の地点だけなのです。
+func (h *EventHub) withCachedSections(payload []byte) []byte {
+	var weatherRsp models.WeatherResponse
+	if err := json.Unmarshal(payload, &weatherRsp); err != nil {
+		return payload
+	}
+	weatherRsp.LocationID = primaryLocation(h.cfg).ID
+	localizer := weather.NewLocalizer(config.LocaleJaHiragana, config.Units{})
+	if h.cfg != nil {
+		localizer = weather.NewLocalizer(h.cfg.Weather.GetLocale(), h.cfg.Weather.Units)
+		weatherRsp.Advice = weather.BuildAdvice(&weatherRsp, h.cfg.Advice, localizer, time.Now())
+	}
+
+	if h.cfg != nil && h.cfg.Alerts.Enabled() {
+		alerts := []models.WeatherAlert{}
+		if _, _, _, err := h.fc.ReadPayload(jma.CacheKey(h.cfg.Alerts.AreaCode), 0, &alerts); err != nil {
+			alerts = []models.WeatherAlert{}
+		}
+		weatherRsp.Alerts = alerts
+	}
+
+	var airQuality models.AirQuality
+	if _, found, _, err := h.fc.ReadPayload(airQualityCacheKey(primaryLocation(h.cfg)), 0, &airQuality); found && err == nil {
+		weatherRsp.AirQuality = &airQuality
+	}
+
+	// 表示言語と単位は設定の既定なのです（SSE には lang クエリが無いのです）
+	localizer.Localize(&weatherRsp)
+	data, err := json.Marshal(weatherRsp)
+	if err != nil {
+		return payload
+	}
+	return data
+}
+
+// publish はハッシュが前回と異なる場合だけメッセージを作って購読者に送るます。
+func (h *EventHub) publish(eventType string, data, hashInput []byte) {
+	sum := sha256.Sum256(hashInput)
+	hash := hex.EncodeToString(sum[:])
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.hashes[eventType] == hash {
+		return
+	}
+	h.hashes[eventType] = hash
+
+	h.nextID++
+	event := sseEvent{ID: h.nextID, Type: eventType, Data: data}
+	h.latest[eventType] = event
+	h.history = append(h.history, event)
+	if len(h.history) > eventHistorySize {
+		h.history = h.history[len(h.history)-eventHistorySize:]
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// 受信が追いつかない購読者は切断するます（Last-Event-ID で再開できるのです）
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe は購読を開始して、最初に送るメッセージと一緒に返すます。
+// Last-Event-ID が履歴内なら続きを、そうでなければ各種類の最新メッセージを返すのです。
+func (h *EventHub) subscribe(lastEventID string) (chan sseEvent, []sseEvent) {
+	// 購読者がいない間は監視していないので、最新の状態を読み直すます
+	h.poll()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan sseEvent, eventSubscriberBuffer)
+	h.subscribers[ch] = struct{}{}
+
+	backlog := []sseEvent{}
+	if id, err := strconv.ParseUint(strings.TrimSpace(lastEventID), 10, 64); err == nil && h.canResume(id) {
+		for _, event := range h.history {
+			if event.ID > id {
+				backlog = append(backlog, event)
+			}
+		}
+		return ch, backlog
+	}
+
+	for _, eventType := range eventTypes {
+		if event, ok := h.latest[eventType]; ok {
+			backlog = append(backlog, event)
+		}
+	}
+	return ch, backlog
+}
+
+// canResume は Last-Event-ID から取りこぼしなく再開できるかを判定するます。
+// サーバー再起動でIDが巻き戻った場合や履歴から溢れた場合は再開できないのです。
+func (h *EventHub) canResume(id uint64) bool {
+	if id > h.nextID {
+		return false
+	}
+	return len(h.history) == 0 || id+1 >= h.history[0].ID
+}
+
+// unsubscribe は購読を終了するます。
+func (h *EventHub) unsubscribe(ch chan sseEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (h *EventHub) subscriberCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+func (h *EventHub) closeSubscribers() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// ============================================================================
+// /api/events ハンドラー
+// ============================================================================
+
+// GetEvents は /api/events の SSE ハンドラーなのです。
+// weather / calendar / tasks / status のメッセージを変化があったときだけ送り、
+// 定期的にハートビートのコメントを送るます。
+func GetEvents(ctx *gin.Context) {
+	hub := getEventHub(ctx)
+	if hub == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "イベント配信が有効になっていません"})
+		return
+	}
+
+	// EventSource は再接続時に Last-Event-ID ヘッダーを付けるます
+	lastEventID := ctx.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = ctx.Query("lastEventId")
+	}
+
+	ch, backlog := hub.subscribe(lastEventID)
+	defer hub.unsubscribe(ch)
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	fmt.Fprintf(ctx.Writer, "retry: %d\n\n", eventRetryMillis)
+	for _, event := range backlog {
+		writeSSEEvent(ctx.Writer, event)
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(hub.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeSSEEvent(ctx.Writer, event)
+			ctx.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(ctx.Writer, ": heartbeat\n\n")
+			ctx.Writer.Flush()
+		}
+	}
+}
+
+// writeSSEEvent は1件のメッセージを SSE 形式で書き出すます。
+func writeSSEEvent(w io.Writer, event sseEvent) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\n", event.ID, event.Type)
+	for _, line := range strings.Split(string(event.Data), "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// getEventHub はコンテキストからイベント配信ハブを取り出すます。
+func getEventHub(ctx *gin.Context) *EventHub {
+	raw, exists := ctx.Get("events")
+	if !exists {
+		return nil
+	}
+	hub, ok := raw.(*EventHub)
+	if !ok {
+		return nil
+	}
+	return hub
+}
@@ -1,14 +1,18 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rihow/FamilyDashboard/internal/cache"
 	"github.com/rihow/FamilyDashboard/internal/config"
 	"github.com/rihow/FamilyDashboard/internal/models"
+	"github.com/rihow/FamilyDashboard/internal/services/jma"
 	"github.com/rihow/FamilyDashboard/internal/services/nextcloud"
 	"github.com/rihow/FamilyDashboard/internal/services/weather"
 	"github.com/rihow/FamilyDashboard/internal/status"
@@ -21,53 +25,72 @@ import (
 // GetStatus は /api/status のGETハンドラーなのです。
 // 現在の状態・エラー・各ソースの最終更新時刻を返すもなのです。
 func GetStatus(ctx *gin.Context) {
-	// 現在時刻を取得する（Asia/Tokyo）
-	now := status.NowRFC3339()
+	response := buildStatusResponse(getCache(ctx), getConfig(ctx), getErrorStore(ctx))
+	ctx.JSON(http.StatusOK, response)
+}
 
+// buildStatusResponse は現在の状態・エラー・各ソースの最終更新時刻を集計するのです。
+// /api/status と /api/events の status メッセージで共通に使うます。
+func buildStatusResponse(fc *cache.FileCache, cfg *config.Config, store *status.ErrorStore) models.StatusResponse {
 	// エラーリストを取得するのです（記録が無い場合は空）
-	errors := []models.ErrorInfo{}
-	if store := getErrorStore(ctx); store != nil {
-		errors = store.List()
+	errorList := []models.ErrorInfo{}
+	if store != nil {
+		errorList = store.List()
 	}
 
-	// キャッシュの最終更新時刻を集計するのです
+	// キャッシュの最終更新時刻と天気の提供元を集計するのです
 	lastUpdated := models.LastUpdatedTimes{}
-	if fc := getCache(ctx); fc != nil {
-		cfg := getConfig(ctx)
-		cityName := "姫路市"
-		country := "JP"
-		if cfg != nil {
-			if cfg.Location.CityName != "" {
-				cityName = cfg.Location.CityName
-			}
-			if cfg.Location.Country != "" {
-				country = cfg.Location.Country
-			}
-		}
+	weatherProvider := ""
+	locations := []models.LocationStatus{}
+	if fc != nil {
+		primaryKey := weatherCacheKey(primaryLocation(cfg))
+		lastUpdated.Weather = readFetchedAt(fc, primaryKey)
+		lastUpdated.Calendar = readFetchedAt(fc, defaultCalendarCacheKey(cfg))
+		lastUpdated.Tasks = readFetchedAt(fc, nextcloud.TasksCacheKey)
+		weatherProvider = readMeta(fc, primaryKey, "source")
 
-		lastUpdated.Weather = readFetchedAt(fc, fmt.Sprintf("weather:%s:%s", country, cityName))
-		lastUpdated.Calendar = readFetchedAt(fc, "nextcloud_calendar_events_all")
-		lastUpdated.Tasks = readFetchedAt(fc, "nextcloud_tasks_items_all")
+		// 地点ごとの天気の最終更新時刻と提供元なのです（地点ごとに別のキャッシュなのです）
+		for _, location := range configLocations(cfg) {
+			cacheKey := weatherCacheKey(location)
+			locations = append(locations, models.LocationStatus{
+				ID:          location.ID,
+				Name:        location.Name,
+				LastUpdated: readFetchedAt(fc, cacheKey),
+				Provider:    readMeta(fc, cacheKey, "source"),
+			})
+		}
 	}
 
-	response := models.StatusResponse{
-		OK:          len(errors) == 0,
-		Now:         now,
-		Errors:      errors,
-		LastUpdated: lastUpdated,
+	return models.StatusResponse{
+		OK:              len(errorList) == 0,
+		Now:             status.NowRFC3339(),
+		Errors:          errorList,
+		LastUpdated:     lastUpdated,
+		WeatherProvider: weatherProvider,
+		Locations:       locations,
 	}
-
-	ctx.JSON(http.StatusOK, response)
 }
 
 // ============================================================================
 // /api/calendar ハンドラー
 // ============================================================================
 
+// calendarMaxFromOffsetDays は from に指定できる今日からの最大日数（前後）なのです。
+const calendarMaxFromOffsetDays = 366
+
 // GetCalendar は /api/calendar のGETハンドラーなのです。
-// Nextcloud CalDAV からイベントを取得し、最大7日分を返すます。
+// Nextcloud CalDAV からイベントを取得し、from（YYYY-MM-DD、既定は今日）から
+// days 日分（既定は設定値、最大 config.MaxCalendarDays 日）を返すます。
 // クライアントが無い場合はダミーデータを返すのです。
 func GetCalendar(ctx *gin.Context) {
+	startDate, days, err := parseCalendarRange(ctx, getConfig(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	// コンテキストから Nextcloud クライアントと設定を取得するます
 	nextcloudRaw, exists := ctx.Get("nextcloud")
 	if !exists {
@@ -88,7 +111,7 @@ func GetCalendar(ctx *gin.Context) {
 	nextcloudClient := nextcloudRaw.(*nextcloud.Client)
 
 	// Nextcloud CalDAV からイベントを取得するます
-	calendarResp, err := nextcloudClient.GetCalendarEvents(ctx)
+	calendarResp, err := nextcloudClient.GetCalendarEvents(ctx, startDate, days)
 	if err != nil {
 		fmt.Printf("❌ カレンダーデータ取得エラー: %v\n", err)
 		setSourceError(ctx, "calendar", err)
@@ -106,6 +129,70 @@ func GetCalendar(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, calendarResp)
 }
 
+// CreateCalendarEvent は POST /api/calendar/events のハンドラーなのです。
+// イベントを指定カレンダーに作成して、作成したイベントを返すます。
+func CreateCalendarEvent(ctx *gin.Context) {
+	nextcloudClient := getNextcloudClient(ctx)
+	if nextcloudClient == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Nextcloud が設定されていません"})
+		return
+	}
+
+	var req models.EventWriteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("リクエストが不正です: %v", err)})
+		return
+	}
+
+	event, err := nextcloudClient.CreateEvent(ctx, req)
+	if err != nil {
+		respondWriteError(ctx, "イベント作成", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, event)
+}
+
+// UpdateCalendarEvent は PATCH /api/calendar/events/:id のハンドラーなのです。
+// 指定したフィールドだけを更新して、更新後のイベントを返すます。
+func UpdateCalendarEvent(ctx *gin.Context) {
+	nextcloudClient := getNextcloudClient(ctx)
+	if nextcloudClient == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Nextcloud が設定されていません"})
+		return
+	}
+
+	var req models.EventWriteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("リクエストが不正です: %v", err)})
+		return
+	}
+
+	event, err := nextcloudClient.UpdateEvent(ctx, ctx.Param("id"), req)
+	if err != nil {
+		respondWriteError(ctx, "イベント更新", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, event)
+}
+
+// DeleteCalendarEvent は DELETE /api/calendar/events/:id のハンドラーなのです。
+func DeleteCalendarEvent(ctx *gin.Context) {
+	nextcloudClient := getNextcloudClient(ctx)
+	if nextcloudClient == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Nextcloud が設定されていません"})
+		return
+	}
+
+	if err := nextcloudClient.DeleteEvent(ctx, ctx.Param("id")); err != nil {
+		respondWriteError(ctx, "イベント削除", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
 // ============================================================================
 // /api/tasks ハンドラー
 // ============================================================================
@@ -146,14 +233,86 @@ func GetTasks(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, tasksResp)
 }
 
+// CreateTask は POST /api/tasks のハンドラーなのです。
+// タスクを Nextcloud に作成して、作成したタスクを返すます。
+func CreateTask(ctx *gin.Context) {
+	nextcloudClient := getNextcloudClient(ctx)
+	if nextcloudClient == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Nextcloud が設定されていません"})
+		return
+	}
+
+	var req models.TaskWriteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("リクエストが不正です: %v", err)})
+		return
+	}
+
+	task, err := nextcloudClient.CreateTask(ctx, req)
+	if err != nil {
+		respondWriteError(ctx, "タスク作成", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, task)
+}
+
+// UpdateTask は PATCH /api/tasks/:id のハンドラーなのです。
+// 指定したフィールドだけを更新して、更新後のタスクを返すます。
+func UpdateTask(ctx *gin.Context) {
+	nextcloudClient := getNextcloudClient(ctx)
+	if nextcloudClient == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Nextcloud が設定されていません"})
+		return
+	}
+
+	var req models.TaskWriteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("リクエストが不正です: %v", err)})
+		return
+	}
+
+	task, err := nextcloudClient.UpdateTask(ctx, ctx.Param("id"), req)
+	if err != nil {
+		respondWriteError(ctx, "タスク更新", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, task)
+}
+
+// DeleteTask は DELETE /api/tasks/:id のハンドラーなのです。
+func DeleteTask(ctx *gin.Context) {
+	nextcloudClient := getNextcloudClient(ctx)
+	if nextcloudClient == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Nextcloud が設定されていません"})
+		return
+	}
+
+	if err := nextcloudClient.DeleteTask(ctx, ctx.Param("id")); err != nil {
+		respondWriteError(ctx, "タスク削除", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
 // ============================================================================
 // /api/weather ハンドラー
 // ============================================================================
 
+// defaultHourlyHours は /api/weather/hourly の hours 省略時の時間数なのです。
+const defaultHourlyHours = 24
+
+// defaultHistoryDays は /api/weather/history の days 省略時の日数なのです。
+const defaultHistoryDays = 7
+
 // GetWeather は /api/weather のGETハンドラーなのです。
 // 現在の天候・今日の気温・降水確率・警報を返すもなのです。
-// 設定から都市名を取得して、weather クライアントで Open-Meteo API から
-// 気象庁データを含む最新の天気情報を取得するます。
+// location クエリ（地点ID、省略時は既定の地点）の都市名を設定から取得して、
+// weather クライアントで最新の天気情報を取得し、気象庁の注意報・警報を合成するます。
+// 注意報・警報は既定の地点（location.alerts の地域）だけに合成するのです。
+// lang クエリ（ja-hiragana / ja-kanji / en、省略時は weather.locale）で表示言語を選べるます。
 func GetWeather(ctx *gin.Context) {
 	// コンテキストから設定と weather クライアントを取得するます
 	cfgRaw, exists := ctx.Get("config")
@@ -174,48 +333,518 @@ func GetWeather(ctx *gin.Context) {
 	}
 	weatherClient := weatherRaw.(*weather.Client)
 
-	// 設定から都市名と国を取得するます
-	cityName := cfg.Location.CityName
-	if cityName == "" {
-		cityName = "姫路市" // デフォルト都市
+	location, ok := resolveLocation(ctx, cfg)
+	if !ok {
+		return
+	}
+	localizer, ok := resolveLocalizer(ctx, cfg)
+	if !ok {
+		return
+	}
+
+	weatherRsp, _ := loadWeather(ctx, cfg, weatherClient, location, localizer)
+
+	// 注意報・警報は天気とは別に気象庁から取得して合成するます
+	if location.ID == primaryLocation(cfg).ID {
+		attachAlerts(ctx, cfg, weatherRsp)
+	}
+
+	// 大気質・花粉も天気とは別のキャッシュ・更新間隔で取得して合成するます
+	attachAirQuality(ctx, cfg, weatherClient, location, weatherRsp)
+
+	// 単位の変換と表示言語への置き換えは合成したあとに行うのです
+	localizer.Localize(weatherRsp)
+	ctx.JSON(http.StatusOK, weatherRsp)
+}
+
+// GetWeatherHourly は /api/weather/hourly のGETハンドラーなのです。
+// hours クエリ（1〜48、省略時は24）で指定した時間数の時間ごとの予報を返すます。
+// 天気データは /api/weather と同じキャッシュから取り出すのです（location・lang クエリも同じなのです）。
+func GetWeatherHourly(ctx *gin.Context) {
+	hours := defaultHourlyHours
+	if hoursRaw := ctx.Query("hours"); hoursRaw != "" {
+		parsed, err := strconv.Atoi(hoursRaw)
+		if err != nil || parsed < 1 || parsed > weather.MaxHourlyHours {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("hours は 1〜%d の整数で指定してください: %s", weather.MaxHourlyHours, hoursRaw),
+			})
+			return
+		}
+		hours = parsed
+	}
+
+	cfg := getConfig(ctx)
+	if cfg == nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "設定が見つからないです",
+		})
+		return
+	}
+	weatherClient := getWeatherClient(ctx)
+	if weatherClient == nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "天気クライアントが見つかりません",
+		})
+		return
+	}
+
+	location, ok := resolveLocation(ctx, cfg)
+	if !ok {
+		return
+	}
+
+	localizer, ok := resolveLocalizer(ctx, cfg)
+	if !ok {
+		return
+	}
+
+	weatherRsp, _ := loadWeather(ctx, cfg, weatherClient, location, localizer)
+	hourly := hourlyWithin(weatherRsp.Hourly, time.Now(), hours)
+	localizer.LocalizeHourly(hourly)
+	ctx.JSON(http.StatusOK, models.HourlyWeatherResponse{
+		Lang:     localizer.Lang(),
+		Units:    localizer.Units(),
+		Location: weatherRsp.Location,
+		Hours:    hourly,
+	})
+}
+
+// GetWeatherAll は /api/weather/all のGETハンドラーなのです。
+// 設定したすべての地点の現在の天候と今日の気温を設定順に返すます。
+// 地点ごとに別のキャッシュを使い、取得に失敗した地点は error に理由を入れるのです（lang クエリも使えるます）。
+func GetWeatherAll(ctx *gin.Context) {
+	cfg := getConfig(ctx)
+	if cfg == nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "設定が見つからないです",
+		})
+		return
+	}
+	weatherClient := getWeatherClient(ctx)
+	if weatherClient == nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "天気クライアントが見つかりません",
+		})
+		return
+	}
+
+	localizer, ok := resolveLocalizer(ctx, cfg)
+	if !ok {
+		return
+	}
+
+	summaries := []models.WeatherSummary{}
+	for _, location := range cfg.GetLocations() {
+		weatherRsp, err := loadWeather(ctx, cfg, weatherClient, location, localizer)
+		localizer.Localize(weatherRsp)
+		summary := models.WeatherSummary{
+			ID:       location.ID,
+			Name:     location.Name,
+			Location: weatherRsp.Location,
+			Current:  weatherRsp.Current,
+			Today:    weatherRsp.Today,
+		}
+		if err != nil {
+			summary.Error = err.Error()
+		}
+		summaries = append(summaries, summary)
+	}
+
+	ctx.JSON(http.StatusOK, models.WeatherAllResponse{
+		Lang:      localizer.Lang(),
+		Units:     localizer.Units(),
+		Locations: summaries,
+	})
+}
+
+// GetWeatherHistory は /api/weather/history のGETハンドラーなのです。
+// 記録した観測値を日ごとに集計して、今日を含む直近 days 日分（1〜保持日数、省略時は7）の
+// 最高・最低気温と降水量を返すます。今日の予報と昨日の記録の気温差（vsYesterday）も返すのです。
+// 記録は℃・mm で、返すときに weather.units と lang クエリに合わせるます。
+func GetWeatherHistory(ctx *gin.Context) {
+	cfg := getConfig(ctx)
+	if cfg == nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "設定が見つからないです",
+		})
+		return
+	}
+	weatherClient := getWeatherClient(ctx)
+	if weatherClient == nil || weatherClient.History() == nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "天気の履歴が見つかりません",
+		})
+		return
+	}
+	history := weatherClient.History()
+
+	days := defaultHistoryDays
+	if daysRaw := ctx.Query("days"); daysRaw != "" {
+		parsed, err := strconv.Atoi(daysRaw)
+		if err != nil || parsed < 1 || parsed > history.RetentionDays() {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("days は 1〜%d の整数で指定してください: %s", history.RetentionDays(), daysRaw),
+			})
+			return
+		}
+		days = parsed
+	}
+
+	location, ok := resolveLocation(ctx, cfg)
+	if !ok {
+		return
+	}
+	localizer, ok := resolveLocalizer(ctx, cfg)
+	if !ok {
+		return
+	}
+	cityName, country := locationCity(location)
+
+	daily, err := history.Daily(cityName, country, days)
+	if err != nil {
+		fmt.Printf("❌ 天気の履歴の読み込みエラー: %v\n", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error": "天気の履歴の読み込みに失敗しました",
+		})
+		return
+	}
+
+	localizer.LocalizeHistory(daily)
+	response := models.WeatherHistoryResponse{
+		Lang:       localizer.Lang(),
+		Units:      localizer.Units(),
+		LocationID: location.ID,
+		Location:   cityName,
+		Days:       daily,
+	}
+
+	// 昨日との比較は今日の予報をキャッシュからだけ読むのです（履歴の表示で天気を取得し直さないため、キャッシュが無ければ比較しないのです）
+	if fc := getCache(ctx); fc != nil {
+		var cachedWeather models.WeatherResponse
+		if _, found, _, err := fc.ReadPayload(weatherCacheKey(location), 0, &cachedWeather); found && err == nil {
+			if recent, err := history.Daily(cityName, country, 2); err == nil {
+				response.VsYesterday = weather.CompareWithYesterday(recent, cachedWeather.Today, localizer, time.Now())
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// ============================================================================
+// /api/admin ハンドラー
+// ============================================================================
+
+// GetAdminCollections は /api/admin/collections のGETハンドラーなのです。
+// 全アカウントの CalDAV サーバーのカレンダー・タスクリストを検出して、表示名・色・対応する種類を返すます。
+// settings.json の calendarNames / taskListNames に書く名前を調べるためのものなのです。
+func GetAdminCollections(ctx *gin.Context) {
+	nextcloudClient := getNextcloudClient(ctx)
+	if nextcloudClient == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Nextcloud が設定されていません"})
+		return
+	}
+
+	// 一部のアカウントだけ失敗した場合は、検出できたコレクションとエラーを返すます
+	collections, err := nextcloudClient.ListCollections(ctx)
+	response := models.CollectionsResponse{Collections: collections}
+	if err != nil {
+		fmt.Printf("❌ CalDAV コレクション検出エラー: %v\n", err)
+		if len(collections) == 0 {
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("コレクションの検出に失敗しました: %v", err)})
+			return
+		}
+		response.Error = err.Error()
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// resolveLocation は location クエリの地点を返すます（省略時は既定の地点）。
+// 設定に無い地点IDの場合は 404 を返して ok=false なのです。
+func resolveLocation(ctx *gin.Context, cfg *config.Config) (config.Location, bool) {
+	locationID := ctx.Query("location")
+	if locationID == "" {
+		return primaryLocation(cfg), true
 	}
-	country := cfg.Location.Country
-	if country == "" {
-		country = "JP" // デフォルト国コード
+	location, found := cfg.FindLocation(locationID)
+	if !found {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("地点が見つかりません: %s", locationID),
+		})
+		return config.Location{}, false
 	}
+	return location, true
+}
+
+// resolveLocalizer は lang クエリ（省略時は weather.locale）と weather.units の Localizer を返すます。
+// 対応していない言語の場合は 400 を返して ok=false なのです。
+func resolveLocalizer(ctx *gin.Context, cfg *config.Config) (*weather.Localizer, bool) {
+	lang := ctx.Query("lang")
+	if lang == "" {
+		lang = cfg.Weather.GetLocale()
+	}
+	if !config.IsLocale(lang) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("lang は %s のいずれかを指定してください: %s", strings.Join(config.Locales, " / "), lang),
+		})
+		return nil, false
+	}
+	return weather.NewLocalizer(lang, cfg.Weather.Units), true
+}
+
+// loadWeather は地点の天気を取得するます（キャッシュから または API から）。
+// 取得に失敗した場合はエラーを記録して、期限切れのキャッシュか「データ取得失敗」をエラーと一緒に返すのです。
+// 値は変換前（℃・m/s・mm）のままで、アドバイスの文言だけ localizer の表示言語と単位で作るのです。
+func loadWeather(ctx *gin.Context, cfg *config.Config, weatherClient *weather.Client, location config.Location, localizer *weather.Localizer) (*models.WeatherResponse, error) {
+	// 地点の都市名と国を取得するます
+	cityName, country := locationCity(location)
+	source := locationSource(cfg, location, "weather")
 
 	// 天気データを取得するます（キャッシュから または API から）
 	weatherRsp, err := weatherClient.GetWeather(ctx, cityName, country)
+	if weatherRsp != nil {
+		// アドバイスは設定と現在時刻で変わるので、キャッシュせずに毎回判定するのです
+		weatherRsp.Advice = weather.BuildAdvice(weatherRsp, cfg.Advice, localizer, time.Now())
+	}
 	if err != nil {
 		// エラーが発生した場合、ログに出力してキャッシュを優先するます
-		fmt.Printf("❌ 天気データ取得エラー: %v\n", err)
-		setSourceError(ctx, "weather", err)
-		if weatherRsp != nil {
-			ctx.JSON(http.StatusOK, weatherRsp)
-			return
+		fmt.Printf("❌ 天気データ取得エラー（%s）: %v\n", location.ID, err)
+		setSourceError(ctx, source, err)
+		if weatherRsp == nil {
+			weatherRsp = &models.WeatherResponse{
+				Location: cityName,
+				Current: models.CurrentWeather{
+					Temperature: 0,
+					Condition:   weather.ConditionUnavailable,
+					Icon:        "04u",
+					IsDay:       true,
+					Humidity:    0,
+					WindSpeed:   0,
+				},
+				Today: models.TodayWeather{
+					MaxTemp: 0,
+					MinTemp: 0,
+					Summary: weather.ConditionUnavailable,
+				},
+				PrecipSlots: []models.PrecipSlot{},
+				Hourly:      []models.HourlyWeather{},
+				Alerts:      []models.WeatherAlert{},
+				Advice:      []models.Advice{},
+			}
 		}
-		ctx.JSON(http.StatusOK, &models.WeatherResponse{
-			Location: cityName,
-			Current: models.CurrentWeather{
-				Temperature: 0,
-				Condition:   "データ取得失敗",
-				Icon:        "04u",
-				Humidity:    0,
-				WindSpeed:   0,
-			},
-			Today: models.TodayWeather{
-				MaxTemp: 0,
-				MinTemp: 0,
-				Summary: "データ取得失敗",
-			},
-			PrecipSlots: []models.PrecipSlot{},
-			Alerts:      []models.WeatherAlert{},
-		})
+	} else {
+		clearSourceError(ctx, source)
+	}
+
+	weatherRsp.LocationID = location.ID
+	return weatherRsp, err
+}
+
+// hourlyWithin は時間ごとの予報から、現在の時間帯から hours 時間分を取り出すます。
+// キャッシュが古くても過ぎた時間帯は返さないのです。
+func hourlyWithin(hourly []models.HourlyWeather, now time.Time, hours int) []models.HourlyWeather {
+	start := now.Truncate(time.Hour)
+	end := start.Add(time.Duration(hours) * time.Hour)
+	result := []models.HourlyWeather{}
+	for _, hour := range hourly {
+		t, err := time.Parse(time.RFC3339, hour.Time)
+		if err != nil || t.Before(start) || !t.Before(end) {
+			continue
+		}
+		result = append(result, hour)
+	}
+	return result
+}
+
+// attachAlerts は気象庁の注意報・警報を天気レスポンスに合成するます。
+// 取得に失敗しても天気は返し、直近のキャッシュ（なければ空）の警報を使うのです。
+func attachAlerts(ctx *gin.Context, cfg *config.Config, weatherRsp *models.WeatherResponse) {
+	if !cfg.Alerts.Enabled() {
+		return
+	}
+	alertsClient := getAlertsClient(ctx)
+	if alertsClient == nil {
 		return
 	}
 
-	clearSourceError(ctx, "weather")
-	ctx.JSON(http.StatusOK, weatherRsp)
+	alerts, err := alertsClient.GetAlerts(ctx, cfg.Alerts.GetOfficeCode(), cfg.Alerts.AreaCode)
+	if err != nil {
+		fmt.Printf("❌ 注意報・警報取得エラー: %v\n", err)
+		setSourceError(ctx, "alerts", err)
+	} else {
+		clearSourceError(ctx, "alerts")
+	}
+	weatherRsp.Alerts = alerts
+}
+
+// attachAirQuality は大気質・花粉を天気レスポンスに合成するます。
+// 取得に失敗しても天気は返し、直近のキャッシュ（なければ null）を使うのです。
+func attachAirQuality(ctx *gin.Context, cfg *config.Config, weatherClient *weather.Client, location config.Location, weatherRsp *models.WeatherResponse) {
+	cityName, country := locationCity(location)
+	source := locationSource(cfg, location, "airQuality")
+	airQuality, err := weatherClient.GetAirQuality(ctx, cityName, country)
+	if err != nil {
+		fmt.Printf("❌ 大気質・花粉取得エラー（%s）: %v\n", location.ID, err)
+		setSourceError(ctx, source, err)
+	} else {
+		clearSourceError(ctx, source)
+	}
+	weatherRsp.AirQuality = airQuality
+}
+
+// parseCalendarRange は /api/calendar の from/days クエリを検証して表示範囲を返すます。
+func parseCalendarRange(ctx *gin.Context, cfg *config.Config) (time.Time, int, error) {
+	today := todayTokyo()
+
+	startDate := today
+	if from := ctx.Query("from"); from != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", from, today.Location())
+		if err != nil {
+			return time.Time{}, 0, fmt.Errorf("from は YYYY-MM-DD 形式で指定してください: %s", from)
+		}
+		if parsed.Before(today.AddDate(0, 0, -calendarMaxFromOffsetDays)) || parsed.After(today.AddDate(0, 0, calendarMaxFromOffsetDays)) {
+			return time.Time{}, 0, fmt.Errorf("from は今日から前後%d日以内で指定してください: %s", calendarMaxFromOffsetDays, from)
+		}
+		startDate = parsed
+	}
+
+	days := config.DefaultCalendarDays
+	if cfg != nil {
+		days = cfg.GetCalendarDays()
+	}
+	if daysRaw := ctx.Query("days"); daysRaw != "" {
+		parsed, err := strconv.Atoi(daysRaw)
+		if err != nil || parsed < 1 || parsed > config.MaxCalendarDays {
+			return time.Time{}, 0, fmt.Errorf("days は 1〜%d の整数で指定してください: %s", config.MaxCalendarDays, daysRaw)
+		}
+		days = parsed
+	}
+
+	return startDate, days, nil
+}
+
+// todayTokyo は Asia/Tokyo の今日0時を返すます。
+func todayTokyo() time.Time {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		loc = time.FixedZone("Asia/Tokyo", 9*3600)
+	}
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// getNextcloudClient はコンテキストから Nextcloud クライアントを取り出すます。
+// 未設定の場合は nil を返すのです。
+func getNextcloudClient(ctx *gin.Context) *nextcloud.Client {
+	raw, exists := ctx.Get("nextcloud")
+	if !exists {
+		return nil
+	}
+	client, ok := raw.(*nextcloud.Client)
+	if !ok {
+		return nil
+	}
+	return client
+}
+
+// getWeatherClient はコンテキストから天気クライアントを取り出すます。
+// 未設定の場合は nil を返すのです。
+func getWeatherClient(ctx *gin.Context) *weather.Client {
+	raw, exists := ctx.Get("weather")
+	if !exists {
+		return nil
+	}
+	client, ok := raw.(*weather.Client)
+	if !ok {
+		return nil
+	}
+	return client
+}
+
+// getAlertsClient はコンテキストから気象庁クライアントを取り出すます。
+// 未設定の場合は nil を返すのです。
+func getAlertsClient(ctx *gin.Context) *jma.Client {
+	raw, exists := ctx.Get("alerts")
+	if !exists {
+		return nil
+	}
+	client, ok := raw.(*jma.Client)
+	if !ok {
+		return nil
+	}
+	return client
+}
+
+// respondWriteError は書き込み系エラーを HTTP ステータスに変換して返すます。
+// ETag 不一致（他の端末での更新）は 409 で分かりやすいメッセージを返すのです。
+func respondWriteError(ctx *gin.Context, action string, err error) {
+	fmt.Printf("❌ %sエラー: %v\n", action, err)
+
+	switch {
+	case errors.Is(err, nextcloud.ErrInvalidInput):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, nextcloud.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": nextcloud.ErrNotFound.Error()})
+	case errors.Is(err, nextcloud.ErrConflict):
+		ctx.JSON(http.StatusConflict, gin.H{"error": nextcloud.ErrConflict.Error()})
+	default:
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("%sに失敗しました", action)})
+	}
+}
+
+// configLocations は設定の地点一覧を返すます（設定が無ければ既定IDの1地点）。
+func configLocations(cfg *config.Config) []config.Location {
+	if cfg == nil {
+		return []config.Location{{ID: config.DefaultLocationID}}
+	}
+	return cfg.GetLocations()
+}
+
+// primaryLocation は既定の地点を返すます。
+func primaryLocation(cfg *config.Config) config.Location {
+	return configLocations(cfg)[0]
+}
+
+// locationCity は地点の都市名と国コードを返すます（未設定なら姫路市, JP）。
+func locationCity(location config.Location) (string, string) {
+	cityName := "姫路市" // デフォルト都市
+	country := "JP"   // デフォルト国コード
+	if location.CityName != "" {
+		cityName = location.CityName
+	}
+	if location.Country != "" {
+		country = location.Country
+	}
+	return cityName, country
+}
+
+// locationSource は地点ごとのエラーソース名を返すます。
+// 既定の地点は従来どおり source のまま、それ以外は "source:<地点ID>" なのです。
+func locationSource(cfg *config.Config, location config.Location, source string) string {
+	if location.ID == primaryLocation(cfg).ID {
+		return source
+	}
+	return source + ":" + location.ID
+}
+
+// weatherCacheKey は地点の天気キャッシュキーを返すます。
+func weatherCacheKey(location config.Location) string {
+	return weather.CacheKey(locationCity(location))
+}
+
+// airQualityCacheKey は地点の大気質・花粉キャッシュキーを返すます。
+func airQualityCacheKey(location config.Location) string {
+	return weather.AirQualityCacheKey(locationCity(location))
+}
+
+// defaultCalendarCacheKey は既定の表示範囲（今日から defaultDays 日分）のカレンダーキャッシュキーを返すます。
+func defaultCalendarCacheKey(cfg *config.Config) string {
+	calendarDays := config.DefaultCalendarDays
+	if cfg != nil {
+		calendarDays = cfg.GetCalendarDays()
+	}
+	return nextcloud.CalendarCacheKey(todayTokyo(), calendarDays)
 }
 
 func getCache(ctx *gin.Context) *cache.FileCache {
@@ -276,3 +905,11 @@ func readFetchedAt(fc *cache.FileCache, cacheKey string) string {
 	}
 	return entry.FetchedAt
 }
+
+func readMeta(fc *cache.FileCache, cacheKey, name string) string {
+	entry, exists, _, err := fc.Read(cacheKey, 0)
+	if err != nil || !exists {
+		return ""
+	}
+	return entry.Meta[name]
+}
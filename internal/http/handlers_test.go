@@ -1,10 +1,13 @@
 package http
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,12 +15,28 @@ import (
 	"github.com/rihow/FamilyDashboard/internal/cache"
 	"github.com/rihow/FamilyDashboard/internal/config"
 	"github.com/rihow/FamilyDashboard/internal/models"
+	"github.com/rihow/FamilyDashboard/internal/services/jma"
 	"github.com/rihow/FamilyDashboard/internal/services/nextcloud"
 	"github.com/rihow/FamilyDashboard/internal/services/weather"
 	"github.com/rihow/FamilyDashboard/internal/status"
 )
 
+// testEnv はテスト用ルーターと依存オブジェクトの組なのです。
+type testEnv struct {
+	router    *gin.Engine
+	cache     *cache.FileCache
+	config    *config.Config
+	hub       *EventHub
+	weather   *weather.Client
+	nextcloud *nextcloud.Client // テスト中に差し替えられるのです（設定を変えたら作り直すます）
+}
+
 func setupTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	return newTestEnv(t).router
+}
+
+func newTestEnv(t *testing.T) *testEnv {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -44,27 +63,31 @@ func setupTestRouter(t *testing.T) *gin.Engine {
 	fc := cache.New(t.TempDir())
 	seedCache(t, fc, cfg)
 
-	weatherClient := weather.NewClient(fc, "http://localhost:8080")
+	weatherClient := weather.NewClient(fc, "http://localhost:8080", weather.Options{})
 	nextcloudClient, _ := nextcloud.NewClient(fc, cfg)
 	errorStore := status.NewErrorStore()
+	hub := NewEventHub(fc, cfg, errorStore)
+	env := &testEnv{router: router, cache: fc, config: cfg, hub: hub, weather: weatherClient, nextcloud: nextcloudClient}
 
 	router.Use(func(ctx *gin.Context) {
 		ctx.Set("config", cfg)
 		ctx.Set("cache", fc)
 		ctx.Set("weather", weatherClient)
-		ctx.Set("nextcloud", nextcloudClient)
+		ctx.Set("alerts", jma.NewClient(fc))
+		ctx.Set("nextcloud", env.nextcloud)
 		ctx.Set("errorStore", errorStore)
+		ctx.Set("events", hub)
 		ctx.Next()
 	})
 
 	SetupRoutes(router)
-	return router
+	return env
 }
 
 func seedCache(t *testing.T, fc *cache.FileCache, cfg *config.Config) {
 	t.Helper()
 
-	weatherKey := fmt.Sprintf("weather:%s:%s", cfg.Location.Country, cfg.Location.CityName)
+	weatherKey := weather.CacheKey(cfg.Location.CityName, cfg.Location.Country)
 	weatherPayload := &models.WeatherResponse{
 		Location: cfg.Location.CityName,
 		Current: models.CurrentWeather{
@@ -80,12 +103,24 @@ func seedCache(t *testing.T, fc *cache.FileCache, cfg *config.Config) {
 			Summary: "晴",
 		},
 		PrecipSlots: []models.PrecipSlot{{Time: "09:00", Precip: 10}},
+		Hourly:      seedHourly(),
 		Alerts:      []models.WeatherAlert{},
 	}
 	if _, err := fc.Write(weatherKey, weatherPayload, map[string]string{"source": "test"}); err != nil {
 		t.Fatalf("seed weather cache: %v", err)
 	}
 
+	airQualityPayload := &models.AirQuality{
+		PM25:     12.5,
+		PM10:     20.1,
+		AQI:      52,
+		Category: "ふつう",
+		Pollen:   []models.PollenLevel{},
+	}
+	if _, err := fc.Write(weather.AirQualityCacheKey(cfg.Location.CityName, cfg.Location.Country), airQualityPayload, map[string]string{"source": "test"}); err != nil {
+		t.Fatalf("seed air quality cache: %v", err)
+	}
+
 	calendarPayload := &models.CalendarResponse{
 		Days: []models.CalendarDay{
 			{
@@ -104,7 +139,29 @@ func seedCache(t *testing.T, fc *cache.FileCache, cfg *config.Config) {
 			},
 		},
 	}
-	if _, err := fc.Write("nextcloud_calendar_events_all", calendarPayload, map[string]string{"source": "test"}); err != nil {
+	if _, err := fc.Write(nextcloud.CalendarCacheKey(todayTokyo(), cfg.GetCalendarDays()), calendarPayload, map[string]string{"source": "test"}); err != nil {
+		t.Fatalf("seed calendar cache: %v", err)
+	}
+
+	shortCalendarPayload := &models.CalendarResponse{
+		Days: []models.CalendarDay{
+			{
+				Date:   time.Now().Format("2006-01-02"),
+				AllDay: []models.Event{},
+				Timed: []models.Event{
+					{
+						ID:       "seed-event-3d",
+						Title:    "テスト（3日表示）",
+						Start:    time.Now().Format(time.RFC3339),
+						End:      time.Now().Add(1 * time.Hour).Format(time.RFC3339),
+						Color:    "#A4BDFC",
+						Calendar: "shared",
+					},
+				},
+			},
+		},
+	}
+	if _, err := fc.Write(nextcloud.CalendarCacheKey(todayTokyo(), 3), shortCalendarPayload, map[string]string{"source": "test"}); err != nil {
 		t.Fatalf("seed calendar cache: %v", err)
 	}
 
@@ -121,11 +178,28 @@ func seedCache(t *testing.T, fc *cache.FileCache, cfg *config.Config) {
 			},
 		},
 	}
-	if _, err := fc.Write("nextcloud_tasks_items_all", tasksPayload, map[string]string{"source": "test"}); err != nil {
+	if _, err := fc.Write(nextcloud.TasksCacheKey, tasksPayload, map[string]string{"source": "test"}); err != nil {
 		t.Fatalf("seed tasks cache: %v", err)
 	}
 }
 
+// seedHourly は1時間前から30時間分の時間ごとの予報を作るます。
+func seedHourly() []models.HourlyWeather {
+	start := time.Now().Truncate(time.Hour).Add(-1 * time.Hour)
+	hourly := []models.HourlyWeather{}
+	for i := 0; i < 30; i++ {
+		hourly = append(hourly, models.HourlyWeather{
+			Time:        start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339),
+			Temperature: 10 + float64(i%5),
+			PrecipProb:  i * 3,
+			WeatherCode: 0,
+			Condition:   "はれ",
+			Icon:        "01d",
+		})
+	}
+	return hourly
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -137,6 +211,14 @@ func performRequest(router *gin.Engine, method, path string) *httptest.ResponseR
 	return rec
 }
 
+func performJSONRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
 func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, out any) {
 	t.Helper()
 	if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
@@ -172,6 +254,9 @@ func TestGetStatus(t *testing.T) {
 	if _, err := time.Parse(time.RFC3339, payload.LastUpdated.Tasks); err != nil {
 		t.Fatalf("tasks lastUpdated parse error: %v", err)
 	}
+	if payload.WeatherProvider != "test" {
+		t.Fatalf("weatherProvider = %q", payload.WeatherProvider)
+	}
 }
 
 func TestGetCalendar(t *testing.T) {
@@ -194,6 +279,50 @@ func TestGetCalendar(t *testing.T) {
 	}
 }
 
+func TestGetCalendarInvalidRange(t *testing.T) {
+	router := setupTestRouter(t)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "invalid from", path: "/api/calendar?from=2026/03/01"},
+		{name: "from too far", path: "/api/calendar?from=" + todayTokyo().AddDate(2, 0, 0).Format("2006-01-02")},
+		{name: "days zero", path: "/api/calendar?days=0"},
+		{name: "days too large", path: "/api/calendar?days=32"},
+		{name: "days not number", path: "/api/calendar?days=abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := performRequest(router, http.MethodGet, tt.path)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status code = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestGetCalendarCustomRange(t *testing.T) {
+	router := setupTestRouter(t)
+	rec := performRequest(router, http.MethodGet, "/api/calendar?days=3")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code = %d", rec.Code)
+	}
+
+	var payload models.CalendarResponse
+	decodeJSON(t, rec, &payload)
+
+	// 範囲ごとにキャッシュキーが分かれているので、3日分のキャッシュが返るはず
+	if len(payload.Days) == 0 || len(payload.Days[0].Timed) == 0 {
+		t.Fatalf("timed events are empty")
+	}
+	if got := payload.Days[0].Timed[0].ID; got != "seed-event-3d" {
+		t.Fatalf("event id = %s, want seed-event-3d", got)
+	}
+}
+
 func TestGetTasks(t *testing.T) {
 	router := setupTestRouter(t)
 	rec := performRequest(router, http.MethodGet, "/api/tasks")
@@ -221,6 +350,37 @@ func TestGetTasks(t *testing.T) {
 	}
 }
 
+func TestTaskWriteInvalidRequest(t *testing.T) {
+	router := setupTestRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "broken json", method: http.MethodPost, path: "/api/tasks", body: "{"},
+		{name: "title missing", method: http.MethodPost, path: "/api/tasks", body: `{"notes":"メモ"}`},
+		{name: "unknown task list", method: http.MethodPost, path: "/api/tasks", body: `{"title":"買い物","taskList":"unknown"}`},
+		{name: "patch broken json", method: http.MethodPatch, path: "/api/tasks/seed-task", body: "["},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := performJSONRequest(router, tt.method, tt.path, tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status code = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+
+			var payload map[string]string
+			decodeJSON(t, rec, &payload)
+			if payload["error"] == "" {
+				t.Fatalf("error message is empty")
+			}
+		})
+	}
+}
+
 func TestGetWeather(t *testing.T) {
 	router := setupTestRouter(t)
 	rec := performRequest(router, http.MethodGet, "/api/weather")
@@ -238,6 +398,380 @@ func TestGetWeather(t *testing.T) {
 	if payload.Current.Condition == "" {
 		t.Fatalf("current.condition is empty")
 	}
+	// 大気質・花粉は別のキャッシュから合成される
+	if payload.AirQuality == nil || payload.AirQuality.AQI != 52 {
+		t.Fatalf("airQuality = %+v", payload.AirQuality)
+	}
+	// アドバイスはリクエストごとに判定される（最低気温5℃は防寒の既定値以下）
+	if len(payload.Advice) != 1 || payload.Advice[0].Type != config.AdviceCold {
+		t.Fatalf("advice = %+v", payload.Advice)
+	}
+}
+
+func TestGetWeatherLocale(t *testing.T) {
+	env := newTestEnv(t)
+
+	// 既定はひらがな・メートル法
+	rec := performRequest(env.router, http.MethodGet, "/api/weather")
+	var payload models.WeatherResponse
+	decodeJSON(t, rec, &payload)
+	if payload.Lang != config.LocaleJaHiragana || payload.Units.Temperature != "℃" || payload.Advice[0].Message != "うわぎをもっていこう" {
+		t.Fatalf("payload = %+v", payload)
+	}
+
+	// lang クエリで表示言語を選び、単位は設定に合わせる
+	env.config.Weather.Units = config.Units{System: config.UnitsImperial}
+	rec = performRequest(env.router, http.MethodGet, "/api/weather?lang=en")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code = %d", rec.Code)
+	}
+	payload = models.WeatherResponse{}
+	decodeJSON(t, rec, &payload)
+	if payload.Lang != config.LocaleEn || payload.Units.Temperature != "℉" || payload.Units.WindSpeed != "mph" {
+		t.Fatalf("lang/units = %s %+v", payload.Lang, payload.Units)
+	}
+	if payload.Current.Temperature != 50 || payload.Today.MaxTemp != 59 || payload.AirQuality.Category != "Moderate" {
+		t.Fatalf("payload = %+v", payload)
+	}
+	if len(payload.Advice) != 1 || payload.Advice[0].Message != "Bring a jacket" || payload.Advice[0].Reason != "Low of 41℉" {
+		t.Fatalf("advice = %+v", payload.Advice)
+	}
+
+	rec = performRequest(env.router, http.MethodGet, "/api/weather/hourly?hours=1&lang=ja-kanji")
+	var hourly models.HourlyWeatherResponse
+	decodeJSON(t, rec, &hourly)
+	if hourly.Lang != config.LocaleJaKanji || len(hourly.Hours) != 1 || hourly.Hours[0].Condition != "晴れ" || hourly.Units.Temperature != "℉" {
+		t.Fatalf("hourly = %+v", hourly)
+	}
+
+	// 設定の既定の言語
+	env.config.Weather.Locale = config.LocaleJaKanji
+	rec = performRequest(env.router, http.MethodGet, "/api/weather/all")
+	var all models.WeatherAllResponse
+	decodeJSON(t, rec, &all)
+	if all.Lang != config.LocaleJaKanji || all.Locations[0].Current.Temperature != 50 {
+		t.Fatalf("all = %+v", all)
+	}
+
+	for _, path := range []string{"/api/weather?lang=fr", "/api/weather/hourly?lang=ja", "/api/weather/all?lang=EN"} {
+		rec := performRequest(env.router, http.MethodGet, path)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status code = %d", path, rec.Code)
+		}
+	}
+}
+
+func TestGetWeatherHourly(t *testing.T) {
+	router := setupTestRouter(t)
+
+	rec := performRequest(router, http.MethodGet, "/api/weather/hourly?hours=3")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code = %d", rec.Code)
+	}
+	var payload models.HourlyWeatherResponse
+	decodeJSON(t, rec, &payload)
+	if payload.Location != "姫路市" || len(payload.Hours) != 3 {
+		t.Fatalf("payload = %+v", payload)
+	}
+	// 過ぎた時間帯（1時間前）は返さない
+	if want := time.Now().Truncate(time.Hour).Format(time.RFC3339); payload.Hours[0].Time != want {
+		t.Fatalf("hours[0].time = %s, want %s", payload.Hours[0].Time, want)
+	}
+
+	// 省略時は24時間分
+	rec = performRequest(router, http.MethodGet, "/api/weather/hourly")
+	decodeJSON(t, rec, &payload)
+	if len(payload.Hours) != 24 {
+		t.Fatalf("default hours = %d", len(payload.Hours))
+	}
+
+	for _, query := range []string{"hours=0", "hours=49", "hours=abc"} {
+		rec := performRequest(router, http.MethodGet, "/api/weather/hourly?"+query)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status code = %d", query, rec.Code)
+		}
+	}
+}
+
+func TestGetWeatherWithAlerts(t *testing.T) {
+	env := newTestEnv(t)
+	env.config.Alerts = config.Alerts{AreaCode: "2820100"}
+
+	alerts := []models.WeatherAlert{{Title: "大雨警報", Severity: jma.SeverityWarning}}
+	if _, err := env.cache.Write(jma.CacheKey("2820100"), alerts, map[string]string{"source": "test"}); err != nil {
+		t.Fatalf("write alerts cache: %v", err)
+	}
+
+	rec := performRequest(env.router, http.MethodGet, "/api/weather")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code = %d", rec.Code)
+	}
+
+	var payload models.WeatherResponse
+	decodeJSON(t, rec, &payload)
+	if len(payload.Alerts) != 1 || payload.Alerts[0].Title != "大雨警報" {
+		t.Fatalf("alerts = %+v", payload.Alerts)
+	}
+
+	// SSE の weather メッセージにも同じ警報が含まれる
+	env.hub.poll()
+	var event models.WeatherResponse
+	if err := json.Unmarshal(env.hub.latest["weather"].Data, &event); err != nil {
+		t.Fatalf("decode weather event: %v", err)
+	}
+	if len(event.Alerts) != 1 || event.Alerts[0].Severity != jma.SeverityWarning {
+		t.Fatalf("event alerts = %+v", event.Alerts)
+	}
+	if event.AirQuality == nil || event.AirQuality.Category != "ふつう" {
+		t.Fatalf("event airQuality = %+v", event.AirQuality)
+	}
+}
+
+func TestWeatherLocations(t *testing.T) {
+	env := newTestEnv(t)
+	env.config.Alerts = config.Alerts{AreaCode: "2820100"}
+	env.config.Locations = []config.Location{
+		{ID: "home", Name: "おうち", CityName: "姫路市", Country: "JP"},
+		{ID: "grandma", Name: "おばあちゃんち", CityName: "大阪市", Country: "JP"},
+	}
+
+	alerts := []models.WeatherAlert{{Title: "大雨警報", Severity: jma.SeverityWarning}}
+	if _, err := env.cache.Write(jma.CacheKey("2820100"), alerts, map[string]string{"source": "test"}); err != nil {
+		t.Fatalf("write alerts cache: %v", err)
+	}
+	osaka := &models.WeatherResponse{
+		Location: "大阪市",
+		Current:  models.CurrentWeather{Temperature: 25, Condition: "くもり", Icon: "03d"},
+		Today:    models.TodayWeather{MaxTemp: 28, MinTemp: 20, Summary: "くもり"},
+		Hourly:   seedHourly(),
+		Alerts:   []models.WeatherAlert{},
+	}
+	if _, err := env.cache.Write(weather.CacheKey("大阪市", "JP"), osaka, map[string]string{"source": "metno"}); err != nil {
+		t.Fatalf("write weather cache: %v", err)
+	}
+	if _, err := env.cache.Write(weather.AirQualityCacheKey("大阪市", "JP"), &models.AirQuality{AQI: 80, Pollen: []models.PollenLevel{}}, nil); err != nil {
+		t.Fatalf("write air quality cache: %v", err)
+	}
+
+	// 地点IDを指定すると、その地点のキャッシュを返す（注意報・警報は既定の地点だけ）
+	rec := performRequest(env.router, http.MethodGet, "/api/weather?location=grandma")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code = %d", rec.Code)
+	}
+	var payload models.WeatherResponse
+	decodeJSON(t, rec, &payload)
+	if payload.LocationID != "grandma" || payload.Location != "大阪市" || payload.Current.Temperature != 25 {
+		t.Fatalf("payload = %+v", payload)
+	}
+	if len(payload.Alerts) != 0 || payload.AirQuality == nil || payload.AirQuality.AQI != 80 {
+		t.Fatalf("alerts = %+v, airQuality = %+v", payload.Alerts, payload.AirQuality)
+	}
+
+	// 省略時は先頭の地点
+	rec = performRequest(env.router, http.MethodGet, "/api/weather")
+	decodeJSON(t, rec, &payload)
+	if payload.LocationID != "home" || payload.Location != "姫路市" || len(payload.Alerts) != 1 {
+		t.Fatalf("primary payload = %+v", payload)
+	}
+
+	var hourly models.HourlyWeatherResponse
+	rec = performRequest(env.router, http.MethodGet, "/api/weather/hourly?location=grandma&hours=2")
+	decodeJSON(t, rec, &hourly)
+	if hourly.Location != "大阪市" || len(hourly.Hours) != 2 {
+		t.Fatalf("hourly = %+v", hourly)
+	}
+
+	for _, path := range []string{"/api/weather?location=nowhere", "/api/weather/hourly?location=nowhere"} {
+		if rec := performRequest(env.router, http.MethodGet, path); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: status code = %d", path, rec.Code)
+		}
+	}
+
+	// すべての地点の概要を設定順に返す
+	rec = performRequest(env.router, http.MethodGet, "/api/weather/all")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("all: status code = %d", rec.Code)
+	}
+	var all models.WeatherAllResponse
+	decodeJSON(t, rec, &all)
+	if len(all.Locations) != 2 {
+		t.Fatalf("all = %+v", all)
+	}
+	if got := all.Locations[0]; got.ID != "home" || got.Name != "おうち" || got.Current.Temperature != 10 || got.Error != "" {
+		t.Fatalf("all[0] = %+v", got)
+	}
+	if got := all.Locations[1]; got.ID != "grandma" || got.Location != "大阪市" || got.Today.MaxTemp != 28 || got.Error != "" {
+		t.Fatalf("all[1] = %+v", got)
+	}
+
+	// /api/status に地点ごとの最終更新時刻と提供元が含まれる
+	rec = performRequest(env.router, http.MethodGet, "/api/status")
+	var statusResp models.StatusResponse
+	decodeJSON(t, rec, &statusResp)
+	if len(statusResp.Locations) != 2 {
+		t.Fatalf("status locations = %+v", statusResp.Locations)
+	}
+	for i, want := range []string{"test", "metno"} {
+		location := statusResp.Locations[i]
+		if location.Provider != want {
+			t.Fatalf("locations[%d].provider = %q, want %q", i, location.Provider, want)
+		}
+		if _, err := time.Parse(time.RFC3339, location.LastUpdated); err != nil {
+			t.Fatalf("locations[%d].lastUpdated parse error: %v", i, err)
+		}
+	}
+}
+
+func TestGetWeatherHistory(t *testing.T) {
+	env := newTestEnv(t)
+
+	// 履歴ストアが無ければ 500
+	if rec := performRequest(env.router, http.MethodGet, "/api/weather/history"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("no history: status code = %d", rec.Code)
+	}
+
+	store := weather.NewHistoryStore(t.TempDir(), 30)
+	env.weather.SetHistory(store)
+	today := todayTokyo()
+	yesterday := today.AddDate(0, 0, -1)
+	at := func(day time.Time, hour int) string {
+		return day.Add(time.Duration(hour) * time.Hour).Format(time.RFC3339)
+	}
+	for _, obs := range []weather.Observation{
+		{Time: at(yesterday, 6), Temperature: 3, PrecipHour: at(yesterday, 6), PrecipAmount: 1.2},
+		{Time: at(yesterday, 14), Temperature: 7, PrecipHour: at(yesterday, 14), PrecipAmount: 0.5},
+		{Time: at(today, 0), Temperature: 4},
+	} {
+		if err := store.Append("姫路市", "JP", obs); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	rec := performRequest(env.router, http.MethodGet, "/api/weather/history?days=3")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code = %d", rec.Code)
+	}
+	var payload models.WeatherHistoryResponse
+	decodeJSON(t, rec, &payload)
+	if payload.LocationID != "home" || payload.Location != "姫路市" || len(payload.Days) != 2 {
+		t.Fatalf("payload = %+v", payload)
+	}
+	if got := payload.Days[0]; got.Date != yesterday.Format("2006-01-02") || got.MaxTemp != 7 || got.MinTemp != 3 || got.Precipitation != 1.7 || got.Samples != 2 {
+		t.Fatalf("days[0] = %+v", got)
+	}
+	// 今日の予報（最高15℃）と昨日の記録（最高7℃）の比較
+	if payload.VsYesterday == nil || payload.VsYesterday.MaxTempDelta != 8 || payload.VsYesterday.Message != "きのうより8℃たかい" {
+		t.Fatalf("vsYesterday = %+v", payload.VsYesterday)
+	}
+
+	// 昨日だけを返す日数でも比較はする
+	rec = performRequest(env.router, http.MethodGet, "/api/weather/history?days=1")
+	decodeJSON(t, rec, &payload)
+	if len(payload.Days) != 1 || payload.VsYesterday == nil {
+		t.Fatalf("days=1 payload = %+v", payload)
+	}
+
+	// 天気のキャッシュが無ければ比較せず、プロバイダからも取得しないのです
+	provider := &countingProvider{}
+	env.weather.SetProviders(provider)
+	if err := env.cache.Delete(weather.CacheKey("姫路市", "JP")); err != nil {
+		t.Fatalf("delete weather cache: %v", err)
+	}
+	rec = performRequest(env.router, http.MethodGet, "/api/weather/history?days=3")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("no weather cache: status code = %d", rec.Code)
+	}
+	payload = models.WeatherHistoryResponse{}
+	decodeJSON(t, rec, &payload)
+	if len(payload.Days) != 2 || payload.VsYesterday != nil {
+		t.Fatalf("no weather cache: payload = %+v", payload)
+	}
+	if provider.calls != 0 {
+		t.Fatalf("provider calls = %d, want 0", provider.calls)
+	}
+
+	for _, query := range []string{"days=0", "days=31", "days=abc"} {
+		if rec := performRequest(env.router, http.MethodGet, "/api/weather/history?"+query); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status code = %d", query, rec.Code)
+		}
+	}
+	if rec := performRequest(env.router, http.MethodGet, "/api/weather/history?location=nowhere"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown location: status code = %d", rec.Code)
+	}
+}
+
+// countingProvider は呼ばれた回数を数えて失敗するだけの天気プロバイダなのです。
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) Fetch(ctx context.Context, lat, lon float64, cityName string) (*models.WeatherResponse, error) {
+	p.calls++
+	return nil, context.DeadlineExceeded
+}
+
+func TestGetAdminCollections(t *testing.T) {
+	env := newTestEnv(t)
+
+	// principal → calendar-home-set → コレクション一覧の順に応答する CalDAV サーバー
+	responses := map[string]string{
+		"/remote.php/dav/": `<d:response><d:href>/remote.php/dav/</d:href><d:propstat><d:prop>` +
+			`<d:current-user-principal><d:href>/remote.php/dav/principals/users/testuser/</d:href></d:current-user-principal>` +
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+		"/remote.php/dav/principals/users/testuser/": `<d:response><d:href>/remote.php/dav/principals/users/testuser/</d:href><d:propstat><d:prop>` +
+			`<cal:calendar-home-set><d:href>/remote.php/dav/calendars/testuser/</d:href></cal:calendar-home-set>` +
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+		"/remote.php/dav/calendars/testuser/": `<d:response><d:href>/remote.php/dav/calendars/testuser/</d:href><d:propstat><d:prop>` +
+			`<d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>` +
+			`<d:response><d:href>/remote.php/dav/calendars/testuser/family/</d:href><d:propstat><d:prop>` +
+			`<d:resourcetype><d:collection/><cal:calendar/></d:resourcetype><d:displayname>家族</d:displayname>` +
+			`<a:calendar-color>#0082C9</a:calendar-color>` +
+			`<cal:supported-calendar-component-set><cal:comp name="VEVENT"/></cal:supported-calendar-component-set>` +
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>` +
+			`<d:response><d:href>/remote.php/dav/calendars/testuser/inbox/</d:href><d:propstat><d:prop>` +
+			`<d:resourcetype><d:collection/><cal:schedule-inbox/></d:resourcetype>` +
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if r.Method != "PROPFIND" || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav" xmlns:a="http://apple.com/ns/ical/">` +
+			body + `</d:multistatus>`))
+	}))
+	defer server.Close()
+	env.config.Nextcloud.ServerURL = server.URL
+	client, err := nextcloud.NewClient(env.cache, env.config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	env.nextcloud = client
+
+	rec := performRequest(env.router, http.MethodGet, "/api/admin/collections")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code = %d: %s", rec.Code, rec.Body.String())
+	}
+	var payload models.CollectionsResponse
+	decodeJSON(t, rec, &payload)
+	if len(payload.Collections) != 1 {
+		t.Fatalf("collections = %+v", payload.Collections)
+	}
+	got := payload.Collections[0]
+	if got.Account != "nextcloud" || got.Name != "family" || got.DisplayName != "家族" || got.URL != server.URL+"/remote.php/dav/calendars/testuser/family/" ||
+		got.Color != "#0082C9" || strings.Join(got.Components, ",") != "VEVENT" || !got.Calendar || got.TaskList {
+		t.Fatalf("collection = %+v", got)
+	}
+
+	// 検出に失敗したら 502
+	delete(responses, "/remote.php/dav/principals/users/testuser/")
+	if rec := performRequest(env.router, http.MethodGet, "/api/admin/collections"); rec.Code != http.StatusBadGateway {
+		t.Fatalf("discovery failure: status code = %d", rec.Code)
+	}
 }
 
 func TestHealth(t *testing.T) {
@@ -255,3 +789,134 @@ func TestHealth(t *testing.T) {
 		t.Fatalf("health ok = false")
 	}
 }
+
+func TestEventHubPublishesOnlyOnChange(t *testing.T) {
+	env := newTestEnv(t)
+	hub := env.hub
+
+	hub.poll()
+	if got := len(hub.history); got != len(eventTypes) {
+		t.Fatalf("初回の history = %d, want %d", got, len(eventTypes))
+	}
+
+	// 変化がなければメッセージは増えない（取得時刻だけ変わる再書き込みも同じ）
+	var tasks models.TasksResponse
+	if _, _, _, err := env.cache.ReadPayload(nextcloud.TasksCacheKey, 0, &tasks); err != nil {
+		t.Fatalf("read tasks cache: %v", err)
+	}
+	if _, err := env.cache.Write(nextcloud.TasksCacheKey, &tasks, nil); err != nil {
+		t.Fatalf("rewrite tasks cache: %v", err)
+	}
+	hub.poll()
+	if got := len(hub.history); got != len(eventTypes) {
+		t.Fatalf("変化なしで history が増えました: %d", got)
+	}
+
+	tasks.Items[0].Status = "completed"
+	if _, err := env.cache.Write(nextcloud.TasksCacheKey, &tasks, nil); err != nil {
+		t.Fatalf("write tasks cache: %v", err)
+	}
+	hub.poll()
+	if got := len(hub.history); got != len(eventTypes)+1 {
+		t.Fatalf("history = %d, want %d", got, len(eventTypes)+1)
+	}
+	if last := hub.history[len(hub.history)-1]; last.Type != "tasks" || !strings.Contains(string(last.Data), `"completed"`) {
+		t.Fatalf("最後のメッセージが不正: type=%s data=%s", last.Type, last.Data)
+	}
+}
+
+// readSSEEvent は SSE ストリームから次のメッセージを1件読むます（コメントと retry は読み飛ばす）。
+func readSSEEvent(t *testing.T, reader *bufio.Reader) (id, eventType, data string) {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if eventType != "" {
+				return id, eventType, data
+			}
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data += strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func openEventStream(t *testing.T, serverURL, lastEventID string) *bufio.Reader {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL+"/api/events", nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status code = %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("content-type = %s", got)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+func TestGetEventsStream(t *testing.T) {
+	env := newTestEnv(t)
+	env.hub.pollInterval = 10 * time.Millisecond
+
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go env.hub.Run(runCtx)
+
+	// ストリームの切断（t.Cleanup）より後に閉じる必要があるのです
+	server := httptest.NewServer(env.router)
+	t.Cleanup(server.Close)
+
+	// 接続直後は各種類の最新状態が届く
+	reader := openEventStream(t, server.URL, "")
+	seen := map[string]bool{}
+	for range eventTypes {
+		_, eventType, data := readSSEEvent(t, reader)
+		if !json.Valid([]byte(data)) {
+			t.Fatalf("%s の data が JSON ではありません: %s", eventType, data)
+		}
+		seen[eventType] = true
+	}
+	for _, eventType := range eventTypes {
+		if !seen[eventType] {
+			t.Fatalf("%s のメッセージが届いていません", eventType)
+		}
+	}
+
+	// キャッシュが変わると配信される
+	if _, err := env.cache.Write(nextcloud.TasksCacheKey, &models.TasksResponse{Items: []models.TaskItem{}}, nil); err != nil {
+		t.Fatalf("write tasks cache: %v", err)
+	}
+	id, eventType, data := readSSEEvent(t, reader)
+	if eventType != "tasks" || data != `{"items":[]}` {
+		t.Fatalf("event = %s %s", eventType, data)
+	}
+
+	// Last-Event-ID で続きから再開できる
+	lastID, _ := strconv.Atoi(id)
+	resumed := openEventStream(t, server.URL, strconv.Itoa(lastID-1))
+	if resumedID, resumedType, _ := readSSEEvent(t, resumed); resumedID != id || resumedType != "tasks" {
+		t.Fatalf("resume event = %s %s, want %s tasks", resumedID, resumedType, id)
+	}
+}
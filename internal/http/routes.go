@@ -14,11 +14,28 @@ func SetupRoutes(router *gin.Engine) {
 		// カレンダー取得
 		api.GET("/calendar", GetCalendar)
 
-		// タスク取得
+		// カレンダーイベント作成・更新・削除
+		api.POST("/calendar/events", CreateCalendarEvent)
+		api.PATCH("/calendar/events/:id", UpdateCalendarEvent)
+		api.DELETE("/calendar/events/:id", DeleteCalendarEvent)
+
+		// タスク取得・作成・更新・削除
 		api.GET("/tasks", GetTasks)
+		api.POST("/tasks", CreateTask)
+		api.PATCH("/tasks/:id", UpdateTask)
+		api.DELETE("/tasks/:id", DeleteTask)
 
 		// 天気取得
 		api.GET("/weather", GetWeather)
+		api.GET("/weather/hourly", GetWeatherHourly)
+		api.GET("/weather/all", GetWeatherAll)
+		api.GET("/weather/history", GetWeatherHistory)
+
+		// CalDAV コレクションの一覧（設定の確認用）
+		api.GET("/admin/collections", GetAdminCollections)
+
+		// 更新通知（Server-Sent Events）
+		api.GET("/events", GetEvents)
 
 		// ヘルスチェック（疎通確認）
 		api.GET("/health", func(ctx *gin.Context) {
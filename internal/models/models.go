@@ -16,6 +16,17 @@ type StatusResponse struct {
 	Now         string           `json:"now"`         // 現在時刻（RFC3339）
 	Errors      []ErrorInfo      `json:"errors"`      // エラーリスト
 	LastUpdated LastUpdatedTimes `json:"lastUpdated"` // 各ソースの最終更新時刻
+
+	WeatherProvider string           `json:"weatherProvider"` // 天気データを提供したプロバイダ（"openmeteo" など、未取得は空）
+	Locations       []LocationStatus `json:"locations"`       // 地点ごとの天気の更新状況（設定順）
+}
+
+// LocationStatus は地点ごとの天気の更新状況なのです。
+type LocationStatus struct {
+	ID          string `json:"id"`          // 地点ID
+	Name        string `json:"name"`        // 表示名
+	LastUpdated string `json:"lastUpdated"` // 天気の最終更新時刻（RFC3339、未取得は空）
+	Provider    string `json:"provider"`    // 天気データを提供したプロバイダ（未取得は空）
 }
 
 // ErrorInfo はエラー情報を表すのです。
@@ -58,6 +69,23 @@ type Event struct {
 	Calendar string `json:"calendar"`    // カレンダー名
 	Location string `json:"location"`    // 場所（省略可）
 	Desc     string `json:"description"` // 説明（省略可）
+	ReadOnly bool   `json:"readOnly"`    // 編集できないイベントか（ICS 購読カレンダー）
+
+	ContinuesFromPrevDay bool `json:"continuesFromPrevDay"` // 前日から続いているイベントか
+	ContinuesToNextDay   bool `json:"continuesToNextDay"`   // 翌日へ続くイベントか
+}
+
+// EventWriteRequest は /api/calendar/events の作成・更新リクエストなのです。
+// nil のフィールドは変更しないのです（PATCH 用）。
+type EventWriteRequest struct {
+	Title       *string `json:"title"`       // イベント名
+	Start       *string `json:"start"`       // 開始（RFC3339、終日は YYYY-MM-DD）
+	End         *string `json:"end"`         // 終了（省略時は1時間／終日は1日。終日は翌日を指定する排他的終了）
+	AllDay      *bool   `json:"allDay"`      // 終日か（省略時は start の形式から判定）
+	Location    *string `json:"location"`    // 場所（空文字で削除）
+	Description *string `json:"description"` // 説明（空文字で削除）
+	Color       *string `json:"color"`       // 色コード（#RRGGBB、空文字で削除）
+	Calendar    string  `json:"calendar"`    // 作成先カレンダー名（作成時のみ、省略時は先頭のカレンダー）
 }
 
 // ============================================================================
@@ -80,42 +108,149 @@ type TaskItem struct {
 	CreatedAt time.Time `json:"createdAt"` // 作成日時
 }
 
+// TaskWriteRequest は /api/tasks の作成・更新リクエストなのです。
+// nil のフィールドは変更しないのです（PATCH 用）。
+type TaskWriteRequest struct {
+	Title    *string `json:"title"`    // タスク名
+	Notes    *string `json:"notes"`    // 説明
+	Status   *string `json:"status"`   // "needsAction" か "completed"
+	DueDate  *string `json:"dueDate"`  // 期限（YYYY-MM-DD、空文字で期限を削除）
+	Priority *int    `json:"priority"` // 優先度（1-3、3が最高）
+	TaskList string  `json:"taskList"` // 作成先タスクリスト名（作成時のみ、省略時は先頭のリスト）
+}
+
+// ============================================================================
+// CalDAV コレクション関連の構造体
+// ============================================================================
+
+// CollectionsResponse は /api/admin/collections のレスポンスなのです。
+type CollectionsResponse struct {
+	Collections []CalDAVCollection `json:"collections"`     // 検出したコレクション（アカウントの順、アカウント内は URL順）
+	Error       string             `json:"error,omitempty"` // 検出に失敗したアカウントのエラー（一部のアカウントだけ失敗した場合）
+}
+
+// CalDAVCollection は CalDAV のカレンダーコレクション1件なのです。
+type CalDAVCollection struct {
+	Account     string   `json:"account"`     // アカウントID（nextcloud の設定は "nextcloud"）
+	Name        string   `json:"name"`        // コレクションのスラッグ（URL の最後の部分）
+	DisplayName string   `json:"displayName"` // 表示名（無ければ空）
+	URL         string   `json:"url"`         // コレクションの URL
+	Color       string   `json:"color"`       // 色（#RRGGBB、無ければ空）
+	Components  []string `json:"components"`  // 対応するコンポーネント（"VEVENT", "VTODO" など。空はすべて）
+	Calendar    bool     `json:"calendar"`    // カレンダーとして表示しているか（calendarNames）
+	TaskList    bool     `json:"taskList"`    // タスクリストとして表示しているか（taskListNames）
+}
+
 // ============================================================================
 // 天気関連の構造体
 // ============================================================================
 
 // WeatherResponse は /api/weather のレスポンスなのです。
 type WeatherResponse struct {
+	Lang        string          `json:"lang"`        // 天候などの表示言語（"ja-hiragana" "ja-kanji" "en"）
+	Units       WeatherUnits    `json:"units"`       // 数値の単位
+	LocationID  string          `json:"locationId"`  // 地点ID（設定の locations[].id）
 	Location    string          `json:"location"`    // 場所（都市名など）
 	Current     CurrentWeather  `json:"current"`     // 現在の天候
 	Today       TodayWeather    `json:"today"`       // 今日の天況
-	PrecipSlots []PrecipSlot    `json:"precipSlots"` // 時間帯ごとの降水確率
+	PrecipSlots []PrecipSlot    `json:"precipSlots"` // 時間帯ごとの降水確率（Hourly から3時間区切りで抜き出したもの）
+	Hourly      []HourlyWeather `json:"hourly"`      // 時間ごとの予報（現在の時間帯から48時間先まで）
 	Weekly      []WeeklyWeather `json:"weekly"`      // 週間天気予報（7日分）
 	Alerts      []WeatherAlert  `json:"alerts"`      // 注意報・警報
+	AirQuality  *AirQuality     `json:"airQuality"`  // 大気質・花粉（取得できない場合は null）
+	Advice      []Advice        `json:"advice"`      // 天気から判定したアドバイス（傘・洗濯物・熱中症など）
+}
+
+// WeatherUnits は天気の数値の単位（表示用の記号）なのです。
+// 気圧（hPa）・UVインデックス・降水確率（%）は単位の設定にかかわらず同じなのです。
+type WeatherUnits struct {
+	Temperature   string `json:"temperature"`   // 気温（"℃" または "℉"）
+	WindSpeed     string `json:"windSpeed"`     // 風速（"m/s" "km/h" "mph"）
+	Precipitation string `json:"precipitation"` // 降水量（"mm" または "in"）
+}
+
+// Advice は天気から判定したアドバイス1件なのです。
+type Advice struct {
+	Type    string `json:"type"`    // ルール名（"umbrella" "laundry" "heat" "cold" "uv" "wind"）
+	Message string `json:"message"` // アドバイス（例："傘を持っていこう"）
+	Reason  string `json:"reason"`  // 根拠（例："18時ごろ 降水確率60%"）
+	Level   string `json:"level"`   // "info"（おすすめ）または "warning"（注意）
+}
+
+// WeatherHistoryResponse は /api/weather/history のレスポンスなのです。
+type WeatherHistoryResponse struct {
+	Lang        string              `json:"lang"`        // 表示言語
+	Units       WeatherUnits        `json:"units"`       // 数値の単位
+	LocationID  string              `json:"locationId"`  // 地点ID
+	Location    string              `json:"location"`    // 場所（都市名など）
+	Days        []WeatherHistoryDay `json:"days"`        // 日ごとの記録（古い順、記録の無い日は含まない）
+	VsYesterday *WeatherTrend       `json:"vsYesterday"` // 今日の予報と昨日の記録の比較（昨日の記録が無い場合は null）
+}
+
+// WeatherHistoryDay は記録した観測値の1日分の集計なのです。
+type WeatherHistoryDay struct {
+	Date          string  `json:"date"`          // 日付（YYYY-MM-DD、Asia/Tokyo）
+	MaxTemp       float64 `json:"maxTemp"`       // 記録した最高気温（℃、imperial なら℉）
+	MinTemp       float64 `json:"minTemp"`       // 記録した最低気温（℃、imperial なら℉）
+	Precipitation float64 `json:"precipitation"` // 降水量の合計（mm、imperial なら inch、時間帯ごとの最後の記録の合計）
+	Samples       int     `json:"samples"`       // 記録の件数
+}
+
+// WeatherTrend は今日と昨日の気温の差なのです。
+type WeatherTrend struct {
+	MaxTempDelta float64 `json:"maxTempDelta"` // 今日の最高気温（予報）− 昨日の最高気温（記録）（℃、imperial なら℉）
+	MinTempDelta float64 `json:"minTempDelta"` // 今日の最低気温（予報）− 昨日の最低気温（記録）（℃、imperial なら℉）
+	Message      string  `json:"message"`      // 表示用（例："きのうより3℃たかい"）
+}
+
+// WeatherAllResponse は /api/weather/all のレスポンスなのです。
+type WeatherAllResponse struct {
+	Lang      string           `json:"lang"`      // 表示言語
+	Units     WeatherUnits     `json:"units"`     // 数値の単位
+	Locations []WeatherSummary `json:"locations"` // 地点ごとの概要（設定順）
+}
+
+// WeatherSummary は /api/weather/all の地点1件分の概要なのです。
+type WeatherSummary struct {
+	ID       string         `json:"id"`       // 地点ID
+	Name     string         `json:"name"`     // 表示名
+	Location string         `json:"location"` // 場所（都市名など）
+	Current  CurrentWeather `json:"current"`  // 現在の天候
+	Today    TodayWeather   `json:"today"`    // 今日の天況
+	Error    string         `json:"error"`    // 取得エラー（無ければ空。直近のキャッシュがあればその内容を返すのです）
 }
 
 // CurrentWeather は現在の天況なのです。
 type CurrentWeather struct {
-	Temperature float64 `json:"temperature"` // 気温（℃）
-	Condition   string  `json:"condition"`   // 天候（"晴" "曇" "雨" など）
-	Icon        string  `json:"icon"`        // 天候アイコンコード
-	Humidity    int     `json:"humidity"`    // 湿度（%）
-	WindSpeed   float64 `json:"windSpeed"`   // 風速（m/s）
+	Temperature   float64 `json:"temperature"`   // 気温（℃、imperial なら℉）
+	FeelsLike     float64 `json:"feelsLike"`     // 体感温度（℃、imperial なら℉）
+	Condition     string  `json:"condition"`     // 天候（表示言語の文言、"はれ" "くもり" "あめ" など）
+	Icon          string  `json:"icon"`          // 天候アイコンコード（末尾 "d" は昼、"n" は夜）
+	IsDay         bool    `json:"isDay"`         // 日の出から日の入りまでの間か
+	Humidity      int     `json:"humidity"`      // 湿度（%）
+	WindSpeed     float64 `json:"windSpeed"`     // 風速（units.windSpeed、既定は m/s）
+	WindDirection int     `json:"windDirection"` // 風向（度、北=0 から時計回り、風が吹いてくる方向）
+	WindGust      float64 `json:"windGust"`      // 最大瞬間風速（units.windSpeed、不明は0）
+	Pressure      float64 `json:"pressure"`      // 海面気圧（hPa）
+	UVIndex       float64 `json:"uvIndex"`       // UVインデックス（不明は0）
 }
 
 // TodayWeather は今日の天況なのです。
 type TodayWeather struct {
-	MaxTemp float64 `json:"maxTemp"` // 最高気温（℃）
-	MinTemp float64 `json:"minTemp"` // 最低気温（℃）
-	Summary string  `json:"summary"` // 概況
+	MaxTemp    float64 `json:"maxTemp"`    // 最高気温（℃、imperial なら℉）
+	MinTemp    float64 `json:"minTemp"`    // 最低気温（℃、imperial なら℉）
+	Summary    string  `json:"summary"`    // 概況
+	Sunrise    string  `json:"sunrise"`    // 日の出（RFC3339、不明は空）
+	Sunset     string  `json:"sunset"`     // 日の入り（RFC3339、不明は空）
+	UVIndexMax float64 `json:"uvIndexMax"` // 今日の最大UVインデックス（不明は0）
 }
 
 // WeeklyWeather は週間天気予報の1日分なのです。
 type WeeklyWeather struct {
 	Date      string  `json:"date"`      // 日付（YYYY-MM-DD）
-	MaxTemp   float64 `json:"maxTemp"`   // 最高気温（℃）
-	MinTemp   float64 `json:"minTemp"`   // 最低気温（℃）
-	Condition string  `json:"condition"` // 天候（"晴" "曇" "雨" など）
+	MaxTemp   float64 `json:"maxTemp"`   // 最高気温（℃、imperial なら℉）
+	MinTemp   float64 `json:"minTemp"`   // 最低気温（℃、imperial なら℉）
+	Condition string  `json:"condition"` // 天候（表示言語の文言、"はれ" "くもり" "あめ" など）
 	Icon      string  `json:"icon"`      // 天候アイコンコード
 }
 
@@ -125,6 +260,46 @@ type PrecipSlot struct {
 	Precip int    `json:"precip"` // 降水確率（%）
 }
 
+// HourlyWeather は時間ごとの予報なのです。
+// プロバイダによっては1時間ごとではなく3時間ごとになるのです（OpenWeatherMap）。
+type HourlyWeather struct {
+	Time          string  `json:"time"`          // 時刻（RFC3339）
+	Temperature   float64 `json:"temperature"`   // 気温（℃、imperial なら℉）
+	PrecipProb    int     `json:"precipProb"`    // 降水確率（%）
+	PrecipAmount  float64 `json:"precipAmount"`  // この時刻からの降水量（mm、imperial なら inch、3時間ごとのプロバイダは3時間分）
+	WeatherCode   int     `json:"weatherCode"`   // WMO天気コード（不明は-1）
+	Condition     string  `json:"condition"`     // 天候（表示言語の文言、"はれ" "あめ" など）
+	Icon          string  `json:"icon"`          // 天候アイコンコード（末尾 "d" は昼、"n" は夜）
+	WindSpeed     float64 `json:"windSpeed"`     // 風速（units.windSpeed、既定は m/s）
+	WindDirection int     `json:"windDirection"` // 風向（度、北=0 から時計回り、風が吹いてくる方向）
+}
+
+// HourlyWeatherResponse は /api/weather/hourly のレスポンスなのです。
+type HourlyWeatherResponse struct {
+	Lang     string          `json:"lang"`     // 表示言語
+	Units    WeatherUnits    `json:"units"`    // 数値の単位
+	Location string          `json:"location"` // 場所（都市名など）
+	Hours    []HourlyWeather `json:"hours"`    // 時間ごとの予報
+}
+
+// AirQuality は大気質と花粉なのです。
+type AirQuality struct {
+	Time     string        `json:"time"`     // 予測の時刻（RFC3339）
+	PM25     float64       `json:"pm25"`     // PM2.5（μg/m³）
+	PM10     float64       `json:"pm10"`     // PM10（μg/m³）
+	AQI      int           `json:"aqi"`      // 大気質指数（米国 AQI、0〜500）
+	Category string        `json:"category"` // AQI の区分（"よい" "ふつう" など）
+	Pollen   []PollenLevel `json:"pollen"`   // 花粉（提供されない地域では空）
+}
+
+// PollenLevel は花粉1種類の飛散量なのです。
+type PollenLevel struct {
+	Type  string  `json:"type"`  // 種類（"grass" "birch" など）
+	Name  string  `json:"name"`  // 日本語名（"イネ科" など）
+	Value float64 `json:"value"` // 飛散量（個/m³）
+	Level string  `json:"level"` // 飛散量の区分（"すくない" "ややおおい" "おおい" "ひじょうにおおい"）
+}
+
 // WeatherAlert は注意報・警報なのです。
 type WeatherAlert struct {
 	Title    string `json:"title"`       // 警報名（e.g., "大雨警報"）
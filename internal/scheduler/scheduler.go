@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/status"
+)
+
+const (
+	// defaultInitialBackoff は最初の失敗後に再試行するまでの待ち時間なのです。
+	defaultInitialBackoff = 15 * time.Second
+	// defaultMaxBackoff は連続失敗時の待ち時間の上限なのです。
+	defaultMaxBackoff = 15 * time.Minute
+	// defaultRunTimeout は1回の更新処理のタイムアウトなのです。
+	defaultRunTimeout = 60 * time.Second
+	// jitterRatio は更新間隔に対する揺らぎの割合なのです。
+	jitterRatio = 0.1
+)
+
+// Job は定期更新するデータソース1件なのです。
+type Job struct {
+	Source   string                          // ErrorStore に記録するソース名（"weather" など）
+	Interval time.Duration                   // 更新間隔（RefreshIntervals）
+	Refresh  func(ctx context.Context) error // キャッシュを無視して取得し、FileCache に書き込む処理
+}
+
+// Scheduler は天気・カレンダー・タスクをバックグラウンドで定期更新するのです。
+// キャッシュの期限が切れる前に更新するので、ハンドラーは常にキャッシュを即座に返せるます。
+// 失敗時は指数バックオフで再試行し、結果を ErrorStore に記録するのです。
+type Scheduler struct {
+	errorStore *status.ErrorStore
+	jobs       []Job
+
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	runTimeout     time.Duration
+	random         func() float64
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+// New はスケジューラーを作成するます。
+func New(errorStore *status.ErrorStore) *Scheduler {
+	return &Scheduler{
+		errorStore:     errorStore,
+		initialBackoff: defaultInitialBackoff,
+		maxBackoff:     defaultMaxBackoff,
+		runTimeout:     defaultRunTimeout,
+		random:         rand.Float64,
+	}
+}
+
+// Add は定期更新ジョブを登録するます。Start より前に呼ぶ必要があるのです。
+func (s *Scheduler) Add(job Job) {
+	if job.Interval <= 0 {
+		fmt.Printf("⚠️ 更新間隔が不正なため '%s' の定期更新をスキップするます: %v\n", job.Source, job.Interval)
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Start は登録済みジョブの定期更新を開始するます。
+// 起動直後に1回ずつ更新してキャッシュを温めるのです。
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.run(ctx, job)
+	}
+
+	fmt.Printf("⏰ バックグラウンド更新を開始しました: %d 件\n", len(s.jobs))
+}
+
+// Stop は定期更新を止めて、実行中の更新が終わるまで待つます。
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	s.wg.Wait()
+	fmt.Println("⏰ バックグラウンド更新を停止しました")
+}
+
+// run はジョブ1件の更新ループなのです。
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	failures := 0
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := s.refresh(ctx, job); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failures++
+			fmt.Printf("❌ バックグラウンド更新失敗 (%s, %d 回連続): %v\n", job.Source, failures, err)
+			s.errorStore.Set(job.Source, err.Error())
+		} else {
+			failures = 0
+			s.errorStore.Clear(job.Source)
+		}
+
+		timer := time.NewTimer(s.nextDelay(job.Interval, failures))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// refresh はタイムアウト付きでジョブを1回実行するます。
+func (s *Scheduler) refresh(ctx context.Context, job Job) error {
+	runCtx, cancel := context.WithTimeout(ctx, s.runTimeout)
+	defer cancel()
+	return job.Refresh(runCtx)
+}
+
+// nextDelay は次の更新までの待ち時間を返すます。
+// 成功時は更新間隔から最大10%早めて（キャッシュ期限切れ前に更新するため）、
+// 失敗時は初回待ち時間を連続失敗回数に応じて倍々にし、上限で頭打ちにするのです。
+func (s *Scheduler) nextDelay(interval time.Duration, failures int) time.Duration {
+	if failures == 0 {
+		return interval - time.Duration(float64(interval)*jitterRatio*s.random())
+	}
+
+	backoff := s.initialBackoff
+	for i := 1; i < failures && backoff < s.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.maxBackoff {
+		backoff = s.maxBackoff
+	}
+
+	// 同時に失敗したソースが一斉に再試行しないように揺らすます
+	return backoff + time.Duration(float64(backoff)*jitterRatio*s.random())
+}
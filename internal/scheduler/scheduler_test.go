@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/status"
+)
+
+func newTestScheduler(store *status.ErrorStore) *Scheduler {
+	s := New(store)
+	s.initialBackoff = 5 * time.Millisecond
+	s.maxBackoff = 20 * time.Millisecond
+	s.random = func() float64 { return 0 }
+	return s
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("条件を満たすまでにタイムアウトしました")
+}
+
+func TestSchedulerRefreshesPeriodically(t *testing.T) {
+	var runs atomic.Int32
+	s := newTestScheduler(status.NewErrorStore())
+	s.Add(Job{
+		Source:   "weather",
+		Interval: 10 * time.Millisecond,
+		Refresh: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	})
+
+	s.Start(context.Background())
+	waitFor(t, func() bool { return runs.Load() >= 3 })
+	s.Stop()
+
+	stopped := runs.Load()
+	time.Sleep(30 * time.Millisecond)
+	if got := runs.Load(); got != stopped {
+		t.Fatalf("Stop 後も更新が続いています: %d -> %d", stopped, got)
+	}
+}
+
+func TestSchedulerRecordsErrors(t *testing.T) {
+	store := status.NewErrorStore()
+	var runs atomic.Int32
+	s := newTestScheduler(store)
+	s.Add(Job{
+		Source:   "calendar",
+		Interval: time.Hour,
+		Refresh: func(ctx context.Context) error {
+			// 最初の2回は失敗し、バックオフ後の再試行で成功するのです
+			if runs.Add(1) <= 2 {
+				return errors.New("nextcloud down")
+			}
+			return nil
+		},
+	})
+
+	s.Start(context.Background())
+	defer s.Stop()
+
+	waitFor(t, func() bool {
+		errs := store.List()
+		return len(errs) == 1 && errs[0].Source == "calendar" && errs[0].Message == "nextcloud down"
+	})
+	waitFor(t, func() bool { return runs.Load() >= 3 && len(store.List()) == 0 })
+}
+
+func TestSchedulerStopCancelsRunningRefresh(t *testing.T) {
+	started := make(chan struct{})
+	s := newTestScheduler(status.NewErrorStore())
+	s.Add(Job{
+		Source:   "tasks",
+		Interval: time.Hour,
+		Refresh: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	s.Start(context.Background())
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Stop が実行中の更新を待ったまま終わりません")
+	}
+}
+
+func TestNextDelay(t *testing.T) {
+	s := New(status.NewErrorStore())
+	s.initialBackoff = 10 * time.Second
+	s.maxBackoff = time.Minute
+
+	s.random = func() float64 { return 0 }
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 5 * time.Minute},
+		{failures: 1, want: 10 * time.Second},
+		{failures: 2, want: 20 * time.Second},
+		{failures: 3, want: 40 * time.Second},
+		{failures: 4, want: time.Minute},
+		{failures: 10, want: time.Minute},
+	}
+	for _, tt := range tests {
+		if got := s.nextDelay(5*time.Minute, tt.failures); got != tt.want {
+			t.Errorf("nextDelay(failures=%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	// 揺らぎは成功時は早める方向、失敗時は遅らせる方向なのです
+	s.random = func() float64 { return 1 }
+	if got, want := s.nextDelay(5*time.Minute, 0), 270*time.Second; got != want {
+		t.Errorf("jittered interval = %v, want %v", got, want)
+	}
+	if got, want := s.nextDelay(5*time.Minute, 1), 11*time.Second; got != want {
+		t.Errorf("jittered backoff = %v, want %v", got, want)
+	}
+}
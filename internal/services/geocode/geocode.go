@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/rihow/FamilyDashboard/internal/cache"
@@ -28,44 +29,93 @@ type NominatimResponse struct {
 	DisplayName string `json:"display_name"`
 }
 
+// ジオコーディング結果のキャッシュの既定値なのです。
+// 都市の座標はほぼ変わらないので数週間は問い合わせず、Nominatim への問い合わせを減らすます。
+const (
+	DefaultTTL                  = 4 * 7 * 24 * time.Hour // 有効期限（4週間）
+	DefaultStaleWhileRevalidate = 8 * 7 * 24 * time.Hour // 期限切れのあと裏で問い合わせながら古い結果を返す期間（8週間）
+)
+
+// Options はジオコーディングクライアントの設定なのです。
+// ゼロ値の項目は既定値を使うます。
+type Options struct {
+	TTL                  time.Duration // キャッシュの有効期限（既定 DefaultTTL）
+	StaleWhileRevalidate time.Duration // 期限切れのあと古い結果を返しつつ裏で問い合わせる期間（既定 DefaultStaleWhileRevalidate）
+}
+
 // Client はジオコーディングクライアントなのです。
 type Client struct {
 	baseURL    string
 	userAgent  string
 	httpClient *http.Client
 	fc         *cache.FileCache
+	ttl        time.Duration
+	swr        time.Duration
+
+	mu           sync.Mutex
+	revalidating map[string]bool // 裏で問い合わせ中のキャッシュキー
+	wg           sync.WaitGroup  // 裏の問い合わせ（テストで終わりを待つため）
 }
 
 // NewClient はジオコーディングクライアントを作成するます。
 // fcはジオコーディング結果のキャッシュを管理するためのFileCacheなのです。
-// TTLは5分（ジオコーディング結果は頻繁に変わらないため、長めのTTL）。
-func NewClient(fc *cache.FileCache) *Client {
+// 有効期限と stale-while-revalidate の期間は opts で指定するのです（ゼロ値なら既定値）。
+func NewClient(fc *cache.FileCache, opts Options) *Client {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.StaleWhileRevalidate <= 0 {
+		opts.StaleWhileRevalidate = DefaultStaleWhileRevalidate
+	}
 	return &Client{
 		baseURL:   "https://nominatim.openstreetmap.org",
 		userAgent: "FamilyDashboard/1.0 (https://github.com/rihow/FamilyDashboard; personal-use; @rihow)",
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		fc: fc,
+		fc:           fc,
+		ttl:          opts.TTL,
+		swr:          opts.StaleWhileRevalidate,
+		revalidating: map[string]bool{},
 	}
 }
 
 // GetCoordinates は都市名（と国コード）から緯度経度を取得するます。
 // キャッシュがあればそれを返し、ない場合はNominatim APIを呼ぶのです。
+// 有効期限が切れていても stale-while-revalidate の期間内なら古い結果を返し、裏で問い合わせ直すます。
 func (c *Client) GetCoordinates(ctx context.Context, cityName, country string) (*Location, error) {
 	// キャッシュキーを生成するます。
 	cacheKey := fmt.Sprintf("geocode_%s_%s", cityName, country)
 
-	// キャッシュをチェックするます。（TTL: 5分）
-	if entry, exists, stale, err := c.fc.Read(cacheKey, 5*time.Minute); err == nil && exists && !stale {
-		var loc Location
-		if err := json.Unmarshal(entry.Payload, &loc); err == nil {
-			return &loc, nil
+	// キャッシュをチェックするます。
+	var cached Location
+	entry, exists, stale, err := c.fc.ReadPayload(cacheKey, c.ttl, &cached)
+	cachedAvailable := exists && err == nil
+	if cachedAvailable && !stale {
+		return &cached, nil
+	}
+	if cachedAvailable {
+		if age, ok := c.fc.Age(entry); ok && age <= c.ttl+c.swr {
+			c.revalidate(cacheKey, cityName, country)
+			return &cached, nil
 		}
-		// キャッシュのデシリアライズに失敗した場合は、APIを呼ぶます。
 	}
 
 	// Nominatim APIを呼ぶます。
+	location, err := c.refresh(ctx, cacheKey, cityName, country)
+	if err != nil {
+		// オフライン時などは期限切れでも以前の結果を使うます。
+		if cachedAvailable {
+			fmt.Printf("⚠️ ジオコーディング失敗のため期限切れキャッシュを使うます: %v\n", err)
+			return &cached, nil
+		}
+		return nil, err
+	}
+	return location, nil
+}
+
+// refresh は Nominatim API に問い合わせて結果をキャッシュに保存するます。
+func (c *Client) refresh(ctx context.Context, cacheKey, cityName, country string) (*Location, error) {
 	location, err := c.queryNominatim(ctx, cityName, country)
 	if err != nil {
 		return nil, err
@@ -78,10 +128,34 @@ func (c *Client) GetCoordinates(ctx context.Context, cityName, country string) (
 			"country":  country,
 		})
 	}
-
 	return location, nil
 }
 
+// revalidate は裏で Nominatim API に問い合わせてキャッシュを更新するます。
+// 同じキーを問い合わせ中なら何もしないのです（失敗しても古い結果はそのまま使うます）。
+func (c *Client) revalidate(cacheKey, cityName, country string) {
+	c.mu.Lock()
+	if c.revalidating[cacheKey] {
+		c.mu.Unlock()
+		return
+	}
+	c.revalidating[cacheKey] = true
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() {
+			c.mu.Lock()
+			delete(c.revalidating, cacheKey)
+			c.mu.Unlock()
+		}()
+		if _, err := c.refresh(context.Background(), cacheKey, cityName, country); err != nil {
+			fmt.Printf("⚠️ ジオコーディングの再取得失敗するます（古い結果を使い続けるのです）: %v\n", err)
+		}
+	}()
+}
+
 // queryNominatim はNominatim APIにクエリーを送り、座標を取得するます。
 // Nominatim利用規約に従い、1秒あたり最大1リクエストのレート制限しぶりを想定するます。
 // （実装アプリ側でリクエストを1秒以上間隔を空ける必要があります）
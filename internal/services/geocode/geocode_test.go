@@ -3,7 +3,10 @@ package geocode
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -21,7 +24,7 @@ func TestGetCoordinates_Nominatim(t *testing.T) {
 		_ = os.RemoveAll(tmpDir)
 	}()
 
-	client := NewClient(fc)
+	client := NewClient(fc, Options{})
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
@@ -107,3 +110,103 @@ func TestLocation_Serialization(t *testing.T) {
 
 	t.Logf("✨ Serialization works correctly")
 }
+
+// TestGetCoordinates_FakeServer はローカルの偽 Nominatim でキャッシュの動作を確認するテストです。
+// ネットワーク接続は不要です。
+func TestGetCoordinates_FakeServer(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("q") != "松江市" || r.URL.Query().Get("countrycodes") != "JP" {
+			_, _ = w.Write([]byte("[]"))
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]NominatimResponse{{Lat: "35.4681", Lon: "133.0484", DisplayName: "松江市"}})
+	}))
+	defer server.Close()
+
+	client := NewClient(cache.New(t.TempDir()), Options{})
+	client.baseURL = server.URL
+	ctx := context.Background()
+
+	location, err := client.GetCoordinates(ctx, "松江市", "JP")
+	if err != nil {
+		t.Fatalf("failed to get coordinates: %v", err)
+	}
+	if location.Latitude != 35.4681 || location.Longitude != 133.0484 {
+		t.Fatalf("unexpected coordinates: %f, %f", location.Latitude, location.Longitude)
+	}
+
+	// 2回目はキャッシュから返るので問い合わせしない
+	if _, err := client.GetCoordinates(ctx, "松江市", "JP"); err != nil {
+		t.Fatalf("second query failed: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("requests = %d, want 1", requests)
+	}
+
+	if _, err := client.GetCoordinates(ctx, "ぜったいないまち12345", "JP"); err == nil {
+		t.Fatal("expected error for unknown city")
+	}
+}
+
+// TestGetCoordinates_StaleWhileRevalidate はキャッシュの有効期限と stale-while-revalidate の期間のテストです。
+// キャッシュの時計を進めて確認するので、ネットワーク接続は不要です。
+func TestGetCoordinates_StaleWhileRevalidate(t *testing.T) {
+	var requests atomic.Int32
+	var lat atomic.Value
+	lat.Store("35.4681")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_ = json.NewEncoder(w).Encode([]NominatimResponse{{Lat: lat.Load().(string), Lon: "133.0484", DisplayName: "松江市"}})
+	}))
+	defer server.Close()
+
+	// 省略時は数週間キャッシュする
+	if client := NewClient(cache.New(t.TempDir()), Options{}); client.ttl != DefaultTTL || client.swr != DefaultStaleWhileRevalidate {
+		t.Fatalf("既定値: ttl=%v swr=%v", client.ttl, client.swr)
+	}
+
+	fc := cache.New(t.TempDir())
+	now := time.Date(2025, 7, 15, 9, 0, 0, 0, time.UTC)
+	fc.SetClock(func() time.Time { return now })
+	client := NewClient(fc, Options{TTL: 24 * time.Hour, StaleWhileRevalidate: 48 * time.Hour})
+	client.baseURL = server.URL
+	ctx := context.Background()
+
+	get := func() float64 {
+		t.Helper()
+		location, err := client.GetCoordinates(ctx, "松江市", "JP")
+		if err != nil {
+			t.Fatalf("GetCoordinates: %v", err)
+		}
+		return location.Latitude
+	}
+
+	get()
+	now = now.Add(24 * time.Hour)
+	if get(); requests.Load() != 1 {
+		t.Fatalf("有効期限内なのに問い合わせています: %d", requests.Load())
+	}
+
+	// 期限切れでも stale-while-revalidate の期間内は古い結果を返し、裏で問い合わせる
+	lat.Store("35.5000")
+	now = now.Add(time.Hour)
+	if got := get(); got != 35.4681 {
+		t.Fatalf("古い結果を返しません: %f", got)
+	}
+	client.wg.Wait()
+	if requests.Load() != 2 {
+		t.Fatalf("裏で問い合わせていません: %d", requests.Load())
+	}
+	if got := get(); got != 35.5 || requests.Load() != 2 {
+		t.Fatalf("更新後: %f (requests=%d)", got, requests.Load())
+	}
+
+	// 期間より古ければその場で問い合わせる
+	lat.Store("35.6000")
+	now = now.Add(73 * time.Hour)
+	if got := get(); got != 35.6 || requests.Load() != 3 {
+		t.Fatalf("期間より古い: %f (requests=%d)", got, requests.Load())
+	}
+}
@@ -0,0 +1,260 @@
+package jma
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/cache"
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// cacheTTL は警報・注意報キャッシュの有効期間なのです。
+// 気象庁の発表は随時なので、天気より短めにするます。
+const cacheTTL = 5 * time.Minute
+
+// 重大度（models.WeatherAlert.Severity）なのです。
+const (
+	SeverityAdvisory  = "注意報"
+	SeverityWarning   = "警報"
+	SeverityEmergency = "特別警報"
+)
+
+// warningKind は気象庁の警報・注意報コード1件の定義なのです。
+type warningKind struct {
+	name     string
+	severity string
+}
+
+// warningKinds は気象庁の警報・注意報コード表なのです。
+var warningKinds = map[string]warningKind{
+	// 特別警報
+	"32": {name: "暴風雪", severity: SeverityEmergency},
+	"33": {name: "大雨", severity: SeverityEmergency},
+	"35": {name: "暴風", severity: SeverityEmergency},
+	"36": {name: "大雪", severity: SeverityEmergency},
+	"37": {name: "波浪", severity: SeverityEmergency},
+	"38": {name: "高潮", severity: SeverityEmergency},
+	// 警報
+	"02": {name: "暴風雪", severity: SeverityWarning},
+	"03": {name: "大雨", severity: SeverityWarning},
+	"04": {name: "洪水", severity: SeverityWarning},
+	"05": {name: "暴風", severity: SeverityWarning},
+	"06": {name: "大雪", severity: SeverityWarning},
+	"07": {name: "波浪", severity: SeverityWarning},
+	"08": {name: "高潮", severity: SeverityWarning},
+	// 注意報
+	"10": {name: "大雨", severity: SeverityAdvisory},
+	"12": {name: "大雪", severity: SeverityAdvisory},
+	"13": {name: "風雪", severity: SeverityAdvisory},
+	"14": {name: "雷", severity: SeverityAdvisory},
+	"15": {name: "強風", severity: SeverityAdvisory},
+	"16": {name: "波浪", severity: SeverityAdvisory},
+	"17": {name: "融雪", severity: SeverityAdvisory},
+	"18": {name: "洪水", severity: SeverityAdvisory},
+	"19": {name: "高潮", severity: SeverityAdvisory},
+	"20": {name: "濃霧", severity: SeverityAdvisory},
+	"21": {name: "乾燥", severity: SeverityAdvisory},
+	"22": {name: "なだれ", severity: SeverityAdvisory},
+	"23": {name: "低温", severity: SeverityAdvisory},
+	"24": {name: "霜", severity: SeverityAdvisory},
+	"25": {name: "着氷", severity: SeverityAdvisory},
+	"26": {name: "着雪", severity: SeverityAdvisory},
+}
+
+// severityRank は重大度の並び順（大きいほど重大）なのです。
+var severityRank = map[string]int{
+	SeverityAdvisory:  1,
+	SeverityWarning:   2,
+	SeverityEmergency: 3,
+}
+
+// WarningDocument は気象庁の警報・注意報 JSON（bosai/warning/data/warning/{府県コード}.json）なのです。
+type WarningDocument struct {
+	ReportDatetime   string     `json:"reportDatetime"`
+	PublishingOffice string     `json:"publishingOffice"`
+	HeadlineText     string     `json:"headlineText"`
+	AreaTypes        []AreaType `json:"areaTypes"`
+}
+
+// AreaType は区域の種類（一次細分区域・市町村など）ごとの一覧なのです。
+type AreaType struct {
+	Areas []Area `json:"areas"`
+}
+
+// Area は1区域の警報・注意報の状態なのです。
+type Area struct {
+	Code     string    `json:"code"`
+	Warnings []Warning `json:"warnings"`
+}
+
+// Warning は警報・注意報1件の状態なのです。
+// Status は "発表" "継続" "解除" "警報から注意報" "発表警報・注意報はなし" などなのです。
+type Warning struct {
+	Code   string `json:"code"`
+	Status string `json:"status"`
+}
+
+// Client は気象庁の警報・注意報を取得するクライアントなのです。
+// 天気とは別のキャッシュキーで保存し、取得できない場合は直近のキャッシュで動き続けるます。
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	fc         *cache.FileCache
+}
+
+// NewClient は気象庁クライアントを作成するます。
+func NewClient(fc *cache.FileCache) *Client {
+	return &Client{
+		baseURL: "https://www.jma.go.jp/bosai/warning/data/warning",
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		fc: fc,
+	}
+}
+
+// CacheKey は区域ごとの警報・注意報キャッシュキーを返すます。
+func CacheKey(areaCode string) string {
+	return "jma_warning_" + areaCode
+}
+
+// GetAlerts は区域の発表中の警報・注意報を返すます。
+// キャッシュが有効ならそれを返し、期限切れなら気象庁から取得するのです。
+func (c *Client) GetAlerts(ctx context.Context, officeCode, areaCode string) ([]models.WeatherAlert, error) {
+	var cached []models.WeatherAlert
+	_, found, stale, err := c.fc.ReadPayload(CacheKey(areaCode), cacheTTL, &cached)
+	if found && !stale && err == nil {
+		return cached, nil
+	}
+
+	return c.RefreshAlerts(ctx, officeCode, areaCode)
+}
+
+// RefreshAlerts はキャッシュを見ずに気象庁から警報・注意報を取得してキャッシュを更新するます。
+// 取得に失敗した場合は直近のキャッシュ（なければ空）をエラーと一緒に返すのです。
+func (c *Client) RefreshAlerts(ctx context.Context, officeCode, areaCode string) ([]models.WeatherAlert, error) {
+	doc, err := c.fetchWarnings(ctx, officeCode)
+	if err != nil {
+		return c.CachedAlerts(areaCode), err
+	}
+
+	alerts := AlertsForArea(doc, areaCode)
+	if _, err := c.fc.Write(CacheKey(areaCode), alerts, map[string]string{
+		"officeCode":     officeCode,
+		"areaCode":       areaCode,
+		"reportDatetime": doc.ReportDatetime,
+		"source":         "jma",
+	}); err != nil {
+		fmt.Printf("⚠️ 警報・注意報キャッシュ保存失敗: %v\n", err)
+	}
+
+	return alerts, nil
+}
+
+// CachedAlerts は期限に関わらずキャッシュ済みの警報・注意報を返すます。
+// キャッシュが無い場合は空のスライスなのです。
+func (c *Client) CachedAlerts(areaCode string) []models.WeatherAlert {
+	cached := []models.WeatherAlert{}
+	if _, found, _, err := c.fc.ReadPayload(CacheKey(areaCode), 0, &cached); !found || err != nil {
+		return []models.WeatherAlert{}
+	}
+	return cached
+}
+
+// fetchWarnings は府県予報区の警報・注意報 JSON を取得するます。
+func (c *Client) fetchWarnings(ctx context.Context, officeCode string) (*WarningDocument, error) {
+	url := fmt.Sprintf("%s/%s.json", c.baseURL, officeCode)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("リクエスト作成失敗するます: %w", err)
+	}
+	req.Header.Set("User-Agent", "FamilyDashboard/1.0 (https://github.com/rihow/FamilyDashboard; personal-use)")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("気象庁 警報・注意報リクエスト失敗するます: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("気象庁APIエラー: code=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var doc WarningDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("警報・注意報レスポンスパース失敗するます: %w", err)
+	}
+
+	return &doc, nil
+}
+
+// AlertsForArea は区域で発表中の警報・注意報を重大度の高い順に返すます。
+// 市町村コードでも一次細分区域コードでも指定できるのです。
+func AlertsForArea(doc *WarningDocument, areaCode string) []models.WeatherAlert {
+	alerts := []models.WeatherAlert{}
+	if doc == nil {
+		return alerts
+	}
+
+	for _, areaType := range doc.AreaTypes {
+		for _, area := range areaType.Areas {
+			if area.Code != areaCode {
+				continue
+			}
+			for _, warning := range area.Warnings {
+				if !isActive(warning.Status) {
+					continue
+				}
+				kind, ok := warningKinds[warning.Code]
+				if !ok {
+					continue
+				}
+				alerts = append(alerts, models.WeatherAlert{
+					Title:    kind.name + kind.severity,
+					Headline: strings.TrimSpace(doc.HeadlineText),
+					Desc:     fmt.Sprintf("%s（%s %s）", warning.Status, doc.PublishingOffice, formatReportTime(doc.ReportDatetime)),
+					Severity: kind.severity,
+				})
+			}
+			// 同じ区域が複数の区域種別に出てくることはないので、最初の一致で終わるます
+			sortAlerts(alerts)
+			return alerts
+		}
+	}
+
+	return alerts
+}
+
+// isActive は発表中（発表・継続・切り替え）の状態かを判定するます。
+func isActive(status string) bool {
+	switch status {
+	case "", "解除", "発表警報・注意報はなし":
+		return false
+	default:
+		return true
+	}
+}
+
+// sortAlerts は重大度の高い順に並べるます（同じ重大度は発表順のまま）。
+func sortAlerts(alerts []models.WeatherAlert) {
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return severityRank[alerts[i].Severity] > severityRank[alerts[j].Severity]
+	})
+}
+
+// formatReportTime は発表時刻を "1月2日 15時04分発表" 形式にするます。
+func formatReportTime(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return fmt.Sprintf("%d月%d日 %02d時%02d分発表", t.Month(), t.Day(), t.Hour(), t.Minute())
+}
@@ -0,0 +1,181 @@
+package jma
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/cache"
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// loadFixture は testdata の警報・注意報 JSON を読み込むます。
+func loadFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("fixture 読み込み失敗: %v", err)
+	}
+	return data
+}
+
+// newFixtureServer は fixture を返す気象庁 API の代わりのサーバーを作るます。
+func newFixtureServer(t *testing.T, data []byte) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/130000.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestAlertsForArea(t *testing.T) {
+	var doc WarningDocument
+	if err := json.Unmarshal(loadFixture(t, "warning_130000.json"), &doc); err != nil {
+		t.Fatalf("fixture パース失敗: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		areaCode string
+		want     []models.WeatherAlert
+	}{
+		{
+			name:     "一次細分区域（解除は除外、警報が先）",
+			areaCode: "130010",
+			want: []models.WeatherAlert{
+				{Title: "大雨警報", Severity: SeverityWarning},
+				{Title: "雷注意報", Severity: SeverityAdvisory},
+				{Title: "洪水注意報", Severity: SeverityAdvisory},
+			},
+		},
+		{
+			name:     "特別警報が先頭・不明なコードは無視",
+			areaCode: "130030",
+			want: []models.WeatherAlert{
+				{Title: "波浪特別警報", Severity: SeverityEmergency},
+				{Title: "波浪注意報", Severity: SeverityAdvisory},
+			},
+		},
+		{
+			name:     "市町村",
+			areaCode: "1310100",
+			want: []models.WeatherAlert{
+				{Title: "大雨警報", Severity: SeverityWarning},
+				{Title: "雷注意報", Severity: SeverityAdvisory},
+			},
+		},
+		{name: "発表なし", areaCode: "130020", want: []models.WeatherAlert{}},
+		{name: "解除のみ", areaCode: "1320100", want: []models.WeatherAlert{}},
+		{name: "区域なし", areaCode: "999999", want: []models.WeatherAlert{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AlertsForArea(&doc, tt.areaCode)
+			if got == nil {
+				t.Fatal("alerts は nil ではなく空スライスであるべき")
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("alerts = %+v, want %d 件", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got[i].Title != want.Title || got[i].Severity != want.Severity {
+					t.Errorf("alerts[%d] = %s/%s, want %s/%s", i, got[i].Title, got[i].Severity, want.Title, want.Severity)
+				}
+				if got[i].Headline != doc.HeadlineText {
+					t.Errorf("alerts[%d].Headline = %q", i, got[i].Headline)
+				}
+			}
+		})
+	}
+
+	if got := AlertsForArea(&doc, "130010")[0].Desc; got != "発表（気象庁 7月15日 16時05分発表）" {
+		t.Errorf("Desc = %q", got)
+	}
+}
+
+func TestGetAlertsUsesCache(t *testing.T) {
+	server, requests := newFixtureServer(t, loadFixture(t, "warning_130000.json"))
+	fc := cache.New(t.TempDir())
+	client := NewClient(fc)
+	client.baseURL = server.URL
+	ctx := context.Background()
+
+	alerts, err := client.GetAlerts(ctx, "130000", "130010")
+	if err != nil {
+		t.Fatalf("GetAlerts: %v", err)
+	}
+	if len(alerts) != 3 {
+		t.Fatalf("alerts = %d 件, want 3", len(alerts))
+	}
+
+	// 2回目はキャッシュから返る
+	if _, err := client.GetAlerts(ctx, "130000", "130010"); err != nil {
+		t.Fatalf("GetAlerts (cache): %v", err)
+	}
+	if *requests != 1 {
+		t.Errorf("requests = %d, want 1", *requests)
+	}
+
+	// 天気とは別のキャッシュキーに保存される
+	entry, exists, _, err := fc.Read(CacheKey("130010"), 0)
+	if err != nil || !exists {
+		t.Fatalf("cache entry missing: exists=%v err=%v", exists, err)
+	}
+	if entry.Meta["reportDatetime"] != "2025-07-15T16:05:00+09:00" {
+		t.Errorf("meta = %+v", entry.Meta)
+	}
+}
+
+func TestRefreshAlertsDegradesWhenUnreachable(t *testing.T) {
+	server, _ := newFixtureServer(t, loadFixture(t, "warning_130000.json"))
+	fc := cache.New(t.TempDir())
+	client := NewClient(fc)
+	client.httpClient.Timeout = 2 * time.Second
+	ctx := context.Background()
+
+	// キャッシュがなければ空のスライスとエラー
+	client.baseURL = "http://127.0.0.1:1"
+	alerts, err := client.RefreshAlerts(ctx, "130000", "130010")
+	if err == nil {
+		t.Fatal("到達できないフィードでエラーになるべき")
+	}
+	if alerts == nil || len(alerts) != 0 {
+		t.Fatalf("alerts = %+v, want 空スライス", alerts)
+	}
+
+	// 一度取得できれば、その後の失敗では直近のキャッシュを返す
+	client.baseURL = server.URL
+	if _, err := client.RefreshAlerts(ctx, "130000", "130010"); err != nil {
+		t.Fatalf("RefreshAlerts: %v", err)
+	}
+	client.baseURL = "http://127.0.0.1:1"
+	alerts, err = client.RefreshAlerts(ctx, "130000", "130010")
+	if err == nil {
+		t.Fatal("到達できないフィードでエラーになるべき")
+	}
+	if len(alerts) != 3 {
+		t.Errorf("stale alerts = %d 件, want 3", len(alerts))
+	}
+
+	// 壊れたレスポンスでもキャッシュを返す
+	broken, _ := newFixtureServer(t, []byte("<html>maintenance</html>"))
+	client.baseURL = broken.URL
+	alerts, err = client.RefreshAlerts(ctx, "130000", "130010")
+	if err == nil || len(alerts) != 3 {
+		t.Errorf("broken feed: alerts=%d err=%v", len(alerts), err)
+	}
+}
@@ -0,0 +1,225 @@
+package nextcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/config"
+)
+
+// account は CalDAV アカウント1件の接続なのです。
+// 接続先・認証・コレクションの検出結果をアカウントごとに持つのです。
+type account struct {
+	id         string
+	preset     string   // config.CalDAVPresetGeneric か config.CalDAVPresetNextcloud
+	legacy     bool     // nextcloud の設定から作ったアカウント（カレンダー名に "ID/" を付けないのです）
+	serverURL  *url.URL // 設定のサーバーURL
+	username   string
+	httpClient *http.Client
+	discovery  discoveryCache // current-user-principal から検出したコレクション
+}
+
+// newAccount は CalDAV アカウントの接続を作るます。
+// ユーザー名が空の場合は認証しないのです（認証の無い Radicale など）。
+// 認証情報はサーバーURLのホストと trustedHosts にだけ送るます。
+func newAccount(cfg config.CalDAVAccount) (*account, error) {
+	serverURL, err := url.Parse(cfg.ServerURL)
+	if err != nil || serverURL.Host == "" {
+		return nil, fmt.Errorf("%s: serverUrl が不正です: %q", cfg.ID, cfg.ServerURL)
+	}
+
+	// HTTPクライアントを作成（タイムアウト付き＋Basic認証）
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	if cfg.Username != "" {
+		hosts := map[string]bool{serverURL.Host: true}
+		for _, host := range cfg.TrustedHosts {
+			hosts[host] = true
+		}
+		httpClient.Transport = &basicAuthTransport{
+			Username: cfg.Username,
+			Password: cfg.Password,
+			Hosts:    hosts,
+		}
+	}
+
+	return &account{
+		id:         cfg.ID,
+		preset:     cfg.GetPreset(),
+		legacy:     cfg.ID == config.NextcloudAccountID && cfg.GetPreset() == config.CalDAVPresetNextcloud,
+		serverURL:  serverURL,
+		username:   cfg.Username,
+		httpClient: httpClient,
+	}, nil
+}
+
+// collectionRef は設定のカレンダー・タスクリスト1つなのです。
+type collectionRef struct {
+	account *account
+	name    string // 設定に書いた名前（名前・表示名・URL）
+	key     string // イベント・タスク・エラーに付ける名前（nextcloud の設定はそのまま、ほかは "アカウントID/名前"）
+}
+
+// newCollectionRefs は設定の名前をアカウントのコレクションの参照にするます。
+func newCollectionRefs(a *account, names []string) []collectionRef {
+	refs := make([]collectionRef, 0, len(names))
+	for _, name := range names {
+		key := name
+		if !a.legacy {
+			key = a.id + "/" + name
+		}
+		refs = append(refs, collectionRef{account: a, name: name, key: key})
+	}
+	return refs
+}
+
+// findCollectionRef は key が一致する参照を返すます。
+func findCollectionRef(refs []collectionRef, key string) (collectionRef, bool) {
+	for _, ref := range refs {
+		if ref.key == key {
+			return ref, true
+		}
+	}
+	return collectionRef{}, false
+}
+
+// collectionKeys は参照の key を設定の順に返すます。
+func collectionKeys(refs []collectionRef) []string {
+	keys := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		keys = append(keys, ref.key)
+	}
+	return keys
+}
+
+// storeID はオブジェクトストアでコレクションを区別するためのアカウントIDなのです。
+// nextcloud の設定のアカウントは、以前から保存しているファイルをそのまま使うために空にするます。
+func (a *account) storeID() string {
+	if a.legacy {
+		return ""
+	}
+	return a.id
+}
+
+// getCalendarPath はカレンダーのCalDAVパスを返すます。
+// Nextcloudの標準パス: /remote.php/dav/calendars/USERNAME/CALENDARNAME/
+func (a *account) getCalendarPath(calendarName string) string {
+	if calendarName == "" {
+		calendarName = "personal" // デフォルトカレンダー
+	}
+	return fmt.Sprintf("/remote.php/dav/calendars/%s/%s/", a.username, calendarName)
+}
+
+// getTasksPath はタスクのCalDAVパスを返すます。
+// Nextcloudの標準パス: /remote.php/dav/calendars/USERNAME/TASKLISTNAME/
+func (a *account) getTasksPath(taskListName string) string {
+	if taskListName == "" {
+		taskListName = "tasks" // デフォルトタスクリスト
+	}
+	return fmt.Sprintf("/remote.php/dav/calendars/%s/%s/", a.username, taskListName)
+}
+
+// resolveDAVURL は base URL と DAVパスを結合するます。
+// DAVパスは絶対URLでもよいのです（別のホストにあるコレクション）。
+func (a *account) resolveDAVURL(davPath string) (string, error) {
+	ref, err := url.Parse(davPath)
+	if err != nil {
+		return "", fmt.Errorf("DAVパス解析失敗: %w", err)
+	}
+	return a.serverURL.ResolveReference(ref).String(), nil
+}
+
+// davRef は href を base から解決して、このアカウントで使う参照にするます。
+// サーバーURLと同じホストならパス、別のホスト（iCloud のコレクションなど）なら絶対URLなのです。
+func (a *account) davRef(base, href string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", err
+	}
+	resolved := a.serverURL.ResolveReference(baseURL).ResolveReference(ref)
+	if resolved.Scheme == a.serverURL.Scheme && resolved.Host == a.serverURL.Host {
+		return resolved.Path, nil
+	}
+	resolved.RawQuery, resolved.Fragment = "", ""
+	return resolved.String(), nil
+}
+
+// memberHref は multistatus の href をコレクション内のリソースの参照にするます。
+// コレクション自身とサブコレクションは対象外なのです。
+func (a *account) memberHref(collectionPath, href string) (string, bool) {
+	ref, err := a.davRef(collectionPath, href)
+	if err != nil || ref == "" || strings.HasSuffix(ref, "/") {
+		return "", false
+	}
+	if strings.TrimSuffix(ref, "/") == strings.TrimSuffix(collectionPath, "/") {
+		return "", false
+	}
+	return ref, true
+}
+
+// davStatusError は PROPFIND・REPORT が 207 以外を返した場合のエラーなのです。
+type davStatusError struct {
+	Method string
+	Code   int
+}
+
+// Error はエラーメッセージを返すます。
+func (e *davStatusError) Error() string {
+	return fmt.Sprintf("%s HTTPエラー: code=%d", e.Method, e.Code)
+}
+
+// hrefFor はリクエスト本文に書く href（エスケープしたパス）を返すます。
+func hrefFor(ref string) string {
+	if u, err := url.Parse(ref); err == nil && u.IsAbs() {
+		return u.EscapedPath()
+	}
+	return (&url.URL{Path: ref}).EscapedPath()
+}
+
+// davMultiStatus は PROPFIND・REPORT を送信して multistatus を解析するます。
+// 207 以外はエラーなのです（sync-token の期限切れ（403/409 の valid-sync-token）は errSyncTokenInvalid を含むのです）。
+func (a *account) davMultiStatus(ctx context.Context, method, davPath, depth string, body []byte) (*davMultiStatus, error) {
+	targetURL, err := a.resolveDAVURL(davPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%s リクエスト作成失敗: %w", method, err)
+	}
+	if depth != "" {
+		req.Header.Set("Depth", depth)
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s リクエスト失敗: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if strings.Contains(string(respBody), "valid-sync-token") {
+			return nil, fmt.Errorf("%s HTTPエラー: code=%d: %w", method, resp.StatusCode, errSyncTokenInvalid)
+		}
+		return nil, &davStatusError{Method: method, Code: resp.StatusCode}
+	}
+
+	var ms davMultiStatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("%s レスポンス解析失敗: %w", method, err)
+	}
+	return &ms, nil
+}
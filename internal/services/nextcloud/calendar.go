@@ -5,9 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -17,10 +17,29 @@ import (
 	"github.com/rihow/FamilyDashboard/internal/models"
 )
 
+// calendarCacheKeyPrefix はカレンダーキャッシュキーの接頭辞なのです。
+// 書き込み時は表示範囲に関わらずこの接頭辞のキャッシュをすべて破棄するます。
+const calendarCacheKeyPrefix = "nextcloud_calendar_events_"
+
+// CalendarCacheKey は表示範囲ごとのカレンダーキャッシュキーを返すます。
+// 範囲ごとにキーを分けるので、表示日数の異なる端末同士でキャッシュを上書きし合わないのです。
+func CalendarCacheKey(startDate time.Time, days int) string {
+	return fmt.Sprintf("%s%s_%dd", calendarCacheKeyPrefix, startDate.Format("20060102"), days)
+}
+
 // GetCalendarEvents はNextcloud CalDAVからカレンダーイベントを取得するます。
-// 複数のカレンダーから今日から7日分のイベントを取得し、終日/時間帯別に分類して返すのです。
-func (c *Client) GetCalendarEvents(ctx context.Context) (*models.CalendarResponse, error) {
-	cacheKey := "nextcloud_calendar_events_all"
+// 複数のカレンダーから startDate（Asia/Tokyo の0時）から days 日分のイベントを取得し、
+// 終日/時間帯別に分類して返すのです。
+func (c *Client) GetCalendarEvents(ctx context.Context, startDate time.Time, days int) (*models.CalendarResponse, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("表示日数は1以上である必要があります: %d", days)
+	}
+
+	// 指定日から days 日分の範囲を設定（Asia/Tokyo）
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	startDate = startOfDay(startDate, loc)
+
+	cacheKey := CalendarCacheKey(startDate, days)
 	ttl := c.config.GetRefreshInterval("calendar")
 
 	// キャッシュを確認するます
@@ -34,71 +53,46 @@ func (c *Client) GetCalendarEvents(ctx context.Context) (*models.CalendarRespons
 		fmt.Printf("⚠️ キャッシュデータのパース失敗: %v\n", err)
 	}
 
-	// 複数カレンダー名を取得するます
-	calendarNames := c.config.GetCalendarNames()
-	if len(calendarNames) == 0 {
-		return nil, fmt.Errorf("カレンダー名が設定されていません")
-	}
+	return c.RefreshCalendarEvents(ctx, startDate, days)
+}
 
-	fmt.Printf("🌐 Nextcloud CalDAV から %d 個のカレンダーを取得するます...\n", len(calendarNames))
+// RefreshCalendarEvents はキャッシュを見ずに Nextcloud からイベントを取得してキャッシュを更新するます。
+// バックグラウンド更新から呼ばれるほか、GetCalendarEvents のキャッシュミス時にも使うのです。
+func (c *Client) RefreshCalendarEvents(ctx context.Context, startDate time.Time, days int) (*models.CalendarResponse, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("表示日数は1以上である必要があります: %d", days)
+	}
 
-	// 今日から7日分の範囲を設定（Asia/Tokyo）
 	loc, _ := time.LoadLocation("Asia/Tokyo")
-	now := time.Now().In(loc)
-	startDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
-	endDate := startDate.AddDate(0, 0, 7)
+	startDate = startOfDay(startDate, loc)
+	endDate := startDate.AddDate(0, 0, days)
+	cacheKey := CalendarCacheKey(startDate, days)
+
+	// 全アカウントのカレンダーと ICS 購読カレンダーを取得するます
+	if len(c.calendars) == 0 && len(c.subscriptions) == 0 {
+		return nil, fmt.Errorf("カレンダー名が設定されていません")
+	}
 
-	// 全カレンダーからイベントを収集するます
+	fmt.Printf("🌐 CalDAV から %d 個のカレンダー、ICS から %d 個の購読カレンダーを取得するます...\n", len(c.calendars), len(c.subscriptions))
+
+	// 全カレンダーを並行に取得して、設定の順（CalDAV のカレンダー、購読カレンダーの順）にイベントを集めるます
+	calendarNames := append(collectionKeys(c.calendars), subscriptionNames(c.subscriptions)...)
+	results := fetchCollections(ctx, c, collectionKindCalendar, calendarNames, func(ctx context.Context, calendarName string) ([]eventWithDate, error) {
+		if ref, ok := findCollectionRef(c.calendars, calendarName); ok {
+			return c.fetchCalendarEvents(ctx, ref, startDate, endDate)
+		}
+		subscription, _ := findSubscription(c.subscriptions, calendarName)
+		return c.fetchSubscriptionEvents(ctx, subscription, startDate, endDate)
+	})
 	allEvents := []eventWithDate{}
 	var fetchErrors []error
-
-	for _, calendarName := range calendarNames {
-		fmt.Printf("  📅 カレンダー '%s' からイベント取得中...\n", calendarName)
-
-		// CalDAVクエリを実行するます
-		calendarPath := c.getCalendarPath(calendarName)
-		calendarColor, colorErr := c.getCalendarColor(ctx, calendarPath)
-		if colorErr != nil {
-			fmt.Printf("⚠️ カレンダー '%s' の色取得失敗: %v\n", calendarName, colorErr)
-		}
-
-		query := &caldav.CalendarQuery{
-			CompRequest: caldav.CalendarCompRequest{
-				Name: "VCALENDAR",
-				Comps: []caldav.CalendarCompRequest{
-					{
-						Name:  "VEVENT",
-						Props: []string{"UID", "SUMMARY", "DTSTART", "DTEND", "DESCRIPTION", "LOCATION", "COLOR"},
-					},
-				},
-			},
-			CompFilter: caldav.CompFilter{
-				Name: "VCALENDAR",
-				Comps: []caldav.CompFilter{
-					{
-						Name:  "VEVENT",
-						Start: startDate,
-						End:   endDate,
-					},
-				},
-			},
-		}
-
-		calendarObjects, err := c.caldavClient.QueryCalendar(ctx, calendarPath, query)
-		if err != nil {
+	for _, result := range results {
+		if result.err != nil {
 			// エラーを記録するが続行するます（部分的成功を許容）
-			fmt.Printf("❌ カレンダー '%s' のCalDAVクエリエラー: %v\n", calendarName, err)
-			fetchErrors = append(fetchErrors, fmt.Errorf("calendar '%s': %w", calendarName, err))
+			fetchErrors = append(fetchErrors, result.err)
 			continue
 		}
-
-		// iCalendarオブジェクトをパースして構造化するます
-		for _, obj := range calendarObjects {
-			parsedEvents := parseCalendarObject(obj.Data, startDate, endDate, calendarName, calendarColor)
-			allEvents = append(allEvents, parsedEvents...)
-		}
-
-		fmt.Printf("  ✅ カレンダー '%s' から %d 件のイベント取得\n", calendarName, len(calendarObjects))
+		allEvents = append(allEvents, result.items...)
 	}
 
 	// すべてのカレンダー取得に失敗した場合
@@ -110,17 +104,21 @@ func (c *Client) GetCalendarEvents(ctx context.Context) (*models.CalendarRespons
 			fmt.Println("📦 期限切れキャッシュを返すます")
 			var resp models.CalendarResponse
 			if unmarshalErr := json.Unmarshal(entry.Payload, &resp); unmarshalErr == nil {
-				return &resp, fmt.Errorf("全カレンダー取得失敗（キャッシュ返却）: %d エラー", len(fetchErrors))
+				return &resp, fmt.Errorf("全カレンダー取得失敗（キャッシュ返却）: %d エラー: %w", len(fetchErrors), errors.Join(fetchErrors...))
 			}
 		}
-		return nil, fmt.Errorf("全カレンダー取得失敗: %d エラー", len(fetchErrors))
+		return nil, fmt.Errorf("全カレンダー取得失敗: %d エラー: %w", len(fetchErrors), errors.Join(fetchErrors...))
 	}
 
 	// 日付ごとにイベントを分類するます
 	response := convertToCalendarResponse(allEvents, startDate, endDate)
 
 	// キャッシュに保存するます
-	meta := map[string]string{"source": "nextcloud_calendar_all"}
+	meta := map[string]string{
+		"source": "nextcloud_calendar_all",
+		"from":   startDate.Format("2006-01-02"),
+		"days":   fmt.Sprintf("%d", days),
+	}
 	if _, err := c.cache.Write(cacheKey, response, meta); err != nil {
 		fmt.Printf("⚠️ キャッシュ保存失敗: %v\n", err)
 	}
@@ -133,9 +131,51 @@ func (c *Client) GetCalendarEvents(ctx context.Context) (*models.CalendarRespons
 	return response, nil
 }
 
+// fetchCalendarEvents はカレンダー1つを同期して、期間内のイベントを返すます。
+// 色の PROPFIND は同期と並行に行うのです（色が取れなくてもイベントは返すます）。
+func (c *Client) fetchCalendarEvents(ctx context.Context, ref collectionRef, startDate, endDate time.Time) ([]eventWithDate, error) {
+	calendarName := ref.key
+	fmt.Printf("  📅 カレンダー '%s' からイベント取得中...\n", calendarName)
+	calendarPath, discoveredColor, err := ref.account.resolveCollection(ctx, ref.name, "VEVENT")
+	if err != nil {
+		return nil, err
+	}
+
+	// 検出時に色が分かっていればそれを使い、分からなければ PROPFIND で取得するます
+	colorCh := make(chan string, 1)
+	go func() {
+		if discoveredColor != "" {
+			colorCh <- discoveredColor
+			return
+		}
+		calendarColor, err := ref.account.getCalendarColor(ctx, calendarPath)
+		if err != nil {
+			fmt.Printf("⚠️ カレンダー '%s' の色取得失敗: %v\n", calendarName, err)
+		}
+		colorCh <- calendarColor
+	}()
+
+	// 前回からの差分だけを取得して、保存済みのリソースと合わせて返すます
+	calendarObjects, err := c.syncCollection(ctx, ref.account, calendarPath)
+	calendarColor := <-colorCh
+	if err != nil {
+		return nil, err
+	}
+
+	// iCalendarオブジェクトをパースして構造化するます
+	// 期間外のイベントは parseCalendarObject で除くのです
+	events := []eventWithDate{}
+	for _, obj := range calendarObjects {
+		events = append(events, parseCalendarObject(obj, startDate, endDate, calendarName, calendarColor)...)
+	}
+
+	fmt.Printf("  ✅ カレンダー '%s' の %d 件のオブジェクトを同期\n", calendarName, len(calendarObjects))
+	return events, nil
+}
+
 // getCalendarColor はカレンダーコレクションの色（calendar-color）を取得するます。
 // Nextcloud は calendar-color を #RRGGBB または #RRGGBBAA で返すことがあるのです。
-func (c *Client) getCalendarColor(ctx context.Context, calendarPath string) (string, error) {
+func (a *account) getCalendarColor(ctx context.Context, calendarPath string) (string, error) {
 	requestBody := `<?xml version="1.0" encoding="UTF-8"?>
 <d:propfind xmlns:d="DAV:" xmlns:a="http://apple.com/ns/ical/">
 	<d:prop>
@@ -143,7 +183,7 @@ func (c *Client) getCalendarColor(ctx context.Context, calendarPath string) (str
 	</d:prop>
 </d:propfind>`
 
-	targetURL, err := c.resolveDAVURL(calendarPath)
+	targetURL, err := a.resolveDAVURL(calendarPath)
 	if err != nil {
 		return "", err
 	}
@@ -155,7 +195,7 @@ func (c *Client) getCalendarColor(ctx context.Context, calendarPath string) (str
 	req.Header.Set("Depth", "0")
 	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := a.httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("calendar color取得失敗: %w", err)
 	}
@@ -195,29 +235,18 @@ func (c *Client) getCalendarColor(ctx context.Context, calendarPath string) (str
 	return "", nil
 }
 
-// resolveDAVURL は base URL と DAVパスを結合するます。
-func (c *Client) resolveDAVURL(davPath string) (string, error) {
-	base, err := url.Parse(c.config.Nextcloud.ServerURL)
-	if err != nil {
-		return "", fmt.Errorf("ServerURL解析失敗: %w", err)
-	}
-
-	ref, err := url.Parse(davPath)
-	if err != nil {
-		return "", fmt.Errorf("DAVパス解析失敗: %w", err)
-	}
-
-	return base.ResolveReference(ref).String(), nil
-}
-
 // eventWithDate はイベントと日付情報を保持する内部構造体なのです。
+// date は開始日時、end は終了日時（終日イベントは翌日0時の排他的終了）なのです。
 type eventWithDate struct {
 	event  models.Event
 	date   time.Time
+	end    time.Time
 	allDay bool
 }
 
 // parseCalendarObject はiCalendarデータをパースしてイベントリストに変換するます。
+// 繰り返しイベント（RRULE/RDATE/EXDATE）は期間内の発生ごとに展開し、
+// RECURRENCE-ID 付きの上書きイベントで該当の発生を置き換えるのです。
 func parseCalendarObject(cal *ical.Calendar, startDate, endDate time.Time, calendarName, calendarColor string) []eventWithDate {
 	events := []eventWithDate{}
 
@@ -226,6 +255,28 @@ func parseCalendarObject(cal *ical.Calendar, startDate, endDate time.Time, calen
 	}
 
 	loc, _ := time.LoadLocation("Asia/Tokyo")
+	tz := newTZResolver(cal, loc)
+
+	// 上書きされた発生（UID → RECURRENCE-IDキー）を先に集めるます
+	overridden := map[string]map[string]bool{}
+	for _, comp := range cal.Children {
+		if comp.Name != "VEVENT" {
+			continue
+		}
+		uid := comp.Props.Get("UID")
+		recurrenceID := comp.Props.Get("RECURRENCE-ID")
+		if uid == nil || recurrenceID == nil {
+			continue
+		}
+		recurrenceTime, isAllDay := tz.parseProp(recurrenceID)
+		if recurrenceTime.IsZero() {
+			continue
+		}
+		if overridden[uid.Value] == nil {
+			overridden[uid.Value] = map[string]bool{}
+		}
+		overridden[uid.Value][recurrenceKey(recurrenceTime, isAllDay)] = true
+	}
 
 	for _, comp := range cal.Children {
 		if comp.Name != "VEVENT" {
@@ -236,72 +287,133 @@ func parseCalendarObject(cal *ical.Calendar, startDate, endDate time.Time, calen
 		uid := comp.Props.Get("UID")
 		summary := comp.Props.Get("SUMMARY")
 		dtStart := comp.Props.Get("DTSTART")
-		dtEnd := comp.Props.Get("DTEND")
-		description := comp.Props.Get("DESCRIPTION")
-		location := comp.Props.Get("LOCATION")
-		color := comp.Props.Get("COLOR")
 
 		if uid == nil || summary == nil || dtStart == nil {
 			continue
 		}
 
-		// 開始日時をパースするます
-		startTime, isAllDay := parseDateTime(dtStart.Value, loc)
+		// 開始日時をパースするます（TZID/UTC を考慮）
+		startTime, isAllDay := tz.parseProp(dtStart)
 		if startTime.IsZero() {
 			continue
 		}
+		duration := eventEndTime(comp, startTime, isAllDay, tz).Sub(startTime)
 
-		// 期間外のイベントはスキップ
-		if startTime.Before(startDate) || startTime.After(endDate) {
+		// RECURRENCE-ID 付きの上書きイベントは、その発生1件として扱うます
+		if recurrenceID := comp.Props.Get("RECURRENCE-ID"); recurrenceID != nil {
+			if isCancelled(comp) || !overlapsRange(startTime, startTime.Add(duration), startDate, endDate) {
+				continue
+			}
+			recurrenceTime, recurrenceAllDay := tz.parseProp(recurrenceID)
+			id := occurrenceID(uid.Value, recurrenceKey(recurrenceTime, recurrenceAllDay))
+			events = append(events, buildEvent(comp, id, startTime.In(loc), duration, isAllDay, calendarName, calendarColor))
 			continue
 		}
 
-		// 終了日時をパースするます
-		endTime := startTime.Add(1 * time.Hour) // デフォルト1時間
-		if dtEnd != nil {
-			parsedEnd, _ := parseDateTime(dtEnd.Value, loc)
-			if !parsedEnd.IsZero() {
-				endTime = parsedEnd
+		// 単発イベントは期間と重ならなければスキップするます（期間前に開始して継続中のものは含める）
+		if !isRecurring(comp) {
+			if !overlapsRange(startTime, startTime.Add(duration), startDate, endDate) {
+				continue
 			}
+			events = append(events, buildEvent(comp, uid.Value, startTime.In(loc), duration, isAllDay, calendarName, calendarColor))
+			continue
 		}
 
-		// 色を決定（優先順位: イベント色 > カレンダー色 > デフォルト）
-		colorValue := "#3788d8"
-		if normalized, ok := normalizeHexColor(calendarColor); ok {
-			colorValue = normalized
+		// 繰り返しイベントを期間内の発生ごとに展開するます
+		// TZID のタイムゾーンで展開するので、夏時間をまたいでも現地時刻が保たれるのです
+		occurrences, err := expandRecurrence(comp, startTime, startDate.Add(-duration), endDate, tz)
+		if err != nil {
+			fmt.Printf("⚠️ 繰り返しイベント '%s' の展開失敗: %v\n", uid.Value, err)
+			continue
 		}
-		if color != nil && color.Value != "" {
-			if normalized, ok := normalizeHexColor(color.Value); ok {
-				colorValue = normalized
+		for _, occurrenceStart := range occurrences {
+			occurrenceStart = tz.localize(occurrenceStart, dtStart.Params.Get(ical.PropTimezoneID)).In(loc)
+			if !overlapsRange(occurrenceStart, occurrenceStart.Add(duration), startDate, endDate) {
+				continue
 			}
+			key := recurrenceKey(occurrenceStart, isAllDay)
+			if overridden[uid.Value][key] {
+				continue
+			}
+			id := occurrenceID(uid.Value, key)
+			events = append(events, buildEvent(comp, id, occurrenceStart, duration, isAllDay, calendarName, calendarColor))
 		}
+	}
+
+	return events
+}
 
-		// Eventオブジェクトを作成
-		event := models.Event{
-			ID:       uid.Value,
-			Title:    summary.Value,
-			Start:    startTime.Format(time.RFC3339),
-			End:      endTime.Format(time.RFC3339),
-			Color:    colorValue,
-			Calendar: calendarName,
-			Location: "",
-			Desc:     "",
+// eventEndTime は VEVENT の終了日時を返すます。
+// DTEND → DURATION → 既定値（終日は1日、時間指定は1時間）の順で決定するのです。
+func eventEndTime(comp *ical.Component, startTime time.Time, isAllDay bool, tz *tzResolver) time.Time {
+	if dtEnd := comp.Props.Get("DTEND"); dtEnd != nil {
+		parsedEnd, _ := tz.parseProp(dtEnd)
+		if !parsedEnd.IsZero() {
+			return parsedEnd
 		}
-		if description != nil {
-			event.Desc = description.Value
+	}
+	if durationProp := comp.Props.Get("DURATION"); durationProp != nil {
+		if duration, err := durationProp.Duration(); err == nil && duration > 0 {
+			return startTime.Add(duration)
 		}
-		if location != nil {
-			event.Location = normalizeLocation(location.Value)
+	}
+	if isAllDay {
+		return startTime.AddDate(0, 0, 1) // 終日はその日のみ
+	}
+	return startTime.Add(1 * time.Hour) // デフォルト1時間
+}
+
+// overlapsRange はイベント期間 [start, end) が範囲 [rangeStart, rangeEnd) と重なるかを判定するます。
+// 長さ0のイベントは開始時刻が範囲内にあれば重なるとみなすのです。
+func overlapsRange(start, end, rangeStart, rangeEnd time.Time) bool {
+	if !end.After(start) {
+		return !start.Before(rangeStart) && start.Before(rangeEnd)
+	}
+	return start.Before(rangeEnd) && end.After(rangeStart)
+}
+
+// buildEvent は VEVENT と発生時刻から eventWithDate を組み立てるます。
+func buildEvent(comp *ical.Component, id string, startTime time.Time, duration time.Duration, isAllDay bool, calendarName, calendarColor string) eventWithDate {
+	summary := comp.Props.Get("SUMMARY")
+	description := comp.Props.Get("DESCRIPTION")
+	location := comp.Props.Get("LOCATION")
+	color := comp.Props.Get("COLOR")
+
+	// 色を決定（優先順位: イベント色 > カレンダー色 > デフォルト）
+	colorValue := "#3788d8"
+	if normalized, ok := normalizeHexColor(calendarColor); ok {
+		colorValue = normalized
+	}
+	if color != nil && color.Value != "" {
+		if normalized, ok := normalizeHexColor(color.Value); ok {
+			colorValue = normalized
 		}
+	}
 
-		events = append(events, eventWithDate{
-			event:  event,
-			date:   startTime,
-			allDay: isAllDay,
-		})
+	// Eventオブジェクトを作成
+	event := models.Event{
+		ID:       id,
+		Title:    summary.Value,
+		Start:    startTime.Format(time.RFC3339),
+		End:      startTime.Add(duration).Format(time.RFC3339),
+		Color:    colorValue,
+		Calendar: calendarName,
+		Location: "",
+		Desc:     "",
+	}
+	if description != nil {
+		event.Desc = description.Value
+	}
+	if location != nil {
+		event.Location = normalizeLocation(location.Value)
 	}
 
-	return events
+	return eventWithDate{
+		event:  event,
+		date:   startTime,
+		end:    startTime.Add(duration),
+		allDay: isAllDay,
+	}
 }
 
 // normalizeLocation は LOCATION 値を表示向けに正規化するます。
@@ -372,6 +484,7 @@ func normalizeHexColor(value string) (string, bool) {
 
 // parseDateTime はiCalendar日時文字列をパースするます。
 // YYYYMMDD形式（終日）とYYYYMMDDTHHMMSS形式（時間指定）に対応するのです。
+// 末尾Zの UTC 時刻は UTC として解釈してから loc に変換し、それ以外は loc のローカル時刻として扱うます。
 func parseDateTime(value string, loc *time.Location) (time.Time, bool) {
 	value = strings.TrimSpace(value)
 
@@ -383,10 +496,16 @@ func parseDateTime(value string, loc *time.Location) (time.Time, bool) {
 		}
 	}
 
+	// UTC時刻（YYYYMMDDTHHMMSSZフォーマット）
+	if len(value) == 16 && strings.HasSuffix(value, "Z") {
+		t, err := time.ParseInLocation("20060102T150405Z", value, time.UTC)
+		if err == nil {
+			return t.In(loc), false // 時間指定
+		}
+	}
+
 	// 時間指定イベント（YYYYMMDDTHHMMSSフォーマット）
-	if len(value) >= 15 {
-		// タイムゾーン指定を取り除く
-		value = strings.TrimSuffix(value, "Z")
+	if len(value) == 15 {
 		t, err := time.ParseInLocation("20060102T150405", value, loc)
 		if err == nil {
 			return t, false // 時間指定
@@ -404,11 +523,12 @@ func parseDateTime(value string, loc *time.Location) (time.Time, bool) {
 
 // convertToCalendarResponse はイベントリストを日付ごとに分類して
 // CalendarResponseに変換するます。
+// 複数日にまたがるイベントは期間内の各日に配置し、継続フラグを付けるのです。
 func convertToCalendarResponse(events []eventWithDate, startDate, endDate time.Time) *models.CalendarResponse {
 	// 日付ごとのマップを作成
 	dayMap := make(map[string]*models.CalendarDay)
 
-	// 7日分の日付を初期化
+	// 表示範囲の日付を初期化
 	for d := startDate; d.Before(endDate); d = d.AddDate(0, 0, 1) {
 		dateStr := d.Format("2006-01-02")
 		dayMap[dateStr] = &models.CalendarDay{
@@ -418,18 +538,30 @@ func convertToCalendarResponse(events []eventWithDate, startDate, endDate time.T
 		}
 	}
 
-	// イベントを分類
+	// イベントを分類（複数日にまたがるイベントは各日に配置する）
 	for _, evt := range events {
-		dateStr := evt.date.Format("2006-01-02")
-		day, exists := dayMap[dateStr]
-		if !exists {
-			continue
+		lastExclusive := evt.end
+		if !lastExclusive.After(evt.date) {
+			lastExclusive = evt.date.Add(time.Nanosecond)
 		}
 
-		if evt.allDay {
-			day.AllDay = append(day.AllDay, evt.event)
-		} else {
-			day.Timed = append(day.Timed, evt.event)
+		loc := startDate.Location()
+		firstDay := time.Date(evt.date.Year(), evt.date.Month(), evt.date.Day(), 0, 0, 0, 0, loc)
+		for d := firstDay; d.Before(lastExclusive) && d.Before(endDate); d = d.AddDate(0, 0, 1) {
+			day, exists := dayMap[d.Format("2006-01-02")]
+			if !exists {
+				continue
+			}
+
+			event := evt.event
+			event.ContinuesFromPrevDay = evt.date.Before(d)
+			event.ContinuesToNextDay = lastExclusive.After(d.AddDate(0, 0, 1))
+
+			if evt.allDay {
+				day.AllDay = append(day.AllDay, event)
+			} else {
+				day.Timed = append(day.Timed, event)
+			}
 		}
 	}
 
@@ -450,3 +582,302 @@ func convertToCalendarResponse(events []eventWithDate, startDate, endDate time.T
 		Days: days,
 	}
 }
+
+// CreateEvent は指定カレンダーに VEVENT を新規作成するます。
+// 成功時はカレンダーキャッシュを破棄して、作成したイベントを返すのです。
+func (c *Client) CreateEvent(ctx context.Context, req models.EventWriteRequest) (*models.Event, error) {
+	if req.Title == nil || strings.TrimSpace(*req.Title) == "" {
+		return nil, fmt.Errorf("%w: title は必須です", ErrInvalidInput)
+	}
+	if req.Start == nil || strings.TrimSpace(*req.Start) == "" {
+		return nil, fmt.Errorf("%w: start は必須です", ErrInvalidInput)
+	}
+
+	if _, ok := findSubscription(c.subscriptions, req.Calendar); ok {
+		return nil, fmt.Errorf("%w: 購読カレンダー '%s' は読み取り専用なのです", ErrInvalidInput, req.Calendar)
+	}
+	calendarName, err := resolveCollectionName(collectionKeys(c.calendars), req.Calendar, "カレンダー")
+	if err != nil {
+		return nil, err
+	}
+	ref, _ := findCollectionRef(c.calendars, calendarName)
+	calendarPath, err := ref.calendarPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := newUID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	vevent := ical.NewComponent(ical.CompEvent)
+	vevent.Props.SetText(ical.PropUID, uid)
+	vevent.Props.SetDateTime(ical.PropCreated, now)
+	if err := applyEventChanges(vevent, req, now); err != nil {
+		return nil, err
+	}
+
+	if err := ref.account.putCalendarObject(ctx, objectPathFor(calendarPath, uid), newCalendarObject(vevent), ""); err != nil {
+		return nil, fmt.Errorf("イベント作成失敗: %w", err)
+	}
+
+	c.invalidateCalendarCache()
+	fmt.Printf("✅ イベント作成成功: %s (%s)\n", *req.Title, calendarName)
+
+	return c.eventFromComponent(ctx, vevent, ref), nil
+}
+
+// UpdateEvent は UID が一致する VEVENT を更新するます。
+// 取得時の ETag を If-Match に付けるので、他の端末の更新と競合した場合は ErrConflict を返すのです。
+func (c *Client) UpdateEvent(ctx context.Context, id string, req models.EventWriteRequest) (*models.Event, error) {
+	object, ref, err := c.findEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var master *ical.Component
+	for _, comp := range object.Data.Children {
+		if comp.Name == ical.CompEvent && comp.Props.Get(ical.PropRecurrenceID) == nil {
+			master = comp
+			break
+		}
+	}
+	if master == nil {
+		return nil, ErrNotFound
+	}
+	if err := applyEventChanges(master, req, now); err != nil {
+		return nil, err
+	}
+
+	if err := ref.account.putCalendarObject(ctx, object.Path, object.Data, object.ETag); err != nil {
+		return nil, fmt.Errorf("イベント更新失敗: %w", err)
+	}
+
+	c.invalidateCalendarCache()
+	fmt.Printf("✅ イベント更新成功: %s\n", id)
+
+	return c.eventFromComponent(ctx, master, ref), nil
+}
+
+// DeleteEvent は UID が一致する VEVENT を削除するます。
+func (c *Client) DeleteEvent(ctx context.Context, id string) error {
+	object, ref, err := c.findEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := ref.account.deleteCalendarObject(ctx, object.Path, object.ETag); err != nil {
+		return fmt.Errorf("イベント削除失敗: %w", err)
+	}
+
+	c.invalidateCalendarCache()
+	fmt.Printf("✅ イベント削除成功: %s\n", id)
+
+	return nil
+}
+
+// findEvent は設定済みの全カレンダーから UID が一致するイベントを探すます。
+// 繰り返しイベントの発生ごとのID（UID_日時）は個別に編集できないので ErrInvalidInput を返すのです。
+func (c *Client) findEvent(ctx context.Context, id string) (*caldav.CalendarObject, collectionRef, error) {
+	if strings.TrimSpace(id) == "" {
+		return nil, collectionRef{}, fmt.Errorf("%w: id は必須です", ErrInvalidInput)
+	}
+
+	object, ref, err := c.findEventByUID(ctx, id)
+	if !errors.Is(err, ErrNotFound) {
+		return object, ref, err
+	}
+
+	// 発生ごとのIDなら、元の UID で一度だけ探し直すます
+	if uid, _, ok := splitOccurrenceID(id); ok {
+		if _, _, err := c.findEventByUID(ctx, uid); err == nil {
+			return nil, collectionRef{}, fmt.Errorf("%w: 繰り返し予定の個別の回は編集できません", ErrInvalidInput)
+		}
+	}
+
+	return nil, collectionRef{}, ErrNotFound
+}
+
+// findEventByUID は設定済みの全カレンダーから UID が完全に一致するイベントを探すます。
+func (c *Client) findEventByUID(ctx context.Context, uid string) (*caldav.CalendarObject, collectionRef, error) {
+	for _, ref := range c.calendars {
+		calendarPath, err := ref.calendarPath(ctx)
+		if err != nil {
+			return nil, collectionRef{}, fmt.Errorf("calendar '%s': %w", ref.key, err)
+		}
+		object, err := ref.account.findObjectByUID(ctx, calendarPath, ical.CompEvent, uid)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, collectionRef{}, fmt.Errorf("calendar '%s': %w", ref.key, err)
+		}
+		return object, ref, nil
+	}
+
+	return nil, collectionRef{}, ErrNotFound
+}
+
+// invalidateCalendarCache は全表示範囲のカレンダーキャッシュを破棄するます。
+func (c *Client) invalidateCalendarCache() {
+	if err := c.cache.DeletePrefix(calendarCacheKeyPrefix); err != nil {
+		fmt.Printf("⚠️ カレンダーキャッシュ削除失敗: %v\n", err)
+	}
+}
+
+// eventFromComponent は書き込んだ VEVENT を API レスポンス用のイベントに変換するます。
+func (c *Client) eventFromComponent(ctx context.Context, comp *ical.Component, ref collectionRef) *models.Event {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	tz := newTZResolver(nil, loc)
+
+	calendarName := ref.key
+	calendarPath, calendarColor, err := ref.account.resolveCollection(ctx, ref.name, "VEVENT")
+	if err == nil && calendarColor == "" {
+		calendarColor, err = ref.account.getCalendarColor(ctx, calendarPath)
+	}
+	if err != nil {
+		fmt.Printf("⚠️ カレンダー '%s' の色取得失敗: %v\n", calendarName, err)
+	}
+
+	startTime, isAllDay := tz.parseProp(comp.Props.Get(ical.PropDateTimeStart))
+	duration := eventEndTime(comp, startTime, isAllDay, tz).Sub(startTime)
+	event := buildEvent(comp, comp.Props.Get(ical.PropUID).Value, startTime.In(loc), duration, isAllDay, calendarName, calendarColor).event
+	return &event
+}
+
+// applyEventChanges はリクエストの変更内容を VEVENT に反映するます。
+// start だけを変更した場合は元の長さを保ったまま移動するのです。
+func applyEventChanges(vevent *ical.Component, req models.EventWriteRequest, now time.Time) error {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	tz := newTZResolver(nil, loc)
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return fmt.Errorf("%w: title は空にできません", ErrInvalidInput)
+		}
+		vevent.Props.SetText(ical.PropSummary, title)
+	}
+
+	if req.Start != nil || req.End != nil || req.AllDay != nil {
+		if isRecurring(vevent) {
+			return fmt.Errorf("%w: 繰り返し予定の日時は変更できません", ErrInvalidInput)
+		}
+
+		currentStart, currentAllDay := tz.parseProp(vevent.Props.Get(ical.PropDateTimeStart))
+		var currentDuration time.Duration
+		if !currentStart.IsZero() {
+			currentDuration = eventEndTime(vevent, currentStart, currentAllDay, tz).Sub(currentStart)
+		}
+
+		start := currentStart
+		allDay := currentAllDay
+		if req.Start != nil {
+			parsed, isDate, err := parseEventTime(*req.Start, loc)
+			if err != nil {
+				return fmt.Errorf("%w: start %v", ErrInvalidInput, err)
+			}
+			start, allDay = parsed, isDate
+		}
+		if req.AllDay != nil {
+			allDay = *req.AllDay
+		}
+		if start.IsZero() {
+			return fmt.Errorf("%w: start は必須です", ErrInvalidInput)
+		}
+
+		var end time.Time
+		if req.End != nil && strings.TrimSpace(*req.End) != "" {
+			parsed, _, err := parseEventTime(*req.End, loc)
+			if err != nil {
+				return fmt.Errorf("%w: end %v", ErrInvalidInput, err)
+			}
+			end = parsed
+		} else if currentDuration > 0 {
+			end = start.Add(currentDuration)
+		}
+
+		if allDay {
+			start = startOfDay(start, loc)
+			if !end.IsZero() {
+				end = startOfDay(end, loc)
+			}
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1) // 終日はその日のみ
+			}
+			vevent.Props.SetDate(ical.PropDateTimeStart, start)
+			vevent.Props.SetDate(ical.PropDateTimeEnd, end)
+		} else {
+			if end.IsZero() {
+				end = start.Add(1 * time.Hour) // デフォルト1時間
+			}
+			if end.Before(start) {
+				return fmt.Errorf("%w: end は start 以降である必要があります", ErrInvalidInput)
+			}
+			vevent.Props.SetDateTime(ical.PropDateTimeStart, start.UTC())
+			vevent.Props.SetDateTime(ical.PropDateTimeEnd, end.UTC())
+		}
+		vevent.Props.Del(ical.PropDuration)
+
+		// 日時を変更したら SEQUENCE を進めるます（RFC 5545）
+		if sequence := vevent.Props.Get(ical.PropSequence); sequence != nil {
+			current, _ := sequence.Int()
+			setIntProp(vevent.Props, ical.PropSequence, current+1)
+		}
+	}
+
+	if req.Location != nil {
+		if strings.TrimSpace(*req.Location) == "" {
+			vevent.Props.Del(ical.PropLocation)
+		} else {
+			vevent.Props.SetText(ical.PropLocation, strings.TrimSpace(*req.Location))
+		}
+	}
+
+	if req.Description != nil {
+		if *req.Description == "" {
+			vevent.Props.Del(ical.PropDescription)
+		} else {
+			vevent.Props.SetText(ical.PropDescription, *req.Description)
+		}
+	}
+
+	if req.Color != nil {
+		if *req.Color == "" {
+			vevent.Props.Del(ical.PropColor)
+		} else {
+			normalized, ok := normalizeHexColor(*req.Color)
+			if !ok {
+				return fmt.Errorf("%w: color は #RRGGBB 形式です: %s", ErrInvalidInput, *req.Color)
+			}
+			vevent.Props.SetText(ical.PropColor, normalized)
+		}
+	}
+
+	vevent.Props.SetDateTime(ical.PropDateTimeStamp, now)
+	vevent.Props.SetDateTime(ical.PropLastModified, now)
+	return nil
+}
+
+// parseEventTime はリクエストの日時文字列をパースするます。
+// YYYY-MM-DD は終日（Asia/Tokyo の0時）、それ以外は RFC3339 として扱うのです。
+func parseEventTime(value string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("は RFC3339 か YYYY-MM-DD 形式です: %s", value)
+	}
+	return t.In(loc), false, nil
+}
+
+// startOfDay は指定時刻の日付の0時を返すます。
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
@@ -6,96 +6,93 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/emersion/go-webdav/caldav"
 	"github.com/rihow/FamilyDashboard/internal/cache"
 	"github.com/rihow/FamilyDashboard/internal/config"
+	"github.com/rihow/FamilyDashboard/internal/status"
 )
 
 // Client は Nextcloud CalDAV/WebDAV のクライアントなのです。
 // カレンダー・タスクの取得とキャッシュ管理を担当するます。
+// Nextcloud のほか、caldavAccounts の CalDAV サーバー（Radicale・Baïkal・Fastmail・iCloud など）もまとめて扱うのです。
 type Client struct {
-	cache        *cache.FileCache
-	config       *config.Config
-	httpClient   *http.Client
-	caldavClient *caldav.Client
+	cache      *cache.FileCache
+	config     *config.Config
+	accounts   []*account
+	calendars  []collectionRef    // 表示するカレンダー（設定の順）
+	taskLists  []collectionRef    // 表示するタスクリスト（設定の順）
+	store      *ObjectStore       // コレクションごとのリソース（差分同期用）
+	errorStore *status.ErrorStore // コレクションごとの取得エラーの記録先（nil なら記録しないのです）
+
+	subscriptions          []config.CalendarSubscription // CalDAV のカレンダーのあとに表示する ICS 購読カレンダー
+	subscriptionHTTPClient *http.Client                  // ICS のダウンロード用（認証なし）
 }
 
 // NewClient は Nextcloud クライアントを初期化するます。
-// Basic認証でCalDAVサーバーに接続する準備をするのです。
+// nextcloud と caldavAccounts のアカウントごとに、Basic認証でCalDAVサーバーに接続する準備をするのです。
+// calendar.subscriptions だけ（CalDAV アカウントなし）でも作れるのです。
 func NewClient(fc *cache.FileCache, cfg *config.Config) (*Client, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("設定が nil なのです")
 	}
 
 	// Nextcloud 設定の検証
-	if cfg.Nextcloud.ServerURL == "" {
-		return nil, fmt.Errorf("Nextcloud ServerURL が設定されていません")
-	}
-	if cfg.Nextcloud.Username == "" {
-		return nil, fmt.Errorf("Nextcloud Username が設定されていません")
+	if cfg.Nextcloud.ServerURL != "" {
+		if cfg.Nextcloud.Username == "" {
+			return nil, fmt.Errorf("Nextcloud Username が設定されていません")
+		}
+		if cfg.Nextcloud.Password == "" {
+			return nil, fmt.Errorf("Nextcloud Password が設定されていません")
+		}
 	}
-	if cfg.Nextcloud.Password == "" {
-		return nil, fmt.Errorf("Nextcloud Password が設定されていません")
+	accountConfigs := cfg.GetCalDAVAccounts()
+	if len(accountConfigs) == 0 && len(cfg.Calendar.Subscriptions) == 0 {
+		return nil, fmt.Errorf("Nextcloud ServerURL が設定されていません（caldavAccounts・calendar.subscriptions も空なのです）")
 	}
 
-	// HTTPクライアントを作成（タイムアウト付き＋Basic認証）
-	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &basicAuthTransport{
-			Username: cfg.Nextcloud.Username,
-			Password: cfg.Nextcloud.Password,
-		},
+	client := &Client{
+		cache:                  fc,
+		config:                 cfg,
+		store:                  NewObjectStore(""),
+		subscriptions:          cfg.Calendar.Subscriptions,
+		subscriptionHTTPClient: &http.Client{Timeout: 30 * time.Second},
 	}
+	for _, accountConfig := range accountConfigs {
+		a, err := newAccount(accountConfig)
+		if err != nil {
+			return nil, fmt.Errorf("CalDAVクライアント初期化エラー: %w", err)
+		}
+		client.accounts = append(client.accounts, a)
+		client.calendars = append(client.calendars, newCollectionRefs(a, accountConfig.CalendarNames)...)
+		client.taskLists = append(client.taskLists, newCollectionRefs(a, accountConfig.TaskListNames)...)
 
-	// CalDAV クライアントを作成（Basic認証付きHTTPクライアント）
-	caldavClient, err := caldav.NewClient(httpClient, cfg.Nextcloud.ServerURL)
-	if err != nil {
-		return nil, fmt.Errorf("CalDAVクライアント初期化エラー: %w", err)
+		fmt.Printf("✅ CalDAV アカウント '%s' 初期化成功: %s (%s, ユーザー: %s)\n",
+			a.id, accountConfig.ServerURL, a.preset, accountConfig.Username)
 	}
-
-	client := &Client{
-		cache:        fc,
-		config:       cfg,
-		httpClient:   httpClient,
-		caldavClient: caldavClient,
+	for _, subscription := range client.subscriptions {
+		fmt.Printf("✅ 購読カレンダー '%s': %s\n", subscription.Name, subscription.GetURL())
 	}
 
-	fmt.Printf("✅ Nextcloud クライアント初期化成功: %s (ユーザー: %s)\n",
-		cfg.Nextcloud.ServerURL, cfg.Nextcloud.Username)
-
 	return client, nil
 }
 
 // basicAuthTransport は Basic認証用のHTTPトランスポートなのです。
+// 認証ヘッダーは Hosts のホスト（アカウントのサーバーと trustedHosts）へのリクエストにだけ付けるのです。
+// 別のホストの href や転送先にパスワードを送らないためなのです。
 type basicAuthTransport struct {
 	Username string
 	Password string
+	Hosts    map[string]bool // 認証情報を送ってよいホスト（host[:port]）
 }
 
 // RoundTrip はHTTPリクエストにBasic認証ヘッダーを追加するます。
 func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req.SetBasicAuth(t.Username, t.Password)
-	return http.DefaultTransport.RoundTrip(req)
-}
-
-// getCalendarPath はカレンダーのCalDAVパスを返すます。
-// Nextcloudの標準パス: /remote.php/dav/calendars/USERNAME/CALENDARNAME/
-func (c *Client) getCalendarPath(calendarName string) string {
-	username := c.config.Nextcloud.Username
-	if calendarName == "" {
-		calendarName = "personal" // デフォルトカレンダー
-	}
-	return fmt.Sprintf("/remote.php/dav/calendars/%s/%s/", username, calendarName)
-}
-
-// getTasksPath はタスクのCalDAVパスを返すます。
-// Nextcloudの標準パス: /remote.php/dav/calendars/USERNAME/TASKLISTNAME/
-func (c *Client) getTasksPath(taskListName string) string {
-	username := c.config.Nextcloud.Username
-	if taskListName == "" {
-		taskListName = "tasks" // デフォルトタスクリスト
+	if !t.Hosts[req.URL.Host] {
+		return http.DefaultTransport.RoundTrip(req)
 	}
-	return fmt.Sprintf("/remote.php/dav/calendars/%s/%s/", username, taskListName)
+	// RoundTrip はリクエストを変更してはいけないので、複製に付けるのです
+	authReq := req.Clone(req.Context())
+	authReq.SetBasicAuth(t.Username, t.Password)
+	return http.DefaultTransport.RoundTrip(authReq)
 }
 
 // getContext はタイムアウト付きのコンテキストを返すます。
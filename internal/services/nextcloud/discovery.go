@@ -0,0 +1,444 @@
+package nextcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/config"
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// davRootPath は Nextcloud の WebDAV のルートなのです（current-user-principal はここに問い合わせるのです）。
+const davRootPath = "/remote.php/dav/"
+
+// wellKnownCalDAVPath は CalDAV のサービスの場所（RFC 6764）なのです。
+// Nextcloud 以外のサーバーは、ここから current-user-principal を探すます。
+const wellKnownCalDAVPath = "/.well-known/caldav"
+
+// discoveryTTL は検出したコレクションの一覧を使い回す時間なのです。
+// カレンダーの追加・名前変更はめったに無いので、更新のたびには問い合わせないのです。
+const discoveryTTL = time.Hour
+
+// discoveryRetryInterval は検出に失敗したときに、次に問い合わせるまでの時間なのです。
+// その間、Nextcloud は従来どおり /remote.php/dav/calendars/USER/NAME/ のパスを使うます。
+const discoveryRetryInterval = 5 * time.Minute
+
+// discoveryCache は検出したコレクションの一覧なのです。
+type discoveryCache struct {
+	mu          sync.Mutex
+	collections []models.CalDAVCollection
+	err         error
+	at          time.Time
+	succeededAt time.Time     // 最後に検出に成功した時刻
+	inflight    chan struct{} // 検出中なら、終わったときに閉じるのです
+}
+
+// ListCollections は全アカウントのコレクションを current-user-principal と calendar-home-set から検出して返すます。
+// キャッシュを使わずに問い合わせて、設定で使っているコレクションに印を付けるのです（/api/admin/collections 用）。
+// 一部のアカウントだけ失敗した場合は、検出できたコレクションとエラーを両方返すます。
+func (c *Client) ListCollections(ctx context.Context) ([]models.CalDAVCollection, error) {
+	result := []models.CalDAVCollection{}
+	var errs []error
+	for _, a := range c.accounts {
+		collections, err := a.discoverCollections(ctx)
+		a.storeDiscovery(collections, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a.id, err))
+			continue
+		}
+
+		calendars := a.usedCollections(collections, c.calendars, "VEVENT")
+		taskLists := a.usedCollections(collections, c.taskLists, "VTODO")
+		for _, collection := range collections {
+			collectionPath := a.collectionPathOf(collection)
+			collection.Account = a.id
+			collection.Calendar = calendars[collectionPath]
+			collection.TaskList = taskLists[collectionPath]
+			result = append(result, collection)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// usedCollections は設定で使っているこのアカウントのコレクションのパスを返すます。
+func (a *account) usedCollections(collections []models.CalDAVCollection, refs []collectionRef, component string) map[string]bool {
+	paths := map[string]bool{}
+	for _, ref := range refs {
+		if ref.account != a {
+			continue
+		}
+		if collection, ok := a.matchCollection(collections, ref.name, component); ok {
+			paths[a.collectionPathOf(collection)] = true
+		}
+	}
+	return paths
+}
+
+// storeDiscovery は検出の結果を保存するます。
+func (a *account) storeDiscovery(collections []models.CalDAVCollection, err error) {
+	a.discovery.mu.Lock()
+	defer a.discovery.mu.Unlock()
+	a.discovery.collections = collections
+	a.discovery.err = err
+	a.discovery.at = time.Now()
+	if err == nil {
+		a.discovery.succeededAt = a.discovery.at
+	}
+}
+
+// discoveredSince は at（RFC3339Nano）より後にコレクションを検出し直したかを返すます。
+func (a *account) discoveredSince(at string) bool {
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return true
+	}
+	a.discovery.mu.Lock()
+	defer a.discovery.mu.Unlock()
+	return a.discovery.succeededAt.After(t)
+}
+
+// cachedCollections は検出したコレクションの一覧を返すます。
+// 期限が切れていたら問い合わせ直し、失敗した場合はしばらく問い合わせないのです。
+// 問い合わせの間はロックを持たず、同時に呼ばれたら1回だけ問い合わせて、ほかはその結果を待つのです。
+func (a *account) cachedCollections(ctx context.Context) ([]models.CalDAVCollection, error) {
+	for {
+		a.discovery.mu.Lock()
+		ttl := discoveryTTL
+		if a.discovery.err != nil {
+			ttl = discoveryRetryInterval
+		}
+		if !a.discovery.at.IsZero() && time.Since(a.discovery.at) < ttl {
+			collections, err := a.discovery.collections, a.discovery.err
+			a.discovery.mu.Unlock()
+			return collections, err
+		}
+		inflight := a.discovery.inflight
+		if inflight == nil {
+			break
+		}
+		a.discovery.mu.Unlock()
+
+		// ほかの呼び出しが検出中なので、終わるのを待ってから結果を読み直すます
+		select {
+		case <-inflight:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	a.discovery.inflight = done
+	a.discovery.mu.Unlock()
+
+	collections, err := a.discoverCollections(ctx)
+	if err != nil {
+		fmt.Printf("⚠️ CalDAV コレクションの検出失敗 (%s): %v\n", a.id, err)
+	} else {
+		fmt.Printf("🔍 CalDAV コレクションを %d 件検出しました (%s)\n", len(collections), a.id)
+	}
+	a.storeDiscovery(collections, err)
+
+	a.discovery.mu.Lock()
+	a.discovery.inflight = nil
+	a.discovery.mu.Unlock()
+	close(done)
+	return collections, err
+}
+
+// discoverCollections は current-user-principal → calendar-home-set → コレクション一覧の順に問い合わせるます。
+func (a *account) discoverCollections(ctx context.Context) ([]models.CalDAVCollection, error) {
+	var principal string
+	var err error
+	for _, root := range a.principalLookupPaths(ctx) {
+		principal, err = a.findHref(ctx, root, "current-user-principal", `<d:current-user-principal/>`, func(p davProp) []string {
+			return p.CurrentUserPrincipal.Hrefs
+		})
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	homeSet, err := a.findHref(ctx, principal, "calendar-home-set", `<cal:calendar-home-set/>`, func(p davProp) []string {
+		return p.CalendarHomeSet.Hrefs
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav" xmlns:a="http://apple.com/ns/ical/">
+	<d:prop>
+		<d:resourcetype/>
+		<d:displayname/>
+		<a:calendar-color/>
+		<cal:supported-calendar-component-set/>
+	</d:prop>
+</d:propfind>`)
+	ms, err := a.davMultiStatus(ctx, "PROPFIND", homeSet, "1", body)
+	if err != nil {
+		return nil, fmt.Errorf("コレクション一覧取得失敗: %w", err)
+	}
+
+	collections := []models.CalDAVCollection{}
+	for _, response := range ms.Responses {
+		prop := response.prop()
+		if prop.ResourceType.Calendar == nil {
+			continue
+		}
+		ref, err := a.davRef(homeSet, response.Href)
+		if err != nil {
+			continue
+		}
+		collectionURL, err := a.resolveDAVURL(ref)
+		if err != nil {
+			continue
+		}
+		u, _ := url.Parse(collectionURL)
+		color, _ := normalizeHexColor(prop.CalendarColor)
+		components := []string{}
+		for _, comp := range prop.SupportedComponents.Comps {
+			components = append(components, strings.ToUpper(comp.Name))
+		}
+		collections = append(collections, models.CalDAVCollection{
+			Name:        path.Base(strings.TrimSuffix(u.Path, "/")),
+			DisplayName: strings.TrimSpace(prop.DisplayName),
+			URL:         collectionURL,
+			Color:       color,
+			Components:  components,
+		})
+	}
+	sort.Slice(collections, func(i, j int) bool { return collections[i].URL < collections[j].URL })
+	return collections, nil
+}
+
+// principalLookupPaths は current-user-principal を問い合わせる先を、試す順に返すます。
+// Nextcloud は /remote.php/dav/、ほかのサーバーは /.well-known/caldav の転送先と設定のURLなのです。
+func (a *account) principalLookupPaths(ctx context.Context) []string {
+	if a.preset == config.CalDAVPresetNextcloud {
+		return []string{davRootPath}
+	}
+
+	paths := []string{}
+	if root, ok := a.wellKnownCalDAV(ctx); ok {
+		paths = append(paths, root)
+	}
+	configured := a.serverURL.Path
+	if configured == "" {
+		configured = "/"
+	}
+	if len(paths) == 0 || paths[0] != configured {
+		paths = append(paths, configured)
+	}
+	return paths
+}
+
+// wellKnownCalDAV は /.well-known/caldav の転送先を返すます（転送されずに応答した場合はそのままのパス）。
+// Go の HTTP クライアントは PROPFIND の転送を GET にしてしまうので、転送先は自分で読むのです。
+func (a *account) wellKnownCalDAV(ctx context.Context) (string, bool) {
+	targetURL, err := a.resolveDAVURL(wellKnownCalDAVPath)
+	if err != nil {
+		return "", false
+	}
+	req, err := http.NewRequestWithContext(ctx, "PROPFIND", targetURL, strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<d:propfind xmlns:d="DAV:">
+	<d:prop>
+		<d:current-user-principal/>
+	</d:prop>
+</d:propfind>`))
+	if err != nil {
+		return "", false
+	}
+	req.Header.Set("Depth", "0")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	noRedirect := *a.httpClient
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusMultiStatus:
+		return wellKnownCalDAVPath, true
+	case resp.StatusCode >= 300 && resp.StatusCode < 400 && resp.Header.Get("Location") != "":
+		root, err := a.davRef(targetURL, resp.Header.Get("Location"))
+		if err != nil {
+			return "", false
+		}
+		return root, true
+	default:
+		return "", false
+	}
+}
+
+// findHref は PROPFIND（Depth: 0）で href を値に持つプロパティを取得して、その参照を返すます。
+func (a *account) findHref(ctx context.Context, davPath, label, propXML string, hrefs func(davProp) []string) (string, error) {
+	body := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+	<d:prop>
+		` + propXML + `
+	</d:prop>
+</d:propfind>`)
+	ms, err := a.davMultiStatus(ctx, "PROPFIND", davPath, "0", body)
+	if err != nil {
+		return "", fmt.Errorf("%s取得失敗: %w", label, err)
+	}
+	for _, response := range ms.Responses {
+		for _, href := range hrefs(response.prop()) {
+			if ref, err := a.davRef(davPath, href); err == nil && ref != "" {
+				return ref, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s が見つかりません: %s", label, davPath)
+}
+
+// resolveCollection は設定のカレンダー名・タスクリスト名をコレクションのパスと色に解決するます。
+// 名前は URL（またはパス）・スラッグ・表示名のどれでもよいのです。
+// Nextcloud は、検出に失敗した場合や見つからない場合に従来どおりスラッグとしてパスを組み立てるます（色は空なのです）。
+func (a *account) resolveCollection(ctx context.Context, name, component string) (string, string, error) {
+	if isCollectionURL(name) {
+		collectionPath, err := a.collectionPathFor(name)
+		if err != nil {
+			return "", "", fmt.Errorf("コレクションのURLが不正です: %s", name)
+		}
+		if collections, err := a.cachedCollections(ctx); err == nil {
+			for _, collection := range collections {
+				if a.collectionPathOf(collection) == collectionPath {
+					return collectionPath, collection.Color, nil
+				}
+			}
+		}
+		return collectionPath, "", nil
+	}
+
+	collections, err := a.cachedCollections(ctx)
+	if err == nil {
+		if collection, ok := a.matchCollection(collections, name, component); ok {
+			return a.collectionPathOf(collection), collection.Color, nil
+		}
+	}
+	if a.preset == config.CalDAVPresetNextcloud {
+		if err == nil {
+			fmt.Printf("⚠️ コレクション '%s' が見つかりません（スラッグとして扱うます）\n", name)
+		}
+		return a.legacyCollectionPath(name, component), "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("コレクションの検出失敗: %w", err)
+	}
+	return "", "", fmt.Errorf("コレクション '%s' が見つかりません", name)
+}
+
+// legacyCollectionPath は名前をスラッグとして Nextcloud の標準パスを組み立てるます。
+func (a *account) legacyCollectionPath(name, component string) string {
+	if component == "VTODO" {
+		return a.getTasksPath(name)
+	}
+	return a.getCalendarPath(name)
+}
+
+// calendarPath はカレンダーのコレクションのパスを返すます。
+func (ref collectionRef) calendarPath(ctx context.Context) (string, error) {
+	collectionPath, _, err := ref.account.resolveCollection(ctx, ref.name, "VEVENT")
+	return collectionPath, err
+}
+
+// taskListPath はタスクリストのコレクションのパスを返すます。
+func (ref collectionRef) taskListPath(ctx context.Context) (string, error) {
+	collectionPath, _, err := ref.account.resolveCollection(ctx, ref.name, "VTODO")
+	return collectionPath, err
+}
+
+// matchCollection は名前に合うコレクションを探すます。
+// スラッグの一致 → 表示名の一致 → 表示名の大文字小文字を無視した一致の順で、
+// 同じ順位なら component に対応するコレクションを優先するのです。
+func (a *account) matchCollection(collections []models.CalDAVCollection, name, component string) (models.CalDAVCollection, bool) {
+	if isCollectionURL(name) {
+		target, err := a.collectionPathFor(name)
+		if err != nil {
+			return models.CalDAVCollection{}, false
+		}
+		for _, collection := range collections {
+			if a.collectionPathOf(collection) == target {
+				return collection, true
+			}
+		}
+		return models.CalDAVCollection{}, false
+	}
+
+	matchers := []func(models.CalDAVCollection) bool{
+		func(collection models.CalDAVCollection) bool { return collection.Name == name },
+		func(collection models.CalDAVCollection) bool { return collection.DisplayName == name },
+		func(collection models.CalDAVCollection) bool { return strings.EqualFold(collection.DisplayName, name) },
+	}
+	for _, matches := range matchers {
+		var fallback *models.CalDAVCollection
+		for i := range collections {
+			if !matches(collections[i]) {
+				continue
+			}
+			if supportsComponent(collections[i], component) {
+				return collections[i], true
+			}
+			if fallback == nil {
+				fallback = &collections[i]
+			}
+		}
+		if fallback != nil {
+			return *fallback, true
+		}
+	}
+	return models.CalDAVCollection{}, false
+}
+
+// supportsComponent はコレクションが component（VEVENT/VTODO）に対応するかを返すます。
+// supported-calendar-component-set が無い場合はすべてに対応するのです。
+func supportsComponent(collection models.CalDAVCollection, component string) bool {
+	if len(collection.Components) == 0 {
+		return true
+	}
+	for _, comp := range collection.Components {
+		if comp == component {
+			return true
+		}
+	}
+	return false
+}
+
+// isCollectionURL は名前が URL かパスで指定されているかを返すます。
+func isCollectionURL(name string) bool {
+	return strings.HasPrefix(name, "/") || strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// collectionPathFor は URL（またはパス）をコレクションの参照（末尾の / 付き）にするます。
+func (a *account) collectionPathFor(rawURL string) (string, error) {
+	collectionPath, err := a.davRef("", rawURL)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(collectionPath, "/") {
+		collectionPath += "/"
+	}
+	return collectionPath, nil
+}
+
+// collectionPathOf は検出したコレクションの参照を返すます。
+func (a *account) collectionPathOf(collection models.CalDAVCollection) string {
+	collectionPath, _ := a.collectionPathFor(collection.URL)
+	return collectionPath
+}
@@ -0,0 +1,92 @@
+package nextcloud
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/status"
+)
+
+// コレクションの種類（エラーのソース名の接頭辞）なのです。
+const (
+	collectionKindCalendar = "calendar"
+	collectionKindTasks    = "tasks"
+)
+
+// CollectionError はカレンダー・タスクリスト1つの取得エラーなのです。
+type CollectionError struct {
+	Kind string // "calendar" か "tasks"
+	Name string // カレンダー名・タスクリスト名
+	Err  error
+}
+
+// Error はエラーメッセージを返すます。
+func (e *CollectionError) Error() string {
+	return fmt.Sprintf("%s '%s': %v", e.Kind, e.Name, e.Err)
+}
+
+// Unwrap は元のエラーを返すます。
+func (e *CollectionError) Unwrap() error {
+	return e.Err
+}
+
+// Source は ErrorStore に記録するソース名（"calendar:family" など）を返すます。
+func (e *CollectionError) Source() string {
+	return collectionSource(e.Kind, e.Name)
+}
+
+func collectionSource(kind, name string) string {
+	return kind + ":" + name
+}
+
+// SetErrorStore はコレクションごとの取得エラーを記録する ErrorStore を設定するます。
+// 一部のコレクションだけ失敗した場合も /api/status でどれが失敗したか分かるようにするのです。
+func (c *Client) SetErrorStore(errorStore *status.ErrorStore) {
+	c.errorStore = errorStore
+}
+
+// collectionResult はコレクション1つの取得結果なのです。
+type collectionResult[T any] struct {
+	name  string
+	items []T
+	err   *CollectionError
+}
+
+// fetchCollections は names のコレクションを最大 maxConcurrency 件ずつ並行に取得するます。
+// コレクションごとに ctx から timeout のタイムアウトを付けるので、1つが遅くても他は待たされないのです。
+// 結果は取得の終わった順ではなく names の順に返すます。
+// 取得エラーはコレクションごとに ErrorStore に記録し、成功したら消すのです。
+func fetchCollections[T any](ctx context.Context, c *Client, kind string, names []string, fetch func(ctx context.Context, name string) ([]T, error)) []collectionResult[T] {
+	maxConcurrency := c.config.Nextcloud.GetMaxConcurrency()
+	timeout := c.config.Nextcloud.GetCollectionTimeout()
+
+	results := make([]collectionResult[T], len(names))
+	semaphore := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			collectionCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			startedAt := time.Now()
+			items, err := fetch(collectionCtx, name)
+			results[i] = collectionResult[T]{name: name, items: items}
+			if err != nil {
+				results[i].err = &CollectionError{Kind: kind, Name: name, Err: err}
+				fmt.Printf("❌ %s の取得エラー (%s): %v\n", results[i].err.Source(), time.Since(startedAt).Round(time.Millisecond), err)
+				c.errorStore.Set(results[i].err.Source(), results[i].err.Error())
+				return
+			}
+			c.errorStore.Clear(collectionSource(kind, name))
+		}()
+	}
+	wg.Wait()
+	return results
+}
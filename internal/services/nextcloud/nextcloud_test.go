@@ -1,7 +1,19 @@
 package nextcloud
 
 import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,6 +21,7 @@ import (
 	"github.com/rihow/FamilyDashboard/internal/cache"
 	"github.com/rihow/FamilyDashboard/internal/config"
 	"github.com/rihow/FamilyDashboard/internal/models"
+	"github.com/rihow/FamilyDashboard/internal/status"
 )
 
 // TestNewClient はクライアント初期化のテストなのです。
@@ -98,27 +111,43 @@ func TestParseDateTime(t *testing.T) {
 	tests := []struct {
 		name       string
 		input      string
+		want       string
 		wantAllDay bool
 		wantError  bool
 	}{
 		{
 			name:       "終日イベント",
 			input:      "20260228",
+			want:       "2026-02-28T00:00:00+09:00",
 			wantAllDay: true,
 			wantError:  false,
 		},
 		{
 			name:       "時間指定イベント",
 			input:      "20260228T143000",
+			want:       "2026-02-28T14:30:00+09:00",
 			wantAllDay: false,
 			wantError:  false,
 		},
 		{
 			name:       "UTC時間",
 			input:      "20260228T143000Z",
+			want:       "2026-02-28T23:30:00+09:00",
 			wantAllDay: false,
 			wantError:  false,
 		},
+		{
+			name:       "UTC時間（日付またぎ）",
+			input:      "20260228T010000Z",
+			want:       "2026-02-28T10:00:00+09:00",
+			wantAllDay: false,
+			wantError:  false,
+		},
+		{
+			name:      "不正な値",
+			input:     "2026-02-28 14:30",
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -136,6 +165,10 @@ func TestParseDateTime(t *testing.T) {
 			if !tt.wantError && isAllDay != tt.wantAllDay {
 				t.Errorf("allDay 不一致: got %v, want %v", isAllDay, tt.wantAllDay)
 			}
+
+			if !tt.wantError && result.Format(time.RFC3339) != tt.want {
+				t.Errorf("時刻不一致: got %s, want %s", result.Format(time.RFC3339), tt.want)
+			}
 		})
 	}
 }
@@ -209,7 +242,7 @@ func TestGetCalendarPath(t *testing.T) {
 	fc := cache.New("./test_cache")
 	client, _ := NewClient(fc, cfg)
 
-	path := client.getCalendarPath("family")
+	path := client.accounts[0].getCalendarPath("family")
 	expected := "/remote.php/dav/calendars/testuser/family/"
 
 	if path != expected {
@@ -231,7 +264,7 @@ func TestGetTasksPath(t *testing.T) {
 	fc := cache.New("./test_cache")
 	client, _ := NewClient(fc, cfg)
 
-	path := client.getTasksPath("tasks")
+	path := client.accounts[0].getTasksPath("tasks")
 	expected := "/remote.php/dav/calendars/testuser/tasks/"
 
 	if path != expected {
@@ -285,7 +318,7 @@ func TestMultipleCalendarPaths(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		path := client.getCalendarPath(tt.calendarName)
+		path := client.accounts[0].getCalendarPath(tt.calendarName)
 		if path != tt.expectedPath {
 			t.Errorf("カレンダーパス不一致 (%s): got %s, want %s", tt.calendarName, path, tt.expectedPath)
 		}
@@ -317,7 +350,7 @@ func TestMultipleTaskListPaths(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		path := client.getTasksPath(tt.taskListName)
+		path := client.accounts[0].getTasksPath(tt.taskListName)
 		if path != tt.expectedPath {
 			t.Errorf("タスクパス不一致 (%s): got %s, want %s", tt.taskListName, path, tt.expectedPath)
 		}
@@ -522,3 +555,1782 @@ func TestParseCalendarObjectLocationWithNewline(t *testing.T) {
 		}
 	})
 }
+
+// loadCalendarFixture は testdata 配下の ICS フィクスチャを読み込むます。
+func loadCalendarFixture(t *testing.T, name string) *ical.Calendar {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("フィクスチャ読み込み失敗: %v", err)
+	}
+	cal, err := ical.NewDecoder(strings.NewReader(string(data))).Decode()
+	if err != nil {
+		t.Fatalf("iCalendarデコード失敗: %v", err)
+	}
+	return cal
+}
+
+// TestParseCalendarObjectRecurrence は RRULE/RDATE/EXDATE/RECURRENCE-ID の展開テストなのです。
+func TestParseCalendarObjectRecurrence(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	startDate := time.Date(2026, 2, 28, 0, 0, 0, 0, loc)
+	endDate := startDate.AddDate(0, 0, 7)
+
+	type wantEvent struct {
+		id     string
+		title  string
+		start  string
+		end    string
+		allDay bool
+	}
+
+	tests := []struct {
+		name    string
+		fixture string
+		want    []wantEvent
+	}{
+		{
+			name:    "weekly rrule before window",
+			fixture: "recurring_weekly.ics",
+			want: []wantEvent{
+				{id: "school-pickup_20260302T060000Z", title: "お迎え", start: "2026-03-02T15:00:00+09:00", end: "2026-03-02T16:00:00+09:00"},
+				{id: "school-pickup_20260304T060000Z", title: "お迎え", start: "2026-03-04T15:00:00+09:00", end: "2026-03-04T16:00:00+09:00"},
+			},
+		},
+		{
+			name:    "exdate rdate and overrides",
+			fixture: "recurring_exceptions.ics",
+			want: []wantEvent{
+				{id: "piano-lesson_20260302T080000Z", title: "ピアノ教室（時間変更）", start: "2026-03-02T19:00:00+09:00", end: "2026-03-02T20:00:00+09:00"},
+				{id: "piano-lesson_20260306T080000Z", title: "ピアノ教室", start: "2026-03-06T17:00:00+09:00", end: "2026-03-06T18:00:00+09:00"},
+			},
+		},
+		{
+			name:    "monthly all-day and rdate only",
+			fixture: "recurring_monthly_allday.ics",
+			want: []wantEvent{
+				{id: "trash-day_20260301", title: "資源ごみの日", start: "2026-03-01T00:00:00+09:00", end: "2026-03-02T00:00:00+09:00", allDay: true},
+				{id: "rdate-only_20260303T010000Z", title: "検診", start: "2026-03-03T10:00:00+09:00", end: "2026-03-03T11:00:00+09:00"},
+				{id: "rdate-only_20260305T010000Z", title: "検診", start: "2026-03-05T10:00:00+09:00", end: "2026-03-05T11:00:00+09:00"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cal := loadCalendarFixture(t, tt.fixture)
+			events := parseCalendarObject(cal, startDate, endDate, "family", "#0082c9")
+
+			sort.Slice(events, func(i, j int) bool {
+				return events[i].event.Start < events[j].event.Start
+			})
+
+			if len(events) != len(tt.want) {
+				t.Fatalf("イベント数不一致: got %d, want %d (%+v)", len(events), len(tt.want), events)
+			}
+
+			for i, want := range tt.want {
+				got := events[i]
+				if got.event.ID != want.id {
+					t.Errorf("[%d] ID不一致: got %s, want %s", i, got.event.ID, want.id)
+				}
+				if got.event.Title != want.title {
+					t.Errorf("[%d] タイトル不一致: got %s, want %s", i, got.event.Title, want.title)
+				}
+				if got.event.Start != want.start {
+					t.Errorf("[%d] 開始不一致: got %s, want %s", i, got.event.Start, want.start)
+				}
+				if got.event.End != want.end {
+					t.Errorf("[%d] 終了不一致: got %s, want %s", i, got.event.End, want.end)
+				}
+				if got.allDay != want.allDay {
+					t.Errorf("[%d] allDay不一致: got %v, want %v", i, got.allDay, want.allDay)
+				}
+			}
+		})
+	}
+}
+
+// TestTZResolverParseValue は TZID/UTC/VTIMEZONE を考慮した日時解決のテストなのです。
+func TestTZResolverParseValue(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	cal := loadCalendarFixture(t, "timezones.ics")
+	tz := newTZResolver(cal, loc)
+
+	tests := []struct {
+		name  string
+		value string
+		tzid  string
+		want  string
+	}{
+		{name: "floating", value: "20260301T090000", tzid: "", want: "2026-03-01T09:00:00+09:00"},
+		{name: "utc", value: "20260301T010000Z", tzid: "", want: "2026-03-01T10:00:00+09:00"},
+		{name: "utc ignores tzid", value: "20260301T010000Z", tzid: "Europe/Berlin", want: "2026-03-01T10:00:00+09:00"},
+		{name: "iana winter", value: "20260115T100000", tzid: "Europe/Berlin", want: "2026-01-15T18:00:00+09:00"},
+		{name: "iana summer", value: "20260715T100000", tzid: "Europe/Berlin", want: "2026-07-15T17:00:00+09:00"},
+		{name: "prefixed iana", value: "20260715T100000", tzid: "/mozilla.org/20070129_1/Europe/Berlin", want: "2026-07-15T17:00:00+09:00"},
+		{name: "vtimezone winter", value: "20260115T100000", tzid: "W. Europe Standard Time", want: "2026-01-15T18:00:00+09:00"},
+		{name: "vtimezone summer", value: "20260715T100000", tzid: "W. Europe Standard Time", want: "2026-07-15T17:00:00+09:00"},
+		{name: "unknown tzid", value: "20260301T090000", tzid: "Nowhere/Unknown", want: "2026-03-01T09:00:00+09:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := tz.parseValue(tt.value, tt.tzid)
+			if got.IsZero() {
+				t.Fatalf("パース失敗しました: %s", tt.value)
+			}
+			if got.In(loc).Format(time.RFC3339) != tt.want {
+				t.Errorf("時刻不一致: got %s, want %s", got.In(loc).Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+// TestParseCalendarObjectTimezones は TZID 付きイベントと夏時間をまたぐ繰り返しのテストなのです。
+func TestParseCalendarObjectTimezones(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	startDate := time.Date(2026, 3, 23, 0, 0, 0, 0, loc)
+	endDate := startDate.AddDate(0, 0, 14)
+
+	cal := loadCalendarFixture(t, "timezones.ics")
+	events := parseCalendarObject(cal, startDate, endDate, "family", "#0082c9")
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].event.Start != events[j].event.Start {
+			return events[i].event.Start < events[j].event.Start
+		}
+		return events[i].event.ID < events[j].event.ID
+	})
+
+	// 2026-03-29 に欧州は夏時間へ移行するため、現地10:00は JST 18:00 → 17:00 になるのです
+	want := []struct {
+		id    string
+		start string
+		end   string
+	}{
+		{id: "berlin-call_20260323T090000Z", start: "2026-03-23T18:00:00+09:00", end: "2026-03-23T19:00:00+09:00"},
+		{id: "outlook-call_20260323T090000Z", start: "2026-03-23T18:00:00+09:00", end: "2026-03-23T18:30:00+09:00"},
+		{id: "utc-event", start: "2026-03-25T10:00:00+09:00", end: "2026-03-25T11:00:00+09:00"},
+		{id: "berlin-call_20260330T080000Z", start: "2026-03-30T17:00:00+09:00", end: "2026-03-30T18:00:00+09:00"},
+		{id: "outlook-call_20260330T080000Z", start: "2026-03-30T17:00:00+09:00", end: "2026-03-30T17:30:00+09:00"},
+	}
+
+	if len(events) != len(want) {
+		t.Fatalf("イベント数不一致: got %d, want %d (%+v)", len(events), len(want), events)
+	}
+
+	for i, w := range want {
+		if events[i].event.ID != w.id {
+			t.Errorf("[%d] ID不一致: got %s, want %s", i, events[i].event.ID, w.id)
+		}
+		if events[i].event.Start != w.start {
+			t.Errorf("[%d] 開始不一致: got %s, want %s", i, events[i].event.Start, w.start)
+		}
+		if events[i].event.End != w.end {
+			t.Errorf("[%d] 終了不一致: got %s, want %s", i, events[i].event.End, w.end)
+		}
+	}
+}
+
+// TestParseTaskObjectTimezones は VTODO の DUE/CREATED の UTC・TZID 解釈のテストなのです。
+func TestParseTaskObjectTimezones(t *testing.T) {
+	raw := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VTODO\nUID:task-utc\nSUMMARY:UTC期限\nDUE:20260301T160000Z\nCREATED:20260220T010000Z\nEND:VTODO\n" +
+		"BEGIN:VTODO\nUID:task-berlin\nSUMMARY:ベルリン期限\nDUE;TZID=Europe/Berlin:20260301T100000\nEND:VTODO\n" +
+		"BEGIN:VTODO\nUID:task-date\nSUMMARY:日付期限\nDUE;VALUE=DATE:20260301\nEND:VTODO\n" +
+		"END:VCALENDAR\n"
+	cal, err := ical.NewDecoder(strings.NewReader(raw)).Decode()
+	if err != nil {
+		t.Fatalf("iCalendarデコード失敗: %v", err)
+	}
+
+	tasks := parseTaskObject(cal)
+
+	tests := []struct {
+		id      string
+		wantDue string
+	}{
+		{id: "task-utc", wantDue: "2026-03-02"},
+		{id: "task-berlin", wantDue: "2026-03-01"},
+		{id: "task-date", wantDue: "2026-03-01"},
+	}
+
+	if len(tasks) != len(tests) {
+		t.Fatalf("タスク数不一致: got %d, want %d", len(tasks), len(tests))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			task := tasks[i]
+			if task.ID != tt.id {
+				t.Fatalf("ID不一致: got %s, want %s", task.ID, tt.id)
+			}
+			if task.DueDate == nil || *task.DueDate != tt.wantDue {
+				t.Errorf("期限不一致: got %v, want %s", task.DueDate, tt.wantDue)
+			}
+		})
+	}
+
+	if got := tasks[0].CreatedAt.Format(time.RFC3339); got != "2026-02-20T10:00:00+09:00" {
+		t.Errorf("作成日時不一致: got %s, want %s", got, "2026-02-20T10:00:00+09:00")
+	}
+}
+
+// TestConvertToCalendarResponseMultiDay は複数日にまたがるイベントが各日に配置されるかのテストなのです。
+func TestConvertToCalendarResponseMultiDay(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	startDate := time.Date(2026, 2, 28, 0, 0, 0, 0, loc)
+	endDate := startDate.AddDate(0, 0, 7)
+
+	cal := loadCalendarFixture(t, "multi_day.ics")
+	events := parseCalendarObject(cal, startDate, endDate, "family", "#0082c9")
+	resp := convertToCalendarResponse(events, startDate, endDate)
+
+	type placement struct {
+		id       string
+		fromPrev bool
+		toNext   bool
+	}
+
+	tests := []struct {
+		date   string
+		allDay []placement
+		timed  []placement
+	}{
+		{date: "2026-02-28", allDay: []placement{{id: "ongoing-visit", fromPrev: true, toNext: false}}},
+		{date: "2026-03-01", allDay: []placement{{id: "family-trip", fromPrev: false, toNext: true}}},
+		{date: "2026-03-02", allDay: []placement{{id: "family-trip", fromPrev: true, toNext: true}}, timed: []placement{{id: "ends-at-midnight"}}},
+		{date: "2026-03-03", allDay: []placement{{id: "family-trip", fromPrev: true, toNext: false}}},
+		{date: "2026-03-04"},
+		{date: "2026-03-05", timed: []placement{{id: "night-shift", fromPrev: false, toNext: true}}},
+		{date: "2026-03-06", timed: []placement{{id: "night-shift", fromPrev: true, toNext: false}}},
+	}
+
+	if len(resp.Days) != len(tests) {
+		t.Fatalf("日数不一致: got %d, want %d", len(resp.Days), len(tests))
+	}
+
+	check := func(t *testing.T, kind string, got []models.Event, want []placement) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s 件数不一致: got %d, want %d (%+v)", kind, len(got), len(want), got)
+		}
+		for i, w := range want {
+			if got[i].ID != w.id {
+				t.Errorf("%s[%d] ID不一致: got %s, want %s", kind, i, got[i].ID, w.id)
+			}
+			if got[i].ContinuesFromPrevDay != w.fromPrev {
+				t.Errorf("%s[%d] continuesFromPrevDay不一致: got %v, want %v", kind, i, got[i].ContinuesFromPrevDay, w.fromPrev)
+			}
+			if got[i].ContinuesToNextDay != w.toNext {
+				t.Errorf("%s[%d] continuesToNextDay不一致: got %v, want %v", kind, i, got[i].ContinuesToNextDay, w.toNext)
+			}
+		}
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			day := resp.Days[i]
+			if day.Date != tt.date {
+				t.Fatalf("日付不一致: got %s, want %s", day.Date, tt.date)
+			}
+			check(t, "allDay", day.AllDay, tt.allDay)
+			check(t, "timed", day.Timed, tt.timed)
+		})
+	}
+}
+
+// fakeCalDAVServer はテスト用の最小限の CalDAV サーバーなのです。
+// REPORT（calendar-query・calendar-multiget・sync-collection）・PROPFIND（getctag・getetag）・PUT・DELETE に対応し、
+// If-Match / If-None-Match を検証するます。collections を設定すると、コレクションの検出（/.well-known/caldav の転送も）にも応答するのです。
+type fakeCalDAVServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	objects map[string]fakeCalDAVObject
+	seq     int
+	changes []fakeCalDAVChange // 変更の記録（sync-token はこの seq なのです）
+
+	syncDisabled  bool     // true なら sync-collection に対応しないサーバーとしてふるまうます
+	weakETags     bool     // true なら ETag を弱い ETag（W/"..."）で返すのです
+	minSyncToken  int      // これより古い sync-token は無効として扱うます
+	multigetHrefs []string // calendar-multiget で取得されたパス
+	requests      map[string]int
+
+	delay       time.Duration   // すべてのリクエストの応答を遅らせる時間
+	hang        map[string]bool // このコレクションへのリクエストはクライアントが切断するまで応答しないのです
+	fail        map[string]bool // このコレクションへのリクエストは 500 を返すのです
+	inFlight    int
+	maxInFlight int // 同時に処理していたリクエスト数の最大
+
+	collections []fakeCalDAVCollection // calendar-home-set のコレクション（空なら検出に対応しないのです）
+}
+
+type fakeCalDAVCollection struct {
+	path        string
+	displayName string
+	color       string
+	components  []string
+}
+
+// fake サーバーの principal と calendar-home-set なのです。
+const (
+	fakePrincipalPath = "/remote.php/dav/principals/users/testuser/"
+	fakeHomeSetPath   = "/remote.php/dav/calendars/testuser/"
+)
+
+type fakeCalDAVObject struct {
+	etag string
+	data string
+}
+
+type fakeCalDAVChange struct {
+	seq  int
+	path string
+}
+
+func newFakeCalDAVServer(t *testing.T) *fakeCalDAVServer {
+	t.Helper()
+	f := &fakeCalDAVServer{objects: map[string]fakeCalDAVObject{}, requests: map[string]int{}, hang: map[string]bool{}, fail: map[string]bool{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// put はオブジェクトを直接登録して ETag を返すます。
+func (f *fakeCalDAVServer) put(path, data string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	etag := fmt.Sprintf("etag-%d", f.seq)
+	f.objects[path] = fakeCalDAVObject{etag: etag, data: data}
+	f.changes = append(f.changes, fakeCalDAVChange{seq: f.seq, path: path})
+	return etag
+}
+
+// remove はオブジェクトを直接削除するます。
+func (f *fakeCalDAVServer) remove(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeLocked(path)
+}
+
+func (f *fakeCalDAVServer) removeLocked(path string) {
+	f.seq++
+	delete(f.objects, path)
+	f.changes = append(f.changes, fakeCalDAVChange{seq: f.seq, path: path})
+}
+
+func (f *fakeCalDAVServer) get(path string) (fakeCalDAVObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.objects[path]
+	return object, ok
+}
+
+// resetCounts はリクエストの記録を消すます。
+func (f *fakeCalDAVServer) resetCounts() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.multigetHrefs = nil
+	f.requests = map[string]int{}
+}
+
+// counts は calendar-multiget で取得されたパスと、種類ごとのリクエスト数を返すます。
+func (f *fakeCalDAVServer) counts() ([]string, map[string]int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	hrefs := append([]string{}, f.multigetHrefs...)
+	sort.Strings(hrefs)
+	requests := map[string]int{}
+	for kind, n := range f.requests {
+		requests[kind] = n
+	}
+	return hrefs, requests
+}
+
+func (f *fakeCalDAVServer) handle(w http.ResponseWriter, r *http.Request) {
+	collectionPath := r.URL.Path[:strings.LastIndex(r.URL.Path, "/")+1]
+	f.mu.Lock()
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	delay, hang, fail := f.delay, f.hang[collectionPath], f.fail[collectionPath]
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+
+	switch {
+	case hang:
+		// 本文を読み切らないと切断が r.Context() に伝わらないのです
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		return
+	case fail:
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	time.Sleep(delay)
+
+	if r.URL.Path == "/.well-known/caldav" && len(f.collections) > 0 {
+		w.Header().Set("Location", "/remote.php/dav/")
+		w.WriteHeader(http.StatusMovedPermanently)
+		return
+	}
+
+	switch r.Method {
+	case "REPORT":
+		f.handleReport(w, r)
+	case "PROPFIND":
+		f.handlePropfind(w, r)
+	case http.MethodPut:
+		f.handlePut(w, r)
+	case http.MethodDelete:
+		f.handleDelete(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeCalDAVServer) handleReport(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var report struct {
+		XMLName   xml.Name
+		SyncToken string   `xml:"DAV: sync-token"`
+		Hrefs     []string `xml:"DAV: href"`
+	}
+	if err := xml.Unmarshal(body, &report); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[report.XMLName.Local]++
+
+	switch report.XMLName.Local {
+	case "sync-collection":
+		f.handleSyncCollection(w, r.URL.Path, report.SyncToken)
+	case "calendar-multiget":
+		paths := []string{}
+		for _, href := range report.Hrefs {
+			path, _ := url.PathUnescape(href)
+			paths = append(paths, path)
+			f.multigetHrefs = append(f.multigetHrefs, path)
+		}
+		f.writeMultiStatus(w, paths, true, "")
+	default:
+		f.writeMultiStatus(w, f.pathsUnder(r.URL.Path), true, "")
+	}
+}
+
+// handleSyncCollection は sync-token より後に変わったオブジェクトを返すます（削除は 404）。
+func (f *fakeCalDAVServer) handleSyncCollection(w http.ResponseWriter, collectionPath, syncToken string) {
+	if f.syncDisabled {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><d:error xmlns:d="DAV:"><d:supported-report/></d:error>`)
+		return
+	}
+
+	since := 0
+	if syncToken != "" {
+		n, err := fmt.Sscanf(syncToken, "http://fake/sync/%d", &since)
+		if err != nil || n != 1 || since < f.minSyncToken {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><d:error xmlns:d="DAV:"><d:valid-sync-token/></d:error>`)
+			return
+		}
+	}
+
+	paths := []string{}
+	if syncToken == "" {
+		paths = f.pathsUnder(collectionPath)
+	} else {
+		seen := map[string]bool{}
+		for _, change := range f.changes {
+			if change.seq > since && strings.HasPrefix(change.path, collectionPath) && !seen[change.path] {
+				seen[change.path] = true
+				paths = append(paths, change.path)
+			}
+		}
+		sort.Strings(paths)
+	}
+	f.writeMultiStatus(w, paths, false, fmt.Sprintf("http://fake/sync/%d", f.seq))
+}
+
+// handlePropfind は Depth: 0 ならコレクションの getctag、Depth: 1 ならオブジェクトの getetag も返すます。
+func (f *fakeCalDAVServer) handlePropfind(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests["propfind-depth-"+r.Header.Get("Depth")]++
+
+	if len(f.collections) > 0 {
+		if body, ok := f.discoveryResponse(r.URL.Path, r.Header.Get("Depth")); ok {
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			w.WriteHeader(http.StatusMultiStatus)
+			io.WriteString(w, body)
+			return
+		}
+	}
+
+	ctag := 0
+	for _, change := range f.changes {
+		if strings.HasPrefix(change.path, r.URL.Path) {
+			ctag = change.seq
+		}
+	}
+
+	var buf strings.Builder
+	buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	buf.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/">`)
+	buf.WriteString(`<d:response><d:href>` + r.URL.Path + `</d:href><d:propstat><d:prop>`)
+	buf.WriteString(fmt.Sprintf(`<cs:getctag>ctag-%d</cs:getctag>`, ctag))
+	buf.WriteString(`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+	if r.Header.Get("Depth") == "1" {
+		for _, path := range f.pathsUnder(r.URL.Path) {
+			buf.WriteString(`<d:response><d:href>` + path + `</d:href><d:propstat><d:prop>`)
+			buf.WriteString(`<d:getetag>` + f.quoteETag(f.objects[path].etag) + `</d:getetag>`)
+			buf.WriteString(`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+		}
+	}
+	buf.WriteString(`</d:multistatus>`)
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, buf.String())
+}
+
+// discoveryResponse は DAV のルート・principal・calendar-home-set への PROPFIND の応答を返すます。
+func (f *fakeCalDAVServer) discoveryResponse(path, depth string) (string, bool) {
+	var buf strings.Builder
+	buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	buf.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav" xmlns:a="http://apple.com/ns/ical/">`)
+	switch {
+	case path == "/remote.php/dav/":
+		buf.WriteString(`<d:response><d:href>` + path + `</d:href><d:propstat><d:prop>`)
+		buf.WriteString(`<d:current-user-principal><d:href>` + fakePrincipalPath + `</d:href></d:current-user-principal>`)
+		buf.WriteString(`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+	case path == fakePrincipalPath:
+		buf.WriteString(`<d:response><d:href>` + path + `</d:href><d:propstat><d:prop>`)
+		buf.WriteString(`<cal:calendar-home-set><d:href>` + fakeHomeSetPath + `</d:href></cal:calendar-home-set>`)
+		buf.WriteString(`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+	case path == fakeHomeSetPath && depth == "1":
+		buf.WriteString(`<d:response><d:href>` + path + `</d:href><d:propstat><d:prop>`)
+		buf.WriteString(`<d:resourcetype><d:collection/></d:resourcetype>`)
+		buf.WriteString(`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+		for _, collection := range f.collections {
+			buf.WriteString(`<d:response><d:href>` + collection.path + `</d:href><d:propstat><d:prop>`)
+			buf.WriteString(`<d:resourcetype><d:collection/><cal:calendar/></d:resourcetype>`)
+			buf.WriteString(`<d:displayname>` + collection.displayName + `</d:displayname>`)
+			buf.WriteString(`<a:calendar-color>` + collection.color + `</a:calendar-color>`)
+			buf.WriteString(`<cal:supported-calendar-component-set>`)
+			for _, component := range collection.components {
+				buf.WriteString(`<cal:comp name="` + component + `"/>`)
+			}
+			buf.WriteString(`</cal:supported-calendar-component-set>`)
+			buf.WriteString(`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+		}
+	default:
+		return "", false
+	}
+	buf.WriteString(`</d:multistatus>`)
+	return buf.String(), true
+}
+
+// pathsUnder はコレクション内のオブジェクトのパスを返すます（パス順）。
+func (f *fakeCalDAVServer) pathsUnder(collectionPath string) []string {
+	paths := make([]string, 0, len(f.objects))
+	for path := range f.objects {
+		if strings.HasPrefix(path, collectionPath) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// writeMultiStatus はオブジェクトの multistatus を書くます。
+// 存在しないパスは 404、withData なら calendar-data も含め、syncToken があれば sync-token を付けるのです。
+func (f *fakeCalDAVServer) writeMultiStatus(w http.ResponseWriter, paths []string, withData bool, syncToken string) {
+	var buf strings.Builder
+	buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	buf.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">`)
+	for _, path := range paths {
+		object, ok := f.objects[path]
+		if !ok {
+			buf.WriteString(`<d:response><d:href>` + path + `</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`)
+			continue
+		}
+		buf.WriteString(`<d:response><d:href>` + path + `</d:href><d:propstat><d:prop>`)
+		buf.WriteString(`<d:getetag>` + f.quoteETag(object.etag) + `</d:getetag>`)
+		if withData {
+			buf.WriteString(`<cal:calendar-data>`)
+			xml.EscapeText(&buf, []byte(object.data))
+			buf.WriteString(`</cal:calendar-data>`)
+		}
+		buf.WriteString(`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+	}
+	if syncToken != "" {
+		buf.WriteString(`<d:sync-token>` + syncToken + `</d:sync-token>`)
+	}
+	buf.WriteString(`</d:multistatus>`)
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, buf.String())
+}
+
+// checkPrecondition は If-Match / If-None-Match を検証するます。
+func (f *fakeCalDAVServer) checkPrecondition(r *http.Request, object fakeCalDAVObject, exists bool) bool {
+	if r.Header.Get("If-None-Match") == "*" && exists {
+		return false
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		return exists && ifMatch == f.quoteETag(object.etag)
+	}
+	return true
+}
+
+// quoteETag はレスポンスに書く ETag（"etag-1" か W/"etag-1"）を返すます。
+func (f *fakeCalDAVServer) quoteETag(etag string) string {
+	if f.weakETags {
+		return `W/"` + etag + `"`
+	}
+	return `"` + etag + `"`
+}
+
+func (f *fakeCalDAVServer) handlePut(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	f.mu.Lock()
+	object, exists := f.objects[r.URL.Path]
+	f.mu.Unlock()
+	if !f.checkPrecondition(r, object, exists) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	etag := f.put(r.URL.Path, string(body))
+	w.Header().Set("ETag", f.quoteETag(etag))
+	if exists {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *fakeCalDAVServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	object, exists := f.objects[r.URL.Path]
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !f.checkPrecondition(r, object, exists) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	f.removeLocked(r.URL.Path)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newFakeClient は fakeCalDAVServer に接続するクライアントを作るます。
+func newFakeClient(t *testing.T, server *fakeCalDAVServer) (*Client, *cache.FileCache) {
+	t.Helper()
+	return newFakeClientWithConfig(t, server, config.Nextcloud{
+		CalendarNames: []string{"family"},
+		TaskListNames: []string{"tasks", "shopping"},
+	})
+}
+
+// newFakeClientWithConfig は Nextcloud の設定（接続先以外）を指定して fakeCalDAVServer に接続するクライアントを作るます。
+func newFakeClientWithConfig(t *testing.T, server *fakeCalDAVServer, nc config.Nextcloud) (*Client, *cache.FileCache) {
+	t.Helper()
+	nc.ServerURL = server.URL
+	nc.Username = "testuser"
+	nc.Password = "testpass"
+	cfg := &config.Config{Nextcloud: nc}
+	fc := cache.New(t.TempDir())
+	client, err := NewClient(fc, cfg)
+	if err != nil {
+		t.Fatalf("NewClient エラー: %v", err)
+	}
+	return client, fc
+}
+
+func TestCreateUpdateDeleteTask(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	client, fc := newFakeClient(t, server)
+	ctx := context.Background()
+
+	seedTasksCache := func() {
+		t.Helper()
+		if _, err := fc.Write(TasksCacheKey, &models.TasksResponse{}, nil); err != nil {
+			t.Fatalf("seed cache: %v", err)
+		}
+	}
+	assertCacheInvalidated := func() {
+		t.Helper()
+		if _, ok, _, _ := fc.Read(TasksCacheKey, 0); ok {
+			t.Fatalf("タスクキャッシュが破棄されていません")
+		}
+	}
+
+	// 作成
+	seedTasksCache()
+	title := "牛乳を買う"
+	due := "2026-03-10"
+	priority := 3
+	created, err := client.CreateTask(ctx, models.TaskWriteRequest{
+		Title:    &title,
+		DueDate:  &due,
+		Priority: &priority,
+		TaskList: "shopping",
+	})
+	if err != nil {
+		t.Fatalf("CreateTask エラー: %v", err)
+	}
+	assertCacheInvalidated()
+
+	if created.Title != title || created.Status != "needsAction" || created.Priority != 3 {
+		t.Fatalf("作成結果が不正: %+v", created)
+	}
+	if created.DueDate == nil || *created.DueDate != due {
+		t.Fatalf("dueDate = %v, want %s", created.DueDate, due)
+	}
+
+	objectPath := "/remote.php/dav/calendars/testuser/shopping/" + created.ID + ".ics"
+	stored, ok := server.get(objectPath)
+	if !ok {
+		t.Fatalf("オブジェクトが保存されていません: %s", objectPath)
+	}
+	if !strings.Contains(stored.data, "SUMMARY:"+title) || !strings.Contains(stored.data, "DUE;VALUE=DATE:20260310") || !strings.Contains(stored.data, "PRIORITY:1") {
+		t.Fatalf("保存内容が不正:\n%s", stored.data)
+	}
+
+	// 更新（完了にする）
+	seedTasksCache()
+	completed := "completed"
+	updated, err := client.UpdateTask(ctx, created.ID, models.TaskWriteRequest{Status: &completed})
+	if err != nil {
+		t.Fatalf("UpdateTask エラー: %v", err)
+	}
+	assertCacheInvalidated()
+
+	if updated.Status != "completed" || updated.Title != title {
+		t.Fatalf("更新結果が不正: %+v", updated)
+	}
+	if latest, _ := server.get(objectPath); !strings.Contains(latest.data, "STATUS:COMPLETED") || !strings.Contains(latest.data, "COMPLETED:") {
+		t.Fatalf("完了状態が保存されていません:\n%s", latest.data)
+	}
+
+	// 古い ETag での書き込みは競合になる
+	stale, err := client.accounts[0].findObjectByUID(ctx, client.accounts[0].getTasksPath("shopping"), "VTODO", created.ID)
+	if err != nil {
+		t.Fatalf("findObjectByUID エラー: %v", err)
+	}
+	server.put(objectPath, stored.data)
+	if err := client.accounts[0].putCalendarObject(ctx, stale.Path, stale.Data, stale.ETag); !errors.Is(err, ErrConflict) {
+		t.Fatalf("err = %v, want ErrConflict", err)
+	}
+
+	// 削除
+	seedTasksCache()
+	if err := client.DeleteTask(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteTask エラー: %v", err)
+	}
+	assertCacheInvalidated()
+	if _, ok := server.get(objectPath); ok {
+		t.Fatalf("オブジェクトが削除されていません")
+	}
+
+	if err := client.DeleteTask(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTaskWriteValidation(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	client, _ := newFakeClient(t, server)
+	ctx := context.Background()
+
+	empty := ""
+	title := "タスク"
+	badStatus := "done"
+	badDue := "2026/03/10"
+	badPriority := 5
+
+	tests := []struct {
+		name string
+		req  models.TaskWriteRequest
+	}{
+		{name: "title missing", req: models.TaskWriteRequest{}},
+		{name: "title empty", req: models.TaskWriteRequest{Title: &empty}},
+		{name: "unknown status", req: models.TaskWriteRequest{Title: &title, Status: &badStatus}},
+		{name: "invalid due", req: models.TaskWriteRequest{Title: &title, DueDate: &badDue}},
+		{name: "invalid priority", req: models.TaskWriteRequest{Title: &title, Priority: &badPriority}},
+		{name: "unknown task list", req: models.TaskWriteRequest{Title: &title, TaskList: "unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.CreateTask(ctx, tt.req); !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("err = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestCreateUpdateDeleteEvent(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	client, fc := newFakeClient(t, server)
+	ctx := context.Background()
+
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	rangeStart := time.Date(2026, 3, 1, 0, 0, 0, 0, loc)
+	seedCalendarCache := func() {
+		t.Helper()
+		for _, days := range []int{3, 7} {
+			if _, err := fc.Write(CalendarCacheKey(rangeStart, days), &models.CalendarResponse{}, nil); err != nil {
+				t.Fatalf("seed cache: %v", err)
+			}
+		}
+	}
+	assertCacheInvalidated := func() {
+		t.Helper()
+		for _, days := range []int{3, 7} {
+			if _, ok, _, _ := fc.Read(CalendarCacheKey(rangeStart, days), 0); ok {
+				t.Fatalf("カレンダーキャッシュ（%d日）が破棄されていません", days)
+			}
+		}
+	}
+
+	// 作成
+	seedCalendarCache()
+	title := "歯医者"
+	start := "2026-03-03T10:00:00+09:00"
+	end := "2026-03-03T10:30:00+09:00"
+	location := "駅前クリニック"
+	color := "#FF8800"
+	created, err := client.CreateEvent(ctx, models.EventWriteRequest{
+		Title:    &title,
+		Start:    &start,
+		End:      &end,
+		Location: &location,
+		Color:    &color,
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent エラー: %v", err)
+	}
+	assertCacheInvalidated()
+
+	if created.Title != title || created.Calendar != "family" || created.Location != location || created.Color != "#FF8800" {
+		t.Fatalf("作成結果が不正: %+v", created)
+	}
+	if created.Start != start || created.End != end {
+		t.Fatalf("start/end = %s/%s, want %s/%s", created.Start, created.End, start, end)
+	}
+
+	objectPath := "/remote.php/dav/calendars/testuser/family/" + created.ID + ".ics"
+	stored, ok := server.get(objectPath)
+	if !ok {
+		t.Fatalf("オブジェクトが保存されていません: %s", objectPath)
+	}
+	for _, want := range []string{"UID:" + created.ID, "DTSTAMP:", "DTSTART:20260303T010000Z", "DTEND:20260303T013000Z", "LOCATION:" + location, "COLOR:#FF8800"} {
+		if !strings.Contains(stored.data, want) {
+			t.Fatalf("保存内容に %q がありません:\n%s", want, stored.data)
+		}
+	}
+
+	// 開始だけ変更すると長さを保って移動する
+	seedCalendarCache()
+	newTitle := "歯医者（再診）"
+	newStart := "2026-03-05T15:00:00+09:00"
+	updated, err := client.UpdateEvent(ctx, created.ID, models.EventWriteRequest{Title: &newTitle, Start: &newStart})
+	if err != nil {
+		t.Fatalf("UpdateEvent エラー: %v", err)
+	}
+	assertCacheInvalidated()
+
+	if updated.Title != newTitle || updated.Start != newStart || updated.End != "2026-03-05T15:30:00+09:00" {
+		t.Fatalf("更新結果が不正: %+v", updated)
+	}
+	if updated.Location != location {
+		t.Fatalf("location が失われました: %+v", updated)
+	}
+
+	// 終日に変更
+	allDay := "2026-03-06"
+	updated, err = client.UpdateEvent(ctx, created.ID, models.EventWriteRequest{Start: &allDay})
+	if err != nil {
+		t.Fatalf("UpdateEvent（終日）エラー: %v", err)
+	}
+	if updated.Start != "2026-03-06T00:00:00+09:00" || updated.End != "2026-03-07T00:00:00+09:00" {
+		t.Fatalf("終日の start/end が不正: %+v", updated)
+	}
+	if latest, _ := server.get(objectPath); !strings.Contains(latest.data, "DTSTART;VALUE=DATE:20260306") {
+		t.Fatalf("終日の DTSTART が不正:\n%s", latest.data)
+	}
+
+	// 削除
+	seedCalendarCache()
+	if err := client.DeleteEvent(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteEvent エラー: %v", err)
+	}
+	assertCacheInvalidated()
+	if _, ok := server.get(objectPath); ok {
+		t.Fatalf("オブジェクトが削除されていません")
+	}
+	if err := client.DeleteEvent(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdateRecurringEventOccurrence(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	client, _ := newFakeClient(t, server)
+	ctx := context.Background()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "recurring_weekly.ics"))
+	if err != nil {
+		t.Fatalf("fixture 読み込み失敗: %v", err)
+	}
+	server.put("/remote.php/dav/calendars/testuser/family/weekly.ics", strings.ReplaceAll(string(data), "\n", "\r\n"))
+
+	uid := "school-pickup"
+
+	title := "変更"
+	if _, err := client.UpdateEvent(ctx, uid+"_20260302T000000Z", models.EventWriteRequest{Title: &title}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("発生ID: err = %v, want ErrInvalidInput", err)
+	}
+
+	start := "2026-03-10T09:00:00+09:00"
+	if _, err := client.UpdateEvent(ctx, uid, models.EventWriteRequest{Start: &start}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("繰り返しの日時変更: err = %v, want ErrInvalidInput", err)
+	}
+
+	if _, err := client.UpdateEvent(ctx, uid, models.EventWriteRequest{Title: &title}); err != nil {
+		t.Fatalf("繰り返しのタイトル変更エラー: %v", err)
+	}
+
+	// _ を多く含む見つからないIDでも、UID の検索はカレンダーごとに1回だけなのです
+	server.resetCounts()
+	if err := client.DeleteEvent(ctx, "a_b_c_d_e_f"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if _, requests := server.counts(); requests["calendar-query"] != len(client.calendars) {
+		t.Fatalf("calendar-query = %d, want %d", requests["calendar-query"], len(client.calendars))
+	}
+
+	// 発生ごとのIDは最後の _ で一度だけ分けて、元の UID を1回探すます
+	server.resetCounts()
+	if err := client.DeleteEvent(ctx, "missing_uid_20260302T000000Z"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if _, requests := server.counts(); requests["calendar-query"] != 2*len(client.calendars) {
+		t.Fatalf("calendar-query = %d, want %d", requests["calendar-query"], 2*len(client.calendars))
+	}
+}
+
+// fakeEventData はテスト用の VEVENT を1件含む iCalendar を返すます。
+func fakeEventData(uid, summary, dtStart string) string {
+	return strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Test//Test//JA",
+		"BEGIN:VEVENT",
+		"UID:" + uid,
+		"DTSTAMP:20260301T000000Z",
+		"DTSTART:" + dtStart,
+		"SUMMARY:" + summary,
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+}
+
+// eventTitles はカレンダーレスポンスのイベント名を並べて返すます。
+func eventTitles(resp *models.CalendarResponse) []string {
+	titles := []string{}
+	for _, day := range resp.Days {
+		for _, event := range day.AllDay {
+			titles = append(titles, event.Title)
+		}
+		for _, event := range day.Timed {
+			titles = append(titles, event.Title)
+		}
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+func TestRefreshCalendarEventsSyncCollection(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	client, _ := newFakeClient(t, server)
+	ctx := context.Background()
+
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	rangeStart := time.Date(2026, 3, 1, 0, 0, 0, 0, loc)
+	dentist := "/remote.php/dav/calendars/testuser/family/dentist.ics"
+	school := "/remote.php/dav/calendars/testuser/family/school.ics"
+	server.put(dentist, fakeEventData("dentist", "歯医者", "20260302T010000Z"))
+	server.put(school, fakeEventData("school", "参観日", "20260303T000000Z"))
+
+	refresh := func(want ...string) {
+		t.Helper()
+		resp, err := client.RefreshCalendarEvents(ctx, rangeStart, 7)
+		if err != nil {
+			t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+		}
+		sort.Strings(want)
+		if got := eventTitles(resp); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("イベント = %v, want %v", got, want)
+		}
+	}
+
+	// 初回は全件を取得する
+	refresh("歯医者", "参観日")
+	hrefs, requests := server.counts()
+	if len(hrefs) != 2 || requests["sync-collection"] != 1 || requests["calendar-query"] != 0 {
+		t.Fatalf("初回: multiget=%v requests=%v", hrefs, requests)
+	}
+
+	// 変更が無ければ本文を取得しない
+	server.resetCounts()
+	refresh("歯医者", "参観日")
+	if hrefs, requests := server.counts(); len(hrefs) != 0 || requests["sync-collection"] != 1 {
+		t.Fatalf("変更なし: multiget=%v requests=%v", hrefs, requests)
+	}
+
+	// 変わったオブジェクトだけを取得する
+	server.resetCounts()
+	server.put(dentist, fakeEventData("dentist", "歯医者（再診）", "20260302T010000Z"))
+	refresh("歯医者（再診）", "参観日")
+	if hrefs, _ := server.counts(); strings.Join(hrefs, ",") != dentist {
+		t.Fatalf("変更: multiget=%v, want [%s]", hrefs, dentist)
+	}
+
+	// 削除されたオブジェクトはストアからも消える
+	server.resetCounts()
+	server.remove(school)
+	refresh("歯医者（再診）")
+	if hrefs, _ := server.counts(); len(hrefs) != 0 {
+		t.Fatalf("削除: multiget=%v", hrefs)
+	}
+
+	// sync-token が無効になったら最初から同期し直す
+	server.resetCounts()
+	server.mu.Lock()
+	server.minSyncToken = server.seq + 1
+	server.mu.Unlock()
+	refresh("歯医者（再診）")
+	if _, requests := server.counts(); requests["sync-collection"] != 2 {
+		t.Fatalf("無効な sync-token: requests=%v", requests)
+	}
+}
+
+func TestRefreshTaskItemsETagFallback(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	server.syncDisabled = true
+	client, _ := newFakeClient(t, server)
+	ctx := context.Background()
+
+	milk := "/remote.php/dav/calendars/testuser/shopping/milk.ics"
+	eggs := "/remote.php/dav/calendars/testuser/shopping/eggs.ics"
+	todo := func(uid, summary string) string {
+		return strings.Join([]string{
+			"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Test//Test//JA",
+			"BEGIN:VTODO", "UID:" + uid, "SUMMARY:" + summary, "STATUS:NEEDS-ACTION", "END:VTODO",
+			"END:VCALENDAR", "",
+		}, "\r\n")
+	}
+	server.put(milk, todo("milk", "牛乳"))
+	server.put(eggs, todo("eggs", "卵"))
+
+	refresh := func(want ...string) {
+		t.Helper()
+		resp, err := client.RefreshTaskItems(ctx)
+		if err != nil {
+			t.Fatalf("RefreshTaskItems エラー: %v", err)
+		}
+		got := []string{}
+		for _, item := range resp.Items {
+			got = append(got, item.Title)
+		}
+		sort.Strings(got)
+		sort.Strings(want)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("タスク = %v, want %v", got, want)
+		}
+	}
+
+	refresh("牛乳", "卵")
+	if hrefs, requests := server.counts(); len(hrefs) != 2 || requests["propfind-depth-1"] != 2 || requests["sync-collection"] != 2 {
+		t.Fatalf("初回: multiget=%v requests=%v", hrefs, requests)
+	}
+
+	// ctag が変わらなければ ETag 一覧も取得せず、断られた sync-collection も送り直さない
+	server.resetCounts()
+	refresh("牛乳", "卵")
+	if hrefs, requests := server.counts(); len(hrefs) != 0 || requests["propfind-depth-0"] != 2 || requests["propfind-depth-1"] != 0 || requests["sync-collection"] != 0 {
+		t.Fatalf("変更なし: multiget=%v requests=%v", hrefs, requests)
+	}
+	if state, _ := client.store.load("", "/remote.php/dav/calendars/testuser/shopping/"); state.SyncUnsupported == "" {
+		t.Fatalf("sync-collection に対応していないことが保存されていません: %+v", state)
+	}
+
+	// ctag が変わったコレクションだけ ETag を比べて、変わったオブジェクトだけを取得する
+	server.resetCounts()
+	server.put(milk, todo("milk", "低脂肪乳"))
+	server.remove(eggs)
+	refresh("低脂肪乳")
+	if hrefs, requests := server.counts(); strings.Join(hrefs, ",") != milk || requests["propfind-depth-1"] != 1 {
+		t.Fatalf("変更: multiget=%v requests=%v", hrefs, requests)
+	}
+
+	// コレクションを検出し直したら、もう一度 sync-collection を試す
+	client.accounts[0].storeDiscovery(nil, nil)
+	server.resetCounts()
+	refresh("低脂肪乳")
+	if _, requests := server.counts(); requests["sync-collection"] != 2 {
+		t.Fatalf("検出し直したあと: requests=%v", requests)
+	}
+}
+
+func TestObjectStorePersistsAcrossRestart(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	rangeStart := time.Date(2026, 3, 1, 0, 0, 0, 0, loc)
+	server.put("/remote.php/dav/calendars/testuser/family/dentist.ics", fakeEventData("dentist", "歯医者", "20260302T010000Z"))
+
+	first, _ := newFakeClient(t, server)
+	first.SetObjectStore(NewObjectStore(dir))
+	if _, err := first.RefreshCalendarEvents(ctx, rangeStart, 7); err != nil {
+		t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("保存されたファイル = %v, want 1件", files)
+	}
+
+	// 再起動後のクライアントは保存済みの sync-token から差分だけを取得する
+	server.resetCounts()
+	second, _ := newFakeClient(t, server)
+	second.SetObjectStore(NewObjectStore(dir))
+	resp, err := second.RefreshCalendarEvents(ctx, rangeStart, 7)
+	if err != nil {
+		t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+	}
+	if got := eventTitles(resp); strings.Join(got, ",") != "歯医者" {
+		t.Fatalf("イベント = %v", got)
+	}
+	if hrefs, _ := server.counts(); len(hrefs) != 0 {
+		t.Fatalf("再起動後に本文を取得し直しました: %v", hrefs)
+	}
+}
+
+func TestFetchCollectionsKeepsOrder(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	client, _ := newFakeClientWithConfig(t, server, config.Nextcloud{MaxConcurrency: 2})
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	names := []string{"a", "b", "c", "d", "e"}
+	results := fetchCollections(context.Background(), client, collectionKindCalendar, names, func(ctx context.Context, name string) ([]string, error) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		// 後ろのコレクションほど早く終わるようにするのです
+		time.Sleep(time.Duration(len(names)-strings.Index("abcde", name)) * 5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if name == "c" {
+			return nil, errors.New("接続失敗")
+		}
+		return []string{name}, nil
+	})
+
+	if maxRunning != 2 {
+		t.Errorf("同時実行数 = %d, want 2", maxRunning)
+	}
+	got := []string{}
+	for _, result := range results {
+		if result.err != nil {
+			got = append(got, "!"+result.err.Name)
+			continue
+		}
+		got = append(got, result.items...)
+	}
+	if strings.Join(got, ",") != "a,b,!c,d,e" {
+		t.Fatalf("結果の順番 = %v", got)
+	}
+}
+
+func TestRefreshCalendarEventsConcurrentPartialFailure(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	server.delay = 20 * time.Millisecond
+	client, _ := newFakeClientWithConfig(t, server, config.Nextcloud{
+		CalendarNames:        []string{"family", "work", "school", "slow", "broken"},
+		MaxConcurrency:       3,
+		CollectionTimeoutSec: 1,
+	})
+	errorStore := status.NewErrorStore()
+	client.SetErrorStore(errorStore)
+	ctx := context.Background()
+
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	rangeStart := time.Date(2026, 3, 1, 0, 0, 0, 0, loc)
+	for i, name := range []string{"family", "work", "school"} {
+		path := "/remote.php/dav/calendars/testuser/" + name + "/event.ics"
+		server.put(path, fakeEventData(name, name+"の予定", fmt.Sprintf("2026030%dT010000Z", i+2)))
+	}
+	server.hang["/remote.php/dav/calendars/testuser/slow/"] = true
+	server.fail["/remote.php/dav/calendars/testuser/broken/"] = true
+
+	startedAt := time.Now()
+	resp, err := client.RefreshCalendarEvents(ctx, rangeStart, 7)
+	if err != nil {
+		t.Fatalf("一部の失敗はエラーにしない: %v", err)
+	}
+	if elapsed := time.Since(startedAt); elapsed > 3*time.Second {
+		t.Fatalf("タイムアウトしたカレンダーを待ちすぎています: %v", elapsed)
+	}
+	if got := eventTitles(resp); strings.Join(got, ",") != "familyの予定,schoolの予定,workの予定" {
+		t.Fatalf("イベント = %v", got)
+	}
+
+	_, requests := server.counts()
+	server.mu.Lock()
+	maxInFlight := server.maxInFlight
+	server.mu.Unlock()
+	// カレンダー3つを並行に、それぞれ色と同期を並行に取得するのです
+	if maxInFlight < 2 || maxInFlight > 6 {
+		t.Errorf("同時リクエスト数 = %d（requests=%v）", maxInFlight, requests)
+	}
+
+	// 失敗したカレンダーはそれぞれ記録される
+	sources := []string{}
+	for _, info := range errorStore.List() {
+		sources = append(sources, info.Source)
+	}
+	if strings.Join(sources, ",") != "calendar:broken,calendar:slow" {
+		t.Fatalf("記録されたエラー = %v", sources)
+	}
+
+	// 直ったら記録が消える
+	server.mu.Lock()
+	delete(server.hang, "/remote.php/dav/calendars/testuser/slow/")
+	delete(server.fail, "/remote.php/dav/calendars/testuser/broken/")
+	server.mu.Unlock()
+	if _, err := client.RefreshCalendarEvents(ctx, rangeStart, 7); err != nil {
+		t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+	}
+	if list := errorStore.List(); len(list) != 0 {
+		t.Fatalf("エラーの記録が消えていません: %+v", list)
+	}
+
+	// すべて失敗したらカレンダーごとのエラーをまとめて返す
+	for _, name := range []string{"family", "work", "school", "slow", "broken"} {
+		server.mu.Lock()
+		server.fail["/remote.php/dav/calendars/testuser/"+name+"/"] = true
+		server.mu.Unlock()
+	}
+	_, err = client.RefreshCalendarEvents(ctx, rangeStart, 7)
+	var collectionErr *CollectionError
+	if !errors.As(err, &collectionErr) || collectionErr.Kind != collectionKindCalendar {
+		t.Fatalf("err = %v, want CollectionError", err)
+	}
+	if len(errorStore.List()) != 5 {
+		t.Fatalf("記録されたエラー = %+v", errorStore.List())
+	}
+}
+
+func TestDiscoverCollections(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	server.collections = []fakeCalDAVCollection{
+		{path: fakeHomeSetPath + "personal/", displayName: "家族", color: "#ff8800ff", components: []string{"VEVENT"}},
+		{path: fakeHomeSetPath + "shopping-events/", displayName: "Shopping", color: "#00AA00", components: []string{"VEVENT"}},
+		{path: fakeHomeSetPath + "shopping-list/", displayName: "Shopping", components: []string{"VTODO"}},
+		{path: fakeHomeSetPath + "tasks-0a1b/", displayName: "やること", components: []string{"VTODO"}},
+	}
+	client, _ := newFakeClientWithConfig(t, server, config.Nextcloud{
+		CalendarNames: []string{"家族"},
+		TaskListNames: []string{server.URL + fakeHomeSetPath + "tasks-0a1b/", "shopping"},
+	})
+	ctx := context.Background()
+
+	collections, err := client.ListCollections(ctx)
+	if err != nil {
+		t.Fatalf("ListCollections エラー: %v", err)
+	}
+	got := []string{}
+	for _, collection := range collections {
+		got = append(got, fmt.Sprintf("%s|%s|%s|%s|%v|%v", collection.Name, collection.DisplayName, collection.Color,
+			strings.Join(collection.Components, "+"), collection.Calendar, collection.TaskList))
+	}
+	want := []string{
+		"personal|家族|#FF8800|VEVENT|true|false",
+		"shopping-events|Shopping|#00AA00|VEVENT|false|false",
+		"shopping-list|Shopping||VTODO|false|true",
+		"tasks-0a1b|やること||VTODO|false|true",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("コレクション =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if collections[0].URL != server.URL+fakeHomeSetPath+"personal/" {
+		t.Errorf("URL = %s", collections[0].URL)
+	}
+
+	// 表示名・URL で指定したコレクションから取得し、色は検出した値を使う
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	server.put(fakeHomeSetPath+"personal/dentist.ics", fakeEventData("dentist", "歯医者", "20260302T010000Z"))
+	server.put(fakeHomeSetPath+"tasks-0a1b/laundry.ics", strings.Join([]string{
+		"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Test//Test//JA",
+		"BEGIN:VTODO", "UID:laundry", "SUMMARY:洗濯", "STATUS:NEEDS-ACTION", "END:VTODO",
+		"END:VCALENDAR", "",
+	}, "\r\n"))
+	calendar, err := client.RefreshCalendarEvents(ctx, time.Date(2026, 3, 1, 0, 0, 0, 0, loc), 7)
+	if err != nil {
+		t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+	}
+	if titles := eventTitles(calendar); strings.Join(titles, ",") != "歯医者" {
+		t.Fatalf("イベント = %v", titles)
+	}
+	for _, day := range calendar.Days {
+		for _, event := range day.Timed {
+			if event.Color != "#FF8800" {
+				t.Errorf("色 = %q, want #FF8800", event.Color)
+			}
+		}
+	}
+	tasks, err := client.RefreshTaskItems(ctx)
+	if err != nil {
+		t.Fatalf("RefreshTaskItems エラー: %v", err)
+	}
+	if len(tasks.Items) != 1 || tasks.Items[0].Title != "洗濯" {
+		t.Fatalf("タスク = %+v", tasks.Items)
+	}
+
+	// 見つからない名前は従来どおりスラッグとして扱う
+	if got, _, err := client.accounts[0].resolveCollection(ctx, "unknown", "VEVENT"); err != nil || got != fakeHomeSetPath+"unknown/" {
+		t.Errorf("resolveCollection(unknown) = %s, %v", got, err)
+	}
+}
+
+func TestCachedCollectionsDoesNotHoldLockWhileDiscovering(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	server.collections = []fakeCalDAVCollection{
+		{path: fakeHomeSetPath + "personal/", displayName: "家族", components: []string{"VEVENT"}},
+	}
+	server.delay = 200 * time.Millisecond
+	client, _ := newFakeClient(t, server)
+	a := client.accounts[0]
+	ctx := context.Background()
+
+	// 3つ同時に呼んでも、検出は1回（リクエストは1本ずつ）だけなのです
+	results := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			collections, err := a.cachedCollections(ctx)
+			if err != nil {
+				results <- -1
+				return
+			}
+			results <- len(collections)
+		}()
+	}
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		server.mu.Lock()
+		inFlight := server.inFlight
+		server.mu.Unlock()
+		if inFlight > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("検出が始まりません")
+		}
+	}
+
+	// 検出中でもロックを使うほかの処理は待たされないのです
+	started := time.Now()
+	a.discoveredSince(time.Now().Format(time.RFC3339Nano))
+	shortCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := a.cachedCollections(shortCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("待っている呼び出しの err = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > 150*time.Millisecond {
+		t.Fatalf("検出中に %v 待たされました", elapsed)
+	}
+
+	for i := 0; i < 3; i++ {
+		if n := <-results; n != 1 {
+			t.Fatalf("コレクション数 = %d, want 1", n)
+		}
+	}
+	server.mu.Lock()
+	maxInFlight := server.maxInFlight
+	server.mu.Unlock()
+	if maxInFlight != 1 {
+		t.Fatalf("同時リクエスト数 = %d, want 1", maxInFlight)
+	}
+}
+
+func TestGenericCalDAVAccount(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	server.collections = []fakeCalDAVCollection{
+		{path: fakeHomeSetPath + "a1b2/", displayName: "家族", color: "#0082C9", components: []string{"VEVENT"}},
+		{path: fakeHomeSetPath + "c3d4/", displayName: "やること", components: []string{"VTODO"}},
+	}
+	cfg := &config.Config{CalDAVAccounts: []config.CalDAVAccount{{
+		ID:            "radicale",
+		ServerURL:     server.URL,
+		Username:      "testuser",
+		Password:      "testpass",
+		CalendarNames: []string{"家族", "missing"},
+		TaskListNames: []string{"やること"},
+	}}}
+	client, err := NewClient(cache.New(t.TempDir()), cfg)
+	if err != nil {
+		t.Fatalf("NewClient エラー: %v", err)
+	}
+	errorStore := status.NewErrorStore()
+	client.SetErrorStore(errorStore)
+	ctx := context.Background()
+
+	// /.well-known/caldav から principal を辿って検出する
+	collections, err := client.ListCollections(ctx)
+	if err != nil {
+		t.Fatalf("ListCollections エラー: %v", err)
+	}
+	got := []string{}
+	for _, collection := range collections {
+		got = append(got, fmt.Sprintf("%s|%s|%v|%v", collection.Account, collection.Name, collection.Calendar, collection.TaskList))
+	}
+	if strings.Join(got, ",") != "radicale|a1b2|true|false,radicale|c3d4|false|true" {
+		t.Fatalf("コレクション = %v", got)
+	}
+
+	// イベントにはアカウントIDを付けたカレンダー名が付き、見つからないカレンダーはエラーとして記録される
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	server.put(fakeHomeSetPath+"a1b2/dentist.ics", fakeEventData("dentist", "歯医者", "20260302T010000Z"))
+	calendar, err := client.RefreshCalendarEvents(ctx, time.Date(2026, 3, 1, 0, 0, 0, 0, loc), 7)
+	if err != nil {
+		t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+	}
+	if titles := eventTitles(calendar); strings.Join(titles, ",") != "歯医者" {
+		t.Fatalf("イベント = %v", titles)
+	}
+	for _, day := range calendar.Days {
+		for _, event := range day.Timed {
+			if event.Calendar != "radicale/家族" || event.Color != "#0082C9" {
+				t.Errorf("イベント = %+v", event)
+			}
+		}
+	}
+	sources := []string{}
+	for _, info := range errorStore.List() {
+		sources = append(sources, info.Source)
+	}
+	if strings.Join(sources, ",") != "calendar:radicale/missing" {
+		t.Fatalf("記録されたエラー = %v", sources)
+	}
+
+	// 書き込みは検出したコレクションに送る
+	title := "牛乳を買う"
+	task, err := client.CreateTask(ctx, models.TaskWriteRequest{Title: &title})
+	if err != nil {
+		t.Fatalf("CreateTask エラー: %v", err)
+	}
+	if _, ok := server.get(fakeHomeSetPath + "c3d4/" + task.ID + ".ics"); !ok {
+		t.Fatalf("タスクが検出したコレクションに作成されていません: %s", task.ID)
+	}
+	tasks, err := client.RefreshTaskItems(ctx)
+	if err != nil {
+		t.Fatalf("RefreshTaskItems エラー: %v", err)
+	}
+	if len(tasks.Items) != 1 || tasks.Items[0].Title != title {
+		t.Fatalf("タスク = %+v", tasks.Items)
+	}
+
+	// 見つからない名前は Nextcloud のパスにしないのです
+	if _, _, err := client.accounts[0].resolveCollection(ctx, "missing", "VEVENT"); err == nil {
+		t.Fatalf("見つからないコレクションがエラーになりません")
+	}
+}
+
+// fakeICSServer は testdata の ICS を配信するサーバーなのです。
+// ETag は etags のパスごとの値を返し、受け取ったリクエスト（パスと条件付きヘッダー）を記録するます。
+type fakeICSServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	etags    map[string]string
+	fail     map[string]bool // このパスへのリクエストは 500 を返すのです
+	requests []string
+}
+
+func newFakeICSServer(t *testing.T, etags map[string]string) *fakeICSServer {
+	t.Helper()
+	f := &fakeICSServer{etags: etags, fail: map[string]bool{}}
+	files := http.FileServer(http.Dir("testdata"))
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests = append(f.requests, strings.TrimSpace(r.URL.Path+" "+r.Header.Get("If-None-Match")))
+		etag, fail := f.etags[r.URL.Path], f.fail[r.URL.Path]
+		f.mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// setFail はパスへのリクエストを 500 にするかを切り替えるます。
+func (f *fakeICSServer) setFail(path string, fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail[path] = fail
+}
+
+// takeRequests は記録したリクエストを（パス順に）返して消すます。
+func (f *fakeICSServer) takeRequests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	got := f.requests
+	f.requests = []string{}
+	sort.Strings(got)
+	return got
+}
+
+func TestCalendarSubscriptions(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	feeds := newFakeICSServer(t, map[string]string{"/subscription_holidays.ics": `"v1"`})
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	now := time.Date(2026, 3, 16, 9, 0, 0, 0, loc)
+
+	cfg := &config.Config{
+		Nextcloud: config.Nextcloud{ServerURL: server.URL, Username: "testuser", Password: "testpass", CalendarNames: []string{"family"}},
+		Calendar: config.Calendar{Subscriptions: []config.CalendarSubscription{
+			{Name: "祝日", URL: feeds.URL + "/subscription_holidays.ics", Color: "#D50000"},
+			{Name: "ごみ", URL: feeds.URL + "/subscription_garbage.ics"},
+			{Name: "行事", URL: feeds.URL + "/missing.ics"},
+		}},
+	}
+	fc := cache.New(t.TempDir())
+	fc.SetClock(func() time.Time { return now })
+	client, err := NewClient(fc, cfg)
+	if err != nil {
+		t.Fatalf("NewClient エラー: %v", err)
+	}
+	errorStore := status.NewErrorStore()
+	client.SetErrorStore(errorStore)
+	ctx := context.Background()
+	server.put("/remote.php/dav/calendars/testuser/family/dentist.ics", fakeEventData("dentist", "歯医者", "20260317T010000Z"))
+
+	// CalDAV のカレンダーと一緒に、購読カレンダーのイベントを編集できないイベントとして返す
+	rangeStart := time.Date(2026, 3, 16, 0, 0, 0, 0, loc)
+	resp, err := client.RefreshCalendarEvents(ctx, rangeStart, 7)
+	if err != nil {
+		t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+	}
+	got := []string{}
+	for _, day := range resp.Days {
+		for _, event := range append(day.AllDay, day.Timed...) {
+			got = append(got, fmt.Sprintf("%s|%s|%s|%s|%v", day.Date, event.Title, event.Calendar, event.Color, event.ReadOnly))
+		}
+	}
+	want := []string{
+		"2026-03-17|燃えるごみ|ごみ|#33B679|true",
+		"2026-03-17|歯医者|family|#3788d8|false",
+		"2026-03-20|春分の日|祝日|#D50000|true",
+		"2026-03-20|燃えるごみ|ごみ|#33B679|true",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("イベント =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if requests := feeds.takeRequests(); strings.Join(requests, ",") != "/missing.ics,/subscription_garbage.ics,/subscription_holidays.ics" {
+		t.Fatalf("リクエスト = %v", requests)
+	}
+
+	// ダウンロードできなかった購読カレンダーはエラーとして記録される
+	sources := []string{}
+	for _, info := range errorStore.List() {
+		sources = append(sources, info.Source)
+	}
+	if strings.Join(sources, ",") != "calendar:行事" {
+		t.Fatalf("記録されたエラー = %v", sources)
+	}
+
+	// refreshSec が経つまではダウンロードし直さない
+	if _, err := client.RefreshCalendarEvents(ctx, rangeStart, 7); err != nil {
+		t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+	}
+	if requests := feeds.takeRequests(); strings.Join(requests, ",") != "/missing.ics" {
+		t.Fatalf("refreshSec 内のリクエスト = %v", requests)
+	}
+
+	// 経ったら ETag / Last-Modified の条件付きリクエストで確かめ、304 ならキャッシュを使う
+	now = now.Add(2 * time.Hour)
+	resp, err = client.RefreshCalendarEvents(ctx, rangeStart, 7)
+	if err != nil {
+		t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+	}
+	if titles := eventTitles(resp); strings.Join(titles, ",") != "春分の日,歯医者,燃えるごみ,燃えるごみ" {
+		t.Fatalf("304 のあとのイベント = %v", titles)
+	}
+	if requests := feeds.takeRequests(); strings.Join(requests, ",") != `/missing.ics,/subscription_garbage.ics,/subscription_holidays.ics "v1"` {
+		t.Fatalf("条件付きリクエスト = %v", requests)
+	}
+	entry, ok, _, _ := fc.Read(subscriptionCacheKey(cfg.Calendar.Subscriptions[1]), 0)
+	if !ok || entry.Meta["lastModified"] == "" || entry.FetchedAt != now.Format(time.RFC3339) {
+		t.Fatalf("Last-Modified のキャッシュ = %+v", entry)
+	}
+
+	// 配信元が落ちていても、保存済みの ICS のイベントを表示し続ける
+	feeds.setFail("/subscription_holidays.ics", true)
+	now = now.Add(2 * time.Hour)
+	resp, err = client.RefreshCalendarEvents(ctx, rangeStart, 7)
+	if err != nil {
+		t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+	}
+	if titles := eventTitles(resp); strings.Join(titles, ",") != "春分の日,歯医者,燃えるごみ,燃えるごみ" {
+		t.Fatalf("500 のあとのイベント = %v", titles)
+	}
+	if requests := feeds.takeRequests(); strings.Join(requests, ",") != `/missing.ics,/subscription_garbage.ics,/subscription_holidays.ics "v1"` {
+		t.Fatalf("500 のときのリクエスト = %v", requests)
+	}
+	if sources := errorStore.List(); len(sources) != 1 || sources[0].Source != "calendar:行事" {
+		t.Fatalf("保存済みの ICS を使ったのにエラーが記録されています: %+v", sources)
+	}
+	feeds.setFail("/subscription_holidays.ics", false)
+
+	// 購読カレンダーには書き込めない
+	title, start := "遠足", "2026-03-18"
+	if _, err := client.CreateEvent(ctx, models.EventWriteRequest{Title: &title, Start: &start, Calendar: "祝日"}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("CreateEvent(祝日) = %v, want ErrInvalidInput", err)
+	}
+
+	// CalDAV アカウントが無くても購読カレンダーだけで使える
+	onlyFeeds, err := NewClient(cache.New(t.TempDir()), &config.Config{Calendar: config.Calendar{Subscriptions: cfg.Calendar.Subscriptions[:1]}})
+	if err != nil {
+		t.Fatalf("購読カレンダーだけの NewClient エラー: %v", err)
+	}
+	resp, err = onlyFeeds.RefreshCalendarEvents(ctx, rangeStart, 7)
+	if err != nil {
+		t.Fatalf("RefreshCalendarEvents エラー: %v", err)
+	}
+	if titles := eventTitles(resp); strings.Join(titles, ",") != "春分の日" {
+		t.Fatalf("購読カレンダーだけのイベント = %v", titles)
+	}
+}
+
+func TestBasicAuthOnlyForAccountHosts(t *testing.T) {
+	var mu sync.Mutex
+	auth := map[string]string{}
+	record := func(name string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			auth[name] = r.Header.Get("Authorization")
+			mu.Unlock()
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	partition := record("partition")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 別のホストへ転送するサーバー
+		mu.Lock()
+		auth["server"] = r.Header.Get("Authorization")
+		mu.Unlock()
+		http.Redirect(w, r, partition.URL+"/dav/", http.StatusMovedPermanently)
+	}))
+	t.Cleanup(server.Close)
+	partitionHost := strings.TrimPrefix(partition.URL, "http://")
+
+	for _, tc := range []struct {
+		trustedHosts []string
+		wantAuth     bool
+	}{
+		{nil, false},
+		{[]string{partitionHost}, true},
+	} {
+		a, err := newAccount(config.CalDAVAccount{ID: "icloud", ServerURL: server.URL, Username: "user", Password: "secret", TrustedHosts: tc.trustedHosts})
+		if err != nil {
+			t.Fatalf("newAccount エラー: %v", err)
+		}
+		for _, target := range []string{partition.URL + "/direct/", server.URL + "/redirect/"} {
+			resp, err := a.httpClient.Get(target)
+			if err != nil {
+				t.Fatalf("GET %s: %v", target, err)
+			}
+			resp.Body.Close()
+			mu.Lock()
+			got, serverAuth := auth["partition"], auth["server"]
+			delete(auth, "partition")
+			mu.Unlock()
+			if strings.HasPrefix(target, server.URL) && serverAuth == "" {
+				t.Errorf("アカウントのサーバーに Authorization がありません")
+			}
+			if (got != "") != tc.wantAuth {
+				t.Errorf("trustedHosts=%v %s: Authorization = %q", tc.trustedHosts, target, got)
+			}
+		}
+	}
+}
+
+func TestWeakETagRoundTrip(t *testing.T) {
+	server := newFakeCalDAVServer(t)
+	server.weakETags = true
+	client, _ := newFakeClient(t, server)
+	ctx := context.Background()
+	tasksPath := client.accounts[0].getTasksPath("tasks")
+	server.put(tasksPath+"laundry.ics", strings.Join([]string{
+		"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Test//Test//JA",
+		"BEGIN:VTODO", "UID:laundry", "SUMMARY:洗濯", "STATUS:NEEDS-ACTION", "END:VTODO",
+		"END:VCALENDAR", "",
+	}, "\r\n"))
+
+	// 弱い ETag はそのまま保存する
+	if _, err := client.RefreshTaskItems(ctx); err != nil {
+		t.Fatalf("RefreshTaskItems エラー: %v", err)
+	}
+	state, err := client.store.load("", tasksPath)
+	if err != nil {
+		t.Fatalf("load エラー: %v", err)
+	}
+	if got := state.Objects[tasksPath+"laundry.ics"].ETag; got != `W/"etag-1"` {
+		t.Fatalf("保存した ETag = %q", got)
+	}
+
+	// If-Match に送り返すので、更新・削除が競合にならない
+	title := "洗濯物をたたむ"
+	if _, err := client.UpdateTask(ctx, "laundry", models.TaskWriteRequest{Title: &title}); err != nil {
+		t.Fatalf("UpdateTask エラー: %v", err)
+	}
+	if err := client.DeleteTask(ctx, "laundry"); err != nil {
+		t.Fatalf("DeleteTask エラー: %v", err)
+	}
+	if _, ok := server.get(tasksPath + "laundry.ics"); ok {
+		t.Fatalf("タスクが削除されていません")
+	}
+}
@@ -0,0 +1,104 @@
+package nextcloud
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/teambition/rrule-go"
+)
+
+// isRecurring は VEVENT が RRULE または RDATE を持つ繰り返しイベントかを判定するます。
+func isRecurring(comp *ical.Component) bool {
+	return comp.Props.Get(ical.PropRecurrenceRule) != nil || comp.Props.Get(ical.PropRecurrenceDates) != nil
+}
+
+// isCancelled は VEVENT が STATUS:CANCELLED かを判定するます。
+func isCancelled(comp *ical.Component) bool {
+	status := comp.Props.Get(ical.PropStatus)
+	return status != nil && strings.EqualFold(strings.TrimSpace(status.Value), "CANCELLED")
+}
+
+// expandRecurrence は繰り返しイベントの発生開始時刻を範囲内で列挙するます。
+// RRULE/RDATE/EXDATE を解釈し、DTSTART 自体も最初の発生として含めるのです。
+func expandRecurrence(comp *ical.Component, start, rangeStart, rangeEnd time.Time, tz *tzResolver) ([]time.Time, error) {
+	set := rrule.Set{}
+	set.DTStart(start)
+	// RFC 5545: DTSTART は常に最初の発生として扱うます
+	set.RDate(start)
+
+	if prop := comp.Props.Get(ical.PropRecurrenceRule); prop != nil && strings.TrimSpace(prop.Value) != "" {
+		option, err := rrule.StrToROptionInLocation(prop.Value, start.Location())
+		if err != nil {
+			return nil, fmt.Errorf("RRULE解析失敗: %w", err)
+		}
+		option.Dtstart = start
+
+		rule, err := rrule.NewRRule(*option)
+		if err != nil {
+			return nil, fmt.Errorf("RRULE生成失敗: %w", err)
+		}
+		set.RRule(rule)
+	}
+
+	for _, rdate := range parseDateTimeList(comp.Props.Values(ical.PropRecurrenceDates), tz) {
+		set.RDate(rdate)
+	}
+	for _, exdate := range parseDateTimeList(comp.Props.Values(ical.PropExceptionDates), tz) {
+		set.ExDate(exdate)
+	}
+
+	return set.Between(rangeStart, rangeEnd, true), nil
+}
+
+// parseDateTimeList は RDATE/EXDATE のようなカンマ区切り日時リストをパースするます。
+// PERIOD 形式（開始/終了）の場合は開始時刻のみを使うのです。
+func parseDateTimeList(props []ical.Prop, tz *tzResolver) []time.Time {
+	times := []time.Time{}
+	for _, prop := range props {
+		tzid := prop.Params.Get(ical.PropTimezoneID)
+		for _, value := range strings.Split(prop.Value, ",") {
+			if idx := strings.Index(value, "/"); idx >= 0 {
+				value = value[:idx]
+			}
+			t, _ := tz.parseValue(value, tzid)
+			if t.IsZero() {
+				continue
+			}
+			times = append(times, t)
+		}
+	}
+	return times
+}
+
+// recurrenceKey は発生時刻を RECURRENCE-ID 相当の文字列に変換するます。
+// 終日は YYYYMMDD、時間指定は UTC の YYYYMMDDTHHMMSSZ なのです。
+func recurrenceKey(t time.Time, allDay bool) string {
+	if allDay {
+		return t.Format("20060102")
+	}
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// occurrenceID は繰り返しイベントの発生ごとに安定したIDを返すます。
+func occurrenceID(uid, key string) string {
+	return uid + "_" + key
+}
+
+// splitOccurrenceID は occurrenceID で作ったIDを UID と recurrenceKey に分けるます。
+// UID にも _ が入ることがあるので、最後の _ で一度だけ分け、後ろが recurrenceKey の形のときだけ ok なのです。
+func splitOccurrenceID(id string) (string, string, bool) {
+	idx := strings.LastIndex(id, "_")
+	if idx <= 0 {
+		return "", "", false
+	}
+	uid, key := id[:idx], id[idx+1:]
+	if _, err := time.Parse("20060102", key); err == nil {
+		return uid, key, true
+	}
+	if _, err := time.Parse("20060102T150405Z", key); err == nil {
+		return uid, key, true
+	}
+	return "", "", false
+}
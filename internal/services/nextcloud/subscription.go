@@ -0,0 +1,180 @@
+package nextcloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/rihow/FamilyDashboard/internal/cache"
+	"github.com/rihow/FamilyDashboard/internal/config"
+)
+
+// subscriptionCacheKeyPrefix は ICS 購読カレンダーのキャッシュキーの接頭辞なのです。
+// 書き込み時に破棄するカレンダーキャッシュ（calendarCacheKeyPrefix）とは別にしているのです。
+const subscriptionCacheKeyPrefix = "ics_subscription_"
+
+// maxSubscriptionSize は ICS 1件のダウンロードの上限なのです（何年分もの予定を含むフィードでも十分な大きさ）。
+const maxSubscriptionSize = 10 << 20
+
+// subscriptionCacheKey は ICS 購読カレンダーのキャッシュキーを返すます。
+func subscriptionCacheKey(subscription config.CalendarSubscription) string {
+	return subscriptionCacheKeyPrefix + subscription.GetURL()
+}
+
+// findSubscription は名前が一致する ICS 購読カレンダーを返すます。
+func findSubscription(subscriptions []config.CalendarSubscription, name string) (config.CalendarSubscription, bool) {
+	for _, subscription := range subscriptions {
+		if subscription.Name == name {
+			return subscription, true
+		}
+	}
+	return config.CalendarSubscription{}, false
+}
+
+// subscriptionNames は ICS 購読カレンダーの名前を設定の順に返すます。
+func subscriptionNames(subscriptions []config.CalendarSubscription) []string {
+	names := make([]string, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		names = append(names, subscription.Name)
+	}
+	return names
+}
+
+// fetchSubscriptionEvents は ICS 購読カレンダー1つから期間内のイベントを返すます。
+// イベントは CalDAV のカレンダーと同じく parseCalendarObject で展開し、編集できないイベントとして返すのです。
+func (c *Client) fetchSubscriptionEvents(ctx context.Context, subscription config.CalendarSubscription, startDate, endDate time.Time) ([]eventWithDate, error) {
+	fmt.Printf("  🗓️ 購読カレンダー '%s' からイベント取得中...\n", subscription.Name)
+	data, err := c.downloadSubscription(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	calendars, err := decodeSubscription(data)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []eventWithDate{}
+	for _, cal := range calendars {
+		// 設定の色が無ければ、フィードの X-APPLE-CALENDAR-COLOR を使うます
+		calendarColor := subscription.Color
+		if calendarColor == "" {
+			if prop := cal.Props.Get("X-APPLE-CALENDAR-COLOR"); prop != nil {
+				calendarColor, _ = normalizeHexColor(prop.Value)
+			}
+		}
+		for _, event := range parseCalendarObject(cal, startDate, endDate, subscription.Name, calendarColor) {
+			event.event.ReadOnly = true
+			events = append(events, event)
+		}
+	}
+
+	fmt.Printf("  ✅ 購読カレンダー '%s' から %d 件のイベントを取得\n", subscription.Name, len(events))
+	return events, nil
+}
+
+// downloadSubscription は ICS をダウンロードして本文を返すます。
+// 前回から refreshSec 経っていなければキャッシュを返し、経っていれば ETag / Last-Modified の条件付きリクエストで
+// 変わったときだけダウンロードするのです（304 ならキャッシュを使い続けるます）。
+// 配信元が一時的に落ちていても、保存済みの ICS があればそれを返すので、祝日などが消えないのです。
+func (c *Client) downloadSubscription(ctx context.Context, subscription config.CalendarSubscription) (string, error) {
+	cacheKey := subscriptionCacheKey(subscription)
+	entry, ok, stale, err := c.cache.Read(cacheKey, subscription.GetRefreshInterval())
+	var cachedData string
+	cached := ok && err == nil && json.Unmarshal(entry.Payload, &cachedData) == nil
+	if cached && !stale {
+		return cachedData, nil
+	}
+
+	data, err := c.requestSubscription(ctx, subscription, cached, entry, cachedData)
+	if err != nil && cached {
+		fmt.Printf("⚠️ 購読カレンダー '%s' のダウンロード失敗（保存済みの ICS を使うます）: %v\n", subscription.Name, err)
+		return cachedData, nil
+	}
+	return data, err
+}
+
+// requestSubscription は ICS を GET して、200 なら本文をキャッシュに保存して返すます。
+// cached なら保存済みの ETag / Last-Modified で条件付きリクエストにし、304 なら cachedData を返すのです。
+func (c *Client) requestSubscription(ctx context.Context, subscription config.CalendarSubscription, cached bool, entry cache.Entry, cachedData string) (string, error) {
+	cacheKey := subscriptionCacheKey(subscription)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, subscription.GetURL(), nil)
+	if err != nil {
+		return "", fmt.Errorf("ICS リクエスト作成失敗: %w", err)
+	}
+	req.Header.Set("Accept", "text/calendar")
+	if cached {
+		if etag := entry.Meta["etag"]; etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Meta["lastModified"]; lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := c.subscriptionHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ICS リクエスト失敗: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		// 変わっていないので、保存時刻だけ更新して次の refreshSec まで聞き直さないのです
+		fmt.Printf("  📦 購読カレンダー '%s' は変更なし（304）\n", subscription.Name)
+		if _, err := c.cache.Write(cacheKey, cachedData, entry.Meta); err != nil {
+			fmt.Printf("⚠️ キャッシュ保存失敗: %v\n", err)
+		}
+		return cachedData, nil
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("ICS HTTPエラー: code=%d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSubscriptionSize+1))
+	if err != nil {
+		return "", fmt.Errorf("ICS 読み込み失敗: %w", err)
+	}
+	if len(body) > maxSubscriptionSize {
+		return "", fmt.Errorf("ICS が大きすぎます（%d バイトまで）", maxSubscriptionSize)
+	}
+	data := string(body)
+	if _, err := decodeSubscription(data); err != nil {
+		return "", err
+	}
+
+	meta := map[string]string{
+		"source":       "ics_subscription",
+		"name":         subscription.Name,
+		"etag":         resp.Header.Get("ETag"),
+		"lastModified": resp.Header.Get("Last-Modified"),
+	}
+	if _, err := c.cache.Write(cacheKey, data, meta); err != nil {
+		fmt.Printf("⚠️ キャッシュ保存失敗: %v\n", err)
+	}
+	return data, nil
+}
+
+// decodeSubscription は ICS の本文を VCALENDAR ごとに解析するます。
+func decodeSubscription(data string) ([]*ical.Calendar, error) {
+	decoder := ical.NewDecoder(strings.NewReader(data))
+	calendars := []*ical.Calendar{}
+	for {
+		cal, err := decoder.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ICS 解析失敗: %w", err)
+		}
+		calendars = append(calendars, cal)
+	}
+	if len(calendars) == 0 {
+		return nil, fmt.Errorf("ICS に VCALENDAR がありません")
+	}
+	return calendars, nil
+}
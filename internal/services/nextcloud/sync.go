@@ -0,0 +1,532 @@
+package nextcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/rihow/FamilyDashboard/internal/cache"
+)
+
+// errSyncTokenInvalid は保存している sync-token をサーバーが受け付けなかった場合のエラーなのです。
+// トークンを捨てて最初から同期し直すます。
+var errSyncTokenInvalid = errors.New("sync-token が無効なのです")
+
+// errSyncUnsupported はサーバーが sync-collection REPORT に対応していない場合のエラーなのです。
+// コレクションを検出し直すまでは sync-collection を送らずに ctag/ETag で比べるます。
+var errSyncUnsupported = errors.New("sync-collection に対応していないのです")
+
+// storedObject はオブジェクトストアに保存するカレンダーリソース1件なのです。
+type storedObject struct {
+	ETag string `json:"etag"`
+	Data string `json:"data"` // iCalendar のテキスト
+}
+
+// collectionState はコレクション1つの同期状態と、そのコレクションのリソースなのです。
+type collectionState struct {
+	Account   string                  `json:"account,omitempty"`   // アカウントID（nextcloud の設定のアカウントは空）
+	Path      string                  `json:"path"`                // コレクションのパス（別のホストのコレクションは絶対URL）
+	SyncToken string                  `json:"syncToken,omitempty"` // RFC 6578 の sync-token（未対応のサーバーでは空）
+	CTag      string                  `json:"ctag,omitempty"`      // CalendarServer の getctag（sync-token が使えないときの変更検出）
+	Objects   map[string]storedObject `json:"objects"`             // href（パス）→ リソース
+	SyncedAt  string                  `json:"syncedAt,omitempty"`  // 最後に同期した時刻（RFC3339）
+
+	SyncUnsupported string `json:"syncUnsupported,omitempty"` // sync-collection を断られた時刻（RFC3339Nano、対応しているなら空）
+}
+
+// ObjectStore は CalDAV コレクションのリソースを href ごとに保存するローカルストアなのです。
+// 前回からの差分だけを取得するために、sync-token・ctag・ETag と一緒に data/ 配下に保存するます。
+// dir が空の場合はメモリ上だけに保持するのです（再起動すると最初から同期し直すのです）。
+type ObjectStore struct {
+	dir string
+
+	mu          sync.Mutex
+	collections map[string]*collectionState // アカウントID・コレクションのパス → 同期状態（読み込み済みのもの）
+	locks       map[string]*sync.Mutex      // コレクションごとの同期のロック
+}
+
+// NewObjectStore は CalDAV のオブジェクトストアを作成するます。
+// dir は保存先（例: ./data/caldav）なのです。
+func NewObjectStore(dir string) *ObjectStore {
+	return &ObjectStore{
+		dir:         dir,
+		collections: map[string]*collectionState{},
+		locks:       map[string]*sync.Mutex{},
+	}
+}
+
+// lock はコレクションの同期を1つずつにするためのロックを取るます。
+// カレンダーとタスクリストで同じコレクションを使う場合も、同時に同期しないのです。
+func (s *ObjectStore) lock(accountID, collectionPath string) func() {
+	key := storeKey(accountID, collectionPath)
+	s.mu.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[key] = l
+	}
+	s.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// load はコレクションの同期状態のコピーを返すます。
+// 保存されていない（初めて同期する）場合は空の状態なのです。
+func (s *ObjectStore) load(accountID, collectionPath string) (*collectionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := storeKey(accountID, collectionPath)
+	state, ok := s.collections[key]
+	if !ok {
+		loaded, err := s.readFile(accountID, collectionPath)
+		if err != nil {
+			return nil, err
+		}
+		state = loaded
+		s.collections[key] = state
+	}
+	return state.clone(), nil
+}
+
+// save はコレクションの同期状態を保存するます。
+// ファイルは一時ファイルに書いてから置き換えるので、途中で止まっても壊れないのです。
+func (s *ObjectStore) save(state *collectionState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.collections[storeKey(state.Account, state.Path)] = state.clone()
+	if s.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	path := s.filePath(state.Account, state.Path)
+	tmpFile, err := os.CreateTemp(s.dir, filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+	return nil
+}
+
+// readFile は保存されているコレクションの同期状態を読み込むます（無ければ空）。
+// 壊れたファイルは捨てて最初から同期し直すのです。
+func (s *ObjectStore) readFile(accountID, collectionPath string) (*collectionState, error) {
+	empty := &collectionState{Account: accountID, Path: collectionPath, Objects: map[string]storedObject{}}
+	if s.dir == "" {
+		return empty, nil
+	}
+
+	data, err := os.ReadFile(s.filePath(accountID, collectionPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state collectionState
+	if err := json.Unmarshal(data, &state); err != nil || state.Account != accountID || state.Path != collectionPath {
+		fmt.Printf("⚠️ CalDAV オブジェクトストアの読み込み失敗（最初から同期し直すます）: %s\n", collectionPath)
+		return empty, nil
+	}
+	if state.Objects == nil {
+		state.Objects = map[string]storedObject{}
+	}
+	return &state, nil
+}
+
+func (s *ObjectStore) filePath(accountID, collectionPath string) string {
+	return filepath.Join(s.dir, cache.SafeFileName("caldav:"+storeKey(accountID, collectionPath))+".json")
+}
+
+// storeKey はアカウントIDとコレクションのパスからストアのキーを作るます。
+// nextcloud の設定のアカウント（ID が空）はパスだけなのです。
+func storeKey(accountID, collectionPath string) string {
+	if accountID == "" {
+		return collectionPath
+	}
+	return accountID + ":" + collectionPath
+}
+
+func (state *collectionState) clone() *collectionState {
+	copied := *state
+	copied.Objects = make(map[string]storedObject, len(state.Objects))
+	for href, object := range state.Objects {
+		copied.Objects[href] = object
+	}
+	return &copied
+}
+
+// calendars は保存されているリソースを iCalendar として返すます（href 順）。
+// 解析できないリソースは読み飛ばすのです。
+func (state *collectionState) calendars() []*ical.Calendar {
+	hrefs := make([]string, 0, len(state.Objects))
+	for href := range state.Objects {
+		hrefs = append(hrefs, href)
+	}
+	sort.Strings(hrefs)
+
+	calendars := make([]*ical.Calendar, 0, len(hrefs))
+	for _, href := range hrefs {
+		cal, err := ical.NewDecoder(strings.NewReader(state.Objects[href].Data)).Decode()
+		if err != nil {
+			fmt.Printf("⚠️ iCalendar の解析失敗: %s: %v\n", href, err)
+			continue
+		}
+		calendars = append(calendars, cal)
+	}
+	return calendars
+}
+
+// SetObjectStore は CalDAV のオブジェクトストアを設定するます。
+// 設定しない場合はメモリ上のストアを使うのです。
+func (c *Client) SetObjectStore(store *ObjectStore) {
+	c.store = store
+}
+
+// syncCollection はコレクションの変更をオブジェクトストアに取り込んで、全リソースを返すます。
+// RFC 6578 の sync-collection で前回からの差分だけを取得し、
+// サーバーが対応していない場合は ctag と ETag を比べて変わったリソースだけを取得するのです。
+func (c *Client) syncCollection(ctx context.Context, a *account, collectionPath string) ([]*ical.Calendar, error) {
+	unlock := c.store.lock(a.storeID(), collectionPath)
+	defer unlock()
+
+	state, err := c.store.load(a.storeID(), collectionPath)
+	if err != nil {
+		return nil, fmt.Errorf("オブジェクトストア読み込み失敗: %w", err)
+	}
+
+	// sync-collection を断られたコレクションは、検出し直すまで ctag/ETag だけで比べるます
+	var fetched, removed int
+	syncUnsupported := state.SyncUnsupported != "" && !a.discoveredSince(state.SyncUnsupported)
+	if syncUnsupported {
+		err = errSyncUnsupported
+	} else {
+		fetched, removed, err = a.syncByToken(ctx, state)
+		if errors.Is(err, errSyncTokenInvalid) {
+			fmt.Printf("🔁 sync-token が無効なので最初から同期するます: %s\n", collectionPath)
+			state.SyncToken = ""
+			fetched, removed, err = a.syncByToken(ctx, state)
+		}
+	}
+	if err != nil {
+		if !syncUnsupported {
+			fmt.Printf("⚠️ sync-collection 失敗（ctag/ETag で比較するます）: %s: %v\n", collectionPath, err)
+			if errors.Is(err, errSyncUnsupported) {
+				state.SyncUnsupported = time.Now().Format(time.RFC3339Nano)
+			}
+		}
+		state.SyncToken = ""
+		fetched, removed, err = a.syncByETag(ctx, state)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		state.SyncUnsupported = ""
+	}
+
+	state.SyncedAt = time.Now().Format(time.RFC3339)
+	if err := c.store.save(state); err != nil {
+		fmt.Printf("⚠️ オブジェクトストア保存失敗: %v\n", err)
+	}
+	if fetched > 0 || removed > 0 {
+		fmt.Printf("  🔄 %s: %d 件取得、%d 件削除（保存 %d 件）\n", collectionPath, fetched, removed, len(state.Objects))
+	}
+	return state.calendars(), nil
+}
+
+// isSyncRejected は sync-collection REPORT への応答が「対応していない」を表すステータスかを判定するます。
+// 5xx やタイムアウトは一時的な失敗かもしれないので、対応していないとは見なさないのです。
+func isSyncRejected(code int) bool {
+	switch code {
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// syncByToken は sync-collection REPORT で前回の sync-token からの変更を取り込むます。
+// sync-token が空の場合は初回同期で、一覧に無いリソースは削除するのです。
+func (a *account) syncByToken(ctx context.Context, state *collectionState) (int, int, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<d:sync-collection xmlns:d="DAV:">
+	<d:sync-token>`)
+	xml.EscapeText(&body, []byte(state.SyncToken))
+	body.WriteString(`</d:sync-token>
+	<d:sync-level>1</d:sync-level>
+	<d:prop>
+		<d:getetag/>
+	</d:prop>
+</d:sync-collection>`)
+
+	ms, err := a.davMultiStatus(ctx, "REPORT", state.Path, "0", body.Bytes())
+	var statusErr *davStatusError
+	if errors.As(err, &statusErr) && isSyncRejected(statusErr.Code) {
+		return 0, 0, fmt.Errorf("%w: %w", err, errSyncUnsupported)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if ms.SyncToken == "" {
+		return 0, 0, fmt.Errorf("sync-token が返されませんでした: %w", errSyncUnsupported)
+	}
+
+	initial := state.SyncToken == ""
+	listed := map[string]bool{}
+	removed := 0
+	changed := []string{}
+	for _, response := range ms.Responses {
+		href, ok := a.memberHref(state.Path, response.Href)
+		if !ok {
+			continue
+		}
+		if strings.Contains(response.Status, " 404") {
+			if _, exists := state.Objects[href]; exists {
+				delete(state.Objects, href)
+				removed++
+			}
+			continue
+		}
+		etag, ok := response.etag()
+		if !ok {
+			continue
+		}
+		listed[href] = true
+		if stored, exists := state.Objects[href]; !exists || stored.ETag != etag {
+			changed = append(changed, href)
+		}
+	}
+	if initial {
+		removed += removeUnlisted(state, listed)
+	}
+
+	if err := a.fetchObjects(ctx, state, changed); err != nil {
+		return 0, 0, err
+	}
+	state.SyncToken = ms.SyncToken
+	return len(changed), removed, nil
+}
+
+// syncByETag は ctag が変わっていればリソースの ETag 一覧を取得して、変わったリソースだけを取り込むます。
+// ctag を返さないサーバーでは毎回 ETag 一覧を比べるのです。
+func (a *account) syncByETag(ctx context.Context, state *collectionState) (int, int, error) {
+	ctagBody := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/">
+	<d:prop>
+		<cs:getctag/>
+	</d:prop>
+</d:propfind>`)
+	ms, err := a.davMultiStatus(ctx, "PROPFIND", state.Path, "0", ctagBody)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ctag取得失敗: %w", err)
+	}
+	ctag := ""
+	for _, response := range ms.Responses {
+		if value, ok := response.ctag(); ok {
+			ctag = value
+		}
+	}
+	if ctag != "" && ctag == state.CTag {
+		return 0, 0, nil
+	}
+
+	etagBody := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<d:propfind xmlns:d="DAV:">
+	<d:prop>
+		<d:getetag/>
+	</d:prop>
+</d:propfind>`)
+	ms, err = a.davMultiStatus(ctx, "PROPFIND", state.Path, "1", etagBody)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ETag一覧取得失敗: %w", err)
+	}
+
+	listed := map[string]bool{}
+	changed := []string{}
+	for _, response := range ms.Responses {
+		href, ok := a.memberHref(state.Path, response.Href)
+		if !ok {
+			continue
+		}
+		etag, ok := response.etag()
+		if !ok {
+			continue
+		}
+		listed[href] = true
+		if stored, exists := state.Objects[href]; !exists || stored.ETag != etag {
+			changed = append(changed, href)
+		}
+	}
+	removed := removeUnlisted(state, listed)
+
+	if err := a.fetchObjects(ctx, state, changed); err != nil {
+		return 0, 0, err
+	}
+	state.CTag = ctag
+	return len(changed), removed, nil
+}
+
+// fetchObjects は calendar-multiget REPORT で変わったリソースの本文をまとめて取得するます。
+// 本文はサーバーが返した iCalendar のテキストのまま保存するのです。
+// 取得までの間に削除されたリソース（404）は次の同期で削除されるので読み飛ばすます。
+func (a *account) fetchObjects(ctx context.Context, state *collectionState, hrefs []string) error {
+	if len(hrefs) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<cal:calendar-multiget xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+	<d:prop>
+		<d:getetag/>
+		<cal:calendar-data/>
+	</d:prop>
+`)
+	for _, href := range hrefs {
+		body.WriteString("\t<d:href>")
+		xml.EscapeText(&body, []byte(hrefFor(href)))
+		body.WriteString("</d:href>\n")
+	}
+	body.WriteString(`</cal:calendar-multiget>`)
+
+	ms, err := a.davMultiStatus(ctx, "REPORT", state.Path, "1", body.Bytes())
+	if err != nil {
+		return fmt.Errorf("calendar-multiget 失敗: %w", err)
+	}
+
+	for _, response := range ms.Responses {
+		href, ok := a.memberHref(state.Path, response.Href)
+		if !ok {
+			continue
+		}
+		etag, ok := response.etag()
+		if !ok {
+			continue
+		}
+		data := response.calendarData()
+		if data == "" {
+			continue
+		}
+		state.Objects[href] = storedObject{ETag: etag, Data: data}
+	}
+	return nil
+}
+
+// removeUnlisted は一覧に無いリソースをストアから削除して、削除した件数を返すます。
+func removeUnlisted(state *collectionState, listed map[string]bool) int {
+	removed := 0
+	for href := range state.Objects {
+		if !listed[href] {
+			delete(state.Objects, href)
+			removed++
+		}
+	}
+	return removed
+}
+
+// davMultiStatus は WebDAV の multistatus レスポンスなのです。
+type davMultiStatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+	SyncToken string        `xml:"DAV: sync-token"`
+}
+
+// davResponse は multistatus のリソース1件なのです。
+type davResponse struct {
+	Href      string `xml:"DAV: href"`
+	Status    string `xml:"DAV: status"` // sync-collection で削除されたリソースは 404
+	PropStats []struct {
+		Status string  `xml:"DAV: status"`
+		Prop   davProp `xml:"DAV: prop"`
+	} `xml:"DAV: propstat"`
+}
+
+// davProp は PROPFIND・REPORT で取得するプロパティなのです（要求していないものは空なのです）。
+type davProp struct {
+	ETag         string `xml:"DAV: getetag"`
+	CTag         string `xml:"http://calendarserver.org/ns/ getctag"`
+	CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+
+	// コレクションの検出で使うプロパティなのです
+	CurrentUserPrincipal davHrefs `xml:"DAV: current-user-principal"`
+	CalendarHomeSet      davHrefs `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	DisplayName          string   `xml:"DAV: displayname"`
+	CalendarColor        string   `xml:"http://apple.com/ns/ical/ calendar-color"`
+	ResourceType         struct {
+		Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+	} `xml:"DAV: resourcetype"`
+	SupportedComponents struct {
+		Comps []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp"`
+	} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+}
+
+// davHrefs は href を含むプロパティなのです。
+type davHrefs struct {
+	Hrefs []string `xml:"DAV: href"`
+}
+
+// prop は 200 の propstat のプロパティを返すます（無ければ空）。
+func (r davResponse) prop() davProp {
+	for _, propStat := range r.PropStats {
+		if strings.Contains(propStat.Status, " 200") {
+			return propStat.Prop
+		}
+	}
+	return davProp{}
+}
+
+// etag は getetag を返すます。
+// 強い ETag（"abc"）は引用符を外し、弱い ETag（W/"abc"）はそのまま返すのです（If-Match にそのまま送り返すため）。
+func (r davResponse) etag() (string, bool) {
+	etag := strings.TrimSpace(r.prop().ETag)
+	if !strings.HasPrefix(etag, "W/") {
+		etag = strings.Trim(etag, `"`)
+	}
+	return etag, etag != ""
+}
+
+// ctag は getctag を返すます。
+func (r davResponse) ctag() (string, bool) {
+	ctag := r.prop().CTag
+	return ctag, ctag != ""
+}
+
+// calendarData は calendar-data を返すます。
+func (r davResponse) calendarData() string {
+	return r.prop().CalendarData
+}
@@ -3,6 +3,7 @@ package nextcloud
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -13,10 +14,13 @@ import (
 	"github.com/rihow/FamilyDashboard/internal/models"
 )
 
+// TasksCacheKey は統合タスクリストのキャッシュキーなのです。
+const TasksCacheKey = "nextcloud_tasks_items_all"
+
 // GetTaskItems はNextcloud WebDAVからタスクアイテムを取得するます。
 // 複数のタスクリストからVTODOコンポーネントを取得し、サーバー側でソート（期限→優先度→作成日時）して返すのです。
 func (c *Client) GetTaskItems(ctx context.Context) (*models.TasksResponse, error) {
-	cacheKey := "nextcloud_tasks_items_all"
+	cacheKey := TasksCacheKey
 	ttl := c.config.GetRefreshInterval("tasks")
 
 	// キャッシュを確認するます
@@ -30,58 +34,35 @@ func (c *Client) GetTaskItems(ctx context.Context) (*models.TasksResponse, error
 		fmt.Printf("⚠️ キャッシュデータのパース失敗: %v\n", err)
 	}
 
-	// 複数タスクリスト名を取得するます
-	taskListNames := c.config.GetTaskListNames()
-	if len(taskListNames) == 0 {
+	return c.RefreshTaskItems(ctx)
+}
+
+// RefreshTaskItems はキャッシュを見ずに Nextcloud からタスクを取得してキャッシュを更新するます。
+// バックグラウンド更新から呼ばれるほか、GetTaskItems のキャッシュミス時にも使うのです。
+func (c *Client) RefreshTaskItems(ctx context.Context) (*models.TasksResponse, error) {
+	cacheKey := TasksCacheKey
+
+	// 全アカウントのタスクリストを取得するます
+	if len(c.taskLists) == 0 {
 		return nil, fmt.Errorf("タスクリスト名が設定されていません")
 	}
 
-	fmt.Printf("🌐 Nextcloud WebDAV から %d 個のタスクリストを取得するます...\n", len(taskListNames))
+	fmt.Printf("🌐 CalDAV から %d 個のタスクリストを取得するます...\n", len(c.taskLists))
 
-	// 全タスクリストからタスクを収集するます
+	// 全タスクリストを並行に取得して、設定の順にタスクを集めるます
+	results := fetchCollections(ctx, c, collectionKindTasks, collectionKeys(c.taskLists), func(ctx context.Context, taskListName string) ([]models.TaskItem, error) {
+		ref, _ := findCollectionRef(c.taskLists, taskListName)
+		return c.fetchTaskItems(ctx, ref)
+	})
 	allTasks := []models.TaskItem{}
 	var fetchErrors []error
-
-	for _, taskListName := range taskListNames {
-		fmt.Printf("  ✅ タスクリスト '%s' からタスク取得中...\n", taskListName)
-
-		// CalDAVクエリを実行（VTODOコンポーネント取得）
-		tasksPath := c.getTasksPath(taskListName)
-		query := &caldav.CalendarQuery{
-			CompRequest: caldav.CalendarCompRequest{
-				Name: "VCALENDAR",
-				Comps: []caldav.CalendarCompRequest{
-					{
-						Name:  "VTODO",
-						Props: []string{"UID", "SUMMARY", "STATUS", "PRIORITY", "DUE", "CREATED", "DESCRIPTION"},
-					},
-				},
-			},
-			CompFilter: caldav.CompFilter{
-				Name: "VCALENDAR",
-				Comps: []caldav.CompFilter{
-					{
-						Name: "VTODO",
-					},
-				},
-			},
-		}
-
-		calendarObjects, err := c.caldavClient.QueryCalendar(ctx, tasksPath, query)
-		if err != nil {
+	for _, result := range results {
+		if result.err != nil {
 			// エラーを記録するが続行するます（部分的成功を許容）
-			fmt.Printf("❌ タスクリスト '%s' のWebDAVクエリエラー: %v\n", taskListName, err)
-			fetchErrors = append(fetchErrors, fmt.Errorf("tasklist '%s': %w", taskListName, err))
+			fetchErrors = append(fetchErrors, result.err)
 			continue
 		}
-
-		// iCalendar VTODO オブジェクトをパースして構造化するます
-		for _, obj := range calendarObjects {
-			parsedTasks := parseTaskObject(obj.Data)
-			allTasks = append(allTasks, parsedTasks...)
-		}
-
-		fmt.Printf("  ✅ タスクリスト '%s' から %d 件のタスク取得\n", taskListName, len(calendarObjects))
+		allTasks = append(allTasks, result.items...)
 	}
 
 	// すべてのタスクリスト取得に失敗した場合
@@ -93,10 +74,10 @@ func (c *Client) GetTaskItems(ctx context.Context) (*models.TasksResponse, error
 			fmt.Println("📦 期限切れキャッシュを返すます")
 			var resp models.TasksResponse
 			if unmarshalErr := json.Unmarshal(entry.Payload, &resp); unmarshalErr == nil {
-				return &resp, fmt.Errorf("全タスクリスト取得失敗（キャッシュ返却）: %d エラー", len(fetchErrors))
+				return &resp, fmt.Errorf("全タスクリスト取得失敗（キャッシュ返却）: %d エラー: %w", len(fetchErrors), errors.Join(fetchErrors...))
 			}
 		}
-		return nil, fmt.Errorf("全タスクリスト取得失敗: %d エラー", len(fetchErrors))
+		return nil, fmt.Errorf("全タスクリスト取得失敗: %d エラー: %w", len(fetchErrors), errors.Join(fetchErrors...))
 	}
 
 	// サーバー側ソート: 期限→優先度→作成日時
@@ -120,6 +101,31 @@ func (c *Client) GetTaskItems(ctx context.Context) (*models.TasksResponse, error
 	return response, nil
 }
 
+// fetchTaskItems はタスクリスト1つを同期して、タスクを返すます。
+func (c *Client) fetchTaskItems(ctx context.Context, ref collectionRef) ([]models.TaskItem, error) {
+	fmt.Printf("  ✅ タスクリスト '%s' からタスク取得中...\n", ref.key)
+
+	taskListPath, err := ref.taskListPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 前回からの差分だけを取得して、保存済みのリソースと合わせて返すます
+	calendarObjects, err := c.syncCollection(ctx, ref.account, taskListPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// iCalendar VTODO オブジェクトをパースして構造化するます
+	tasks := []models.TaskItem{}
+	for _, obj := range calendarObjects {
+		tasks = append(tasks, parseTaskObject(obj)...)
+	}
+
+	fmt.Printf("  ✅ タスクリスト '%s' の %d 件のオブジェクトを同期\n", ref.key, len(calendarObjects))
+	return tasks, nil
+}
+
 // parseTaskObject はiCalendar VTODOデータをパースしてタスクリストに変換するます。
 func parseTaskObject(cal *ical.Calendar) []models.TaskItem {
 	tasks := []models.TaskItem{}
@@ -129,6 +135,7 @@ func parseTaskObject(cal *ical.Calendar) []models.TaskItem {
 	}
 
 	loc, _ := time.LoadLocation("Asia/Tokyo")
+	tz := newTZResolver(cal, loc)
 
 	for _, comp := range cal.Children {
 		if comp.Name != "VTODO" {
@@ -178,7 +185,7 @@ func parseTaskObject(cal *ical.Calendar) []models.TaskItem {
 		// 期限をパース
 		var dueDate *string
 		if due != nil && due.Value != "" {
-			parsedDue, _ := parseTaskDateTime(due.Value, loc)
+			parsedDue, _ := parseTaskDateTime(due, tz)
 			if !parsedDue.IsZero() {
 				dueDateStr := parsedDue.Format("2006-01-02")
 				dueDate = &dueDateStr
@@ -188,7 +195,7 @@ func parseTaskObject(cal *ical.Calendar) []models.TaskItem {
 		// 作成日時をパース
 		createdAt := time.Now()
 		if created != nil && created.Value != "" {
-			parsedCreated, _ := parseTaskDateTime(created.Value, loc)
+			parsedCreated, _ := parseTaskDateTime(created, tz)
 			if !parsedCreated.IsZero() {
 				createdAt = parsedCreated
 			}
@@ -224,34 +231,14 @@ func parsePriority(value string) int {
 	return priority
 }
 
-// parseTaskDateTime はiCalendar日時文字列をパースするます。
-func parseTaskDateTime(value string, loc *time.Location) (time.Time, bool) {
-	value = strings.TrimSpace(value)
-
-	// 日付のみ（YYYYMMDD形式）
-	if len(value) == 8 {
-		t, err := time.ParseInLocation("20060102", value, loc)
-		if err == nil {
-			return t, true
-		}
-	}
-
-	// 日時指定（YYYYMMDDTHHMMSSフォーマット）
-	if len(value) >= 15 {
-		value = strings.TrimSuffix(value, "Z")
-		t, err := time.ParseInLocation("20060102T150405", value, loc)
-		if err == nil {
-			return t, false
-		}
-	}
-
-	// RFC3339形式もサポート
-	t, err := time.Parse(time.RFC3339, value)
-	if err == nil {
-		return t.In(loc), false
+// parseTaskDateTime はiCalendar日時プロパティをパースするます。
+// UTC（末尾Z）と TZID を考慮し、表示用タイムゾーン（Asia/Tokyo）の時刻で返すのです。
+func parseTaskDateTime(prop *ical.Prop, tz *tzResolver) (time.Time, bool) {
+	t, isDate := tz.parseProp(prop)
+	if t.IsZero() {
+		return t, isDate
 	}
-
-	return time.Time{}, false
+	return t.In(tz.display), isDate
 }
 
 // sortTasks はタスクを仕様通りにソートするます。
@@ -283,3 +270,197 @@ func sortTasks(tasks []models.TaskItem) {
 		return taskI.CreatedAt.Before(taskJ.CreatedAt)
 	})
 }
+
+// CreateTask は指定タスクリストに VTODO を新規作成するます。
+// 成功時はタスクキャッシュを破棄して、作成したタスクを返すのです。
+func (c *Client) CreateTask(ctx context.Context, req models.TaskWriteRequest) (*models.TaskItem, error) {
+	if req.Title == nil || strings.TrimSpace(*req.Title) == "" {
+		return nil, fmt.Errorf("%w: title は必須です", ErrInvalidInput)
+	}
+
+	taskListName, err := resolveCollectionName(collectionKeys(c.taskLists), req.TaskList, "タスクリスト")
+	if err != nil {
+		return nil, err
+	}
+	ref, _ := findCollectionRef(c.taskLists, taskListName)
+	taskListPath, err := ref.taskListPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := newUID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	todo := ical.NewComponent(ical.CompToDo)
+	todo.Props.SetText(ical.PropUID, uid)
+	todo.Props.SetDateTime(ical.PropCreated, now)
+	todo.Props.SetText(ical.PropStatus, "NEEDS-ACTION")
+	if err := applyTaskChanges(todo, req, now); err != nil {
+		return nil, err
+	}
+
+	cal := newCalendarObject(todo)
+	objectPath := objectPathFor(taskListPath, uid)
+	if err := ref.account.putCalendarObject(ctx, objectPath, cal, ""); err != nil {
+		return nil, fmt.Errorf("タスク作成失敗: %w", err)
+	}
+
+	c.invalidateTasksCache()
+	fmt.Printf("✅ タスク作成成功: %s (%s)\n", *req.Title, taskListName)
+
+	return firstTask(cal)
+}
+
+// UpdateTask は UID が一致する VTODO を更新するます。
+// 取得時の ETag を If-Match に付けるので、他の端末の更新と競合した場合は ErrConflict を返すのです。
+func (c *Client) UpdateTask(ctx context.Context, id string, req models.TaskWriteRequest) (*models.TaskItem, error) {
+	object, ref, err := c.findTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	for _, comp := range object.Data.Children {
+		if comp.Name != ical.CompToDo {
+			continue
+		}
+		if err := applyTaskChanges(comp, req, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ref.account.putCalendarObject(ctx, object.Path, object.Data, object.ETag); err != nil {
+		return nil, fmt.Errorf("タスク更新失敗: %w", err)
+	}
+
+	c.invalidateTasksCache()
+	fmt.Printf("✅ タスク更新成功: %s\n", id)
+
+	return firstTask(object.Data)
+}
+
+// DeleteTask は UID が一致する VTODO を削除するます。
+func (c *Client) DeleteTask(ctx context.Context, id string) error {
+	object, ref, err := c.findTask(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := ref.account.deleteCalendarObject(ctx, object.Path, object.ETag); err != nil {
+		return fmt.Errorf("タスク削除失敗: %w", err)
+	}
+
+	c.invalidateTasksCache()
+	fmt.Printf("✅ タスク削除成功: %s\n", id)
+
+	return nil
+}
+
+// findTask は設定済みの全タスクリストから UID が一致するタスクを探すます。
+func (c *Client) findTask(ctx context.Context, id string) (*caldav.CalendarObject, collectionRef, error) {
+	if strings.TrimSpace(id) == "" {
+		return nil, collectionRef{}, fmt.Errorf("%w: id は必須です", ErrInvalidInput)
+	}
+
+	for _, ref := range c.taskLists {
+		taskListPath, err := ref.taskListPath(ctx)
+		if err != nil {
+			return nil, collectionRef{}, fmt.Errorf("tasklist '%s': %w", ref.key, err)
+		}
+		object, err := ref.account.findObjectByUID(ctx, taskListPath, ical.CompToDo, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, collectionRef{}, fmt.Errorf("tasklist '%s': %w", ref.key, err)
+		}
+		return object, ref, nil
+	}
+
+	return nil, collectionRef{}, ErrNotFound
+}
+
+// invalidateTasksCache はタスクキャッシュを破棄するます。
+func (c *Client) invalidateTasksCache() {
+	if err := c.cache.Delete(TasksCacheKey); err != nil {
+		fmt.Printf("⚠️ タスクキャッシュ削除失敗: %v\n", err)
+	}
+}
+
+// applyTaskChanges はリクエストの変更内容を VTODO に反映するます。
+func applyTaskChanges(todo *ical.Component, req models.TaskWriteRequest, now time.Time) error {
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return fmt.Errorf("%w: title は空にできません", ErrInvalidInput)
+		}
+		todo.Props.SetText(ical.PropSummary, title)
+	}
+
+	if req.Notes != nil {
+		if *req.Notes == "" {
+			todo.Props.Del(ical.PropDescription)
+		} else {
+			todo.Props.SetText(ical.PropDescription, *req.Notes)
+		}
+	}
+
+	if req.Status != nil {
+		switch *req.Status {
+		case "completed":
+			todo.Props.SetText(ical.PropStatus, "COMPLETED")
+			todo.Props.SetDateTime(ical.PropCompleted, now)
+			setIntProp(todo.Props, ical.PropPercentComplete, 100)
+		case "needsAction":
+			todo.Props.SetText(ical.PropStatus, "NEEDS-ACTION")
+			todo.Props.Del(ical.PropCompleted)
+			todo.Props.Del(ical.PropPercentComplete)
+		default:
+			return fmt.Errorf("%w: status は needsAction か completed です: %s", ErrInvalidInput, *req.Status)
+		}
+	}
+
+	if req.DueDate != nil {
+		if *req.DueDate == "" {
+			todo.Props.Del(ical.PropDue)
+		} else {
+			due, err := time.Parse("2006-01-02", *req.DueDate)
+			if err != nil {
+				return fmt.Errorf("%w: dueDate は YYYY-MM-DD 形式です: %s", ErrInvalidInput, *req.DueDate)
+			}
+			todo.Props.SetDate(ical.PropDue, due)
+		}
+	}
+
+	if req.Priority != nil {
+		// 取得時の変換の逆（Google Tasks互換: 3/2/1 → iCalendar: 1=HIGH/5=MEDIUM/9=LOW）
+		icalPriority := 0
+		switch *req.Priority {
+		case 3:
+			icalPriority = 1
+		case 2:
+			icalPriority = 5
+		case 1:
+			icalPriority = 9
+		default:
+			return fmt.Errorf("%w: priority は 1〜3 です: %d", ErrInvalidInput, *req.Priority)
+		}
+		setIntProp(todo.Props, ical.PropPriority, icalPriority)
+	}
+
+	todo.Props.SetDateTime(ical.PropDateTimeStamp, now)
+	todo.Props.SetDateTime(ical.PropLastModified, now)
+	return nil
+}
+
+// firstTask は VCALENDAR から最初の VTODO を TaskItem として返すます。
+func firstTask(cal *ical.Calendar) (*models.TaskItem, error) {
+	tasks := parseTaskObject(cal)
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("タスクの変換に失敗しました")
+	}
+	return &tasks[0], nil
+}
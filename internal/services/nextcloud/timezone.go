@@ -0,0 +1,257 @@
+package nextcloud
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/teambition/rrule-go"
+)
+
+// tzResolver は iCalendar の日時プロパティを TZID を考慮して解決するのです。
+// UTC（末尾Z）→ IANA タイムゾーン名 → 埋め込み VTIMEZONE → 表示用タイムゾーン の順に判定するます。
+type tzResolver struct {
+	display    *time.Location
+	vtimezones map[string]*vtimezone
+	locations  map[string]*time.Location
+}
+
+// newTZResolver はカレンダー内の VTIMEZONE を読み込んでリゾルバを作るます。
+// display は TZID なし（フローティング）の日時と終日日付に使うタイムゾーンなのです。
+func newTZResolver(cal *ical.Calendar, display *time.Location) *tzResolver {
+	r := &tzResolver{
+		display:    display,
+		vtimezones: map[string]*vtimezone{},
+		locations:  map[string]*time.Location{},
+	}
+	if cal == nil {
+		return r
+	}
+
+	for _, comp := range cal.Children {
+		if comp.Name != ical.CompTimezone {
+			continue
+		}
+		tzid := comp.Props.Get(ical.PropTimezoneID)
+		if tzid == nil || tzid.Value == "" {
+			continue
+		}
+		if vtz := parseVTimezone(tzid.Value, comp); vtz != nil {
+			r.vtimezones[tzid.Value] = vtz
+		}
+	}
+
+	return r
+}
+
+// parseProp は日時プロパティをパースするます。
+// 戻り値の時刻は TZID のタイムゾーン（繰り返し展開用）を保持しているので、
+// 表示時は display に変換して使うのです。
+func (r *tzResolver) parseProp(prop *ical.Prop) (time.Time, bool) {
+	if prop == nil {
+		return time.Time{}, false
+	}
+	return r.parseValue(prop.Value, prop.Params.Get(ical.PropTimezoneID))
+}
+
+// parseValue は日時文字列を TZID を考慮してパースするます。
+func (r *tzResolver) parseValue(value, tzid string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+
+	// 終日・UTC・TZIDなしは TZID を参照しないのです
+	if tzid == "" || len(value) == 8 || strings.HasSuffix(value, "Z") {
+		return parseDateTime(value, r.display)
+	}
+
+	if loc := r.lookupLocation(tzid); loc != nil {
+		return parseDateTime(value, loc)
+	}
+
+	if vtz, ok := r.vtimezones[tzid]; ok {
+		wall, isAllDay := parseDateTime(value, time.UTC)
+		if wall.IsZero() {
+			return wall, isAllDay
+		}
+		return vtz.resolve(wall), isAllDay
+	}
+
+	// 解決できない TZID は表示用タイムゾーンとみなすます
+	fmt.Printf("⚠️ 未知のTZID '%s' を %s として扱うます\n", tzid, r.display)
+	return parseDateTime(value, r.display)
+}
+
+// localize は繰り返し展開後の時刻を TZID に合わせて補正するます。
+// VTIMEZONE のみで定義されたタイムゾーンは固定オフセットで展開されるため、
+// 発生ごとに夏時間などのオフセットを再計算するのです。
+func (r *tzResolver) localize(t time.Time, tzid string) time.Time {
+	if tzid == "" || r.lookupLocation(tzid) != nil {
+		return t
+	}
+	vtz, ok := r.vtimezones[tzid]
+	if !ok {
+		return t
+	}
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return vtz.resolve(wall)
+}
+
+// lookupLocation は TZID を IANA タイムゾーンとして読み込むます。
+// "/mozilla.org/20070129_1/Europe/Berlin" のような接頭辞付きの TZID にも対応するのです。
+func (r *tzResolver) lookupLocation(tzid string) *time.Location {
+	if loc, ok := r.locations[tzid]; ok {
+		return loc
+	}
+
+	var found *time.Location
+	name := strings.Trim(strings.TrimSpace(tzid), "\"")
+	segments := strings.Split(strings.Trim(name, "/"), "/")
+	for i := range segments {
+		candidate := strings.Join(segments[i:], "/")
+		if candidate == "" || candidate == "Local" {
+			continue
+		}
+		if loc, err := time.LoadLocation(candidate); err == nil {
+			found = loc
+			break
+		}
+	}
+
+	r.locations[tzid] = found
+	return found
+}
+
+// vtimezone は埋め込み VTIMEZONE の定義なのです。
+type vtimezone struct {
+	tzid        string
+	observances []tzObservance
+}
+
+// tzObservance は VTIMEZONE の STANDARD/DAYLIGHT 1件分なのです。
+// start と rdates は切り替え前のローカル時刻を UTC として保持するます。
+type tzObservance struct {
+	start    time.Time
+	offsetTo int
+	rule     *rrule.ROption
+	rdates   []time.Time
+}
+
+// parseVTimezone は VTIMEZONE コンポーネントをパースするます。
+func parseVTimezone(tzid string, comp *ical.Component) *vtimezone {
+	vtz := &vtimezone{tzid: tzid}
+
+	for _, child := range comp.Children {
+		if child.Name != ical.CompTimezoneStandard && child.Name != ical.CompTimezoneDaylight {
+			continue
+		}
+
+		offsetTo := child.Props.Get(ical.PropTimezoneOffsetTo)
+		dtStart := child.Props.Get(ical.PropDateTimeStart)
+		if offsetTo == nil || dtStart == nil {
+			continue
+		}
+		offset, ok := parseUTCOffset(offsetTo.Value)
+		if !ok {
+			continue
+		}
+		start, _ := parseDateTime(dtStart.Value, time.UTC)
+		if start.IsZero() {
+			continue
+		}
+
+		observance := tzObservance{
+			start:    start,
+			offsetTo: offset,
+			rdates:   parseDateTimeList(child.Props.Values(ical.PropRecurrenceDates), newTZResolver(nil, time.UTC)),
+		}
+		if prop := child.Props.Get(ical.PropRecurrenceRule); prop != nil {
+			if option, err := rrule.StrToROptionInLocation(prop.Value, time.UTC); err == nil {
+				option.Dtstart = start
+				observance.rule = option
+			}
+		}
+
+		vtz.observances = append(vtz.observances, observance)
+	}
+
+	if len(vtz.observances) == 0 {
+		return nil
+	}
+	return vtz
+}
+
+// resolve はローカル時刻（UTCとして保持）に対応するオフセットを決めて時刻を返すます。
+// 直近に開始した STANDARD/DAYLIGHT のオフセットを採用するのです。
+func (v *vtimezone) resolve(wall time.Time) time.Time {
+	offset := v.observances[0].offsetTo
+	var latest time.Time
+
+	for _, observance := range v.observances {
+		onset := time.Time{}
+		if !observance.start.After(wall) {
+			onset = observance.start
+		}
+		if observance.rule != nil {
+			// 1601年起点などの古い DTSTART は rrule の反復上限に届かないため、
+			// COUNT 指定がなければ月日を BYxxx に固定したうえで2年前の1月1日に起点を寄せるます
+			option := *observance.rule
+			if option.Count == 0 && option.Dtstart.Year() < wall.Year()-2 {
+				start := option.Dtstart
+				if len(option.Bymonth) == 0 {
+					option.Bymonth = []int{int(start.Month())}
+				}
+				if len(option.Byweekday) == 0 && len(option.Bymonthday) == 0 && len(option.Byyearday) == 0 {
+					option.Bymonthday = []int{start.Day()}
+				}
+				option.Dtstart = time.Date(wall.Year()-2, time.January, 1, start.Hour(), start.Minute(), start.Second(), 0, time.UTC)
+			}
+			if rule, err := rrule.NewRRule(option); err == nil {
+				if t := rule.Before(wall, true); t.After(onset) {
+					onset = t
+				}
+			}
+		}
+		for _, rdate := range observance.rdates {
+			if !rdate.After(wall) && rdate.After(onset) {
+				onset = rdate
+			}
+		}
+
+		if !onset.IsZero() && onset.After(latest) {
+			latest = onset
+			offset = observance.offsetTo
+		}
+	}
+
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0,
+		time.FixedZone(v.tzid, offset))
+}
+
+// parseUTCOffset は "+0900" や "-053000" 形式のオフセットを秒に変換するます。
+func parseUTCOffset(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) != 5 && len(value) != 7 {
+		return 0, false
+	}
+
+	sign := 1
+	switch value[0] {
+	case '+':
+	case '-':
+		sign = -1
+	default:
+		return 0, false
+	}
+
+	var hours, minutes, seconds int
+	if _, err := fmt.Sscanf(value[1:5], "%02d%02d", &hours, &minutes); err != nil {
+		return 0, false
+	}
+	if len(value) == 7 {
+		if _, err := fmt.Sscanf(value[5:7], "%02d", &seconds); err != nil {
+			return 0, false
+		}
+	}
+
+	return sign * (hours*3600 + minutes*60 + seconds), true
+}
@@ -0,0 +1,206 @@
+package nextcloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/caldav"
+)
+
+var (
+	// ErrNotFound は更新・削除対象のオブジェクトが見つからない場合のエラーなのです。
+	ErrNotFound = errors.New("対象が見つかりません")
+	// ErrConflict は ETag 不一致（412）で他の端末の更新と競合した場合のエラーなのです。
+	ErrConflict = errors.New("他の端末で更新されています。再読み込みしてください")
+	// ErrInvalidInput は書き込みリクエストの入力値が不正な場合のエラーなのです。
+	ErrInvalidInput = errors.New("入力値が不正です")
+)
+
+// prodID は書き込む iCalendar の PRODID なのです。
+const prodID = "-//FamilyDashboard//FamilyDashboard//JA"
+
+// findObjectByUID はコレクション内から UID が一致するオブジェクトを探すます。
+// 見つからない場合は ErrNotFound を返すのです。
+func (a *account) findObjectByUID(ctx context.Context, collectionPath, compName, uid string) (*caldav.CalendarObject, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<cal:calendar-query xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+	<d:prop>
+		<d:getetag/>
+		<cal:calendar-data/>
+	</d:prop>
+	<cal:filter>
+		<cal:comp-filter name="VCALENDAR">
+			<cal:comp-filter name="` + compName + `">
+				<cal:prop-filter name="UID">
+					<cal:text-match>`)
+	xml.EscapeText(&body, []byte(uid))
+	body.WriteString(`</cal:text-match>
+				</cal:prop-filter>
+			</cal:comp-filter>
+		</cal:comp-filter>
+	</cal:filter>
+</cal:calendar-query>`)
+
+	ms, err := a.davMultiStatus(ctx, "REPORT", collectionPath, "1", body.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("UID検索失敗: %w", err)
+	}
+
+	// text-match は部分一致なので、UID の完全一致を確認するます
+	for _, response := range ms.Responses {
+		href, ok := a.memberHref(collectionPath, response.Href)
+		if !ok || response.calendarData() == "" {
+			continue
+		}
+		cal, err := ical.NewDecoder(strings.NewReader(response.calendarData())).Decode()
+		if err != nil {
+			continue
+		}
+		etag, _ := response.etag()
+		for _, comp := range cal.Children {
+			if comp.Name != compName {
+				continue
+			}
+			if prop := comp.Props.Get(ical.PropUID); prop != nil && prop.Value == uid {
+				return &caldav.CalendarObject{Path: href, ETag: etag, Data: cal}, nil
+			}
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// putCalendarObject は iCalendar オブジェクトを PUT するます。
+// etag が空の場合は新規作成（If-None-Match: *）、指定時は If-Match で競合を検出するのです。
+func (a *account) putCalendarObject(ctx context.Context, objectPath string, cal *ical.Calendar, etag string) error {
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return fmt.Errorf("iCalendarエンコード失敗: %w", err)
+	}
+
+	targetURL, err := a.resolveDAVURL(objectPath)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, targetURL, &buf)
+	if err != nil {
+		return fmt.Errorf("PUTリクエスト作成失敗: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	if etag == "" {
+		req.Header.Set("If-None-Match", "*")
+	} else {
+		req.Header.Set("If-Match", quoteETag(etag))
+	}
+
+	return a.doWriteRequest(req)
+}
+
+// deleteCalendarObject は iCalendar オブジェクトを DELETE するます。
+// etag を指定すると If-Match で競合を検出するのです。
+func (a *account) deleteCalendarObject(ctx context.Context, objectPath, etag string) error {
+	targetURL, err := a.resolveDAVURL(objectPath)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, targetURL, nil)
+	if err != nil {
+		return fmt.Errorf("DELETEリクエスト作成失敗: %w", err)
+	}
+	if etag != "" {
+		req.Header.Set("If-Match", quoteETag(etag))
+	}
+
+	return a.doWriteRequest(req)
+}
+
+// doWriteRequest は書き込みリクエストを送信し、ステータスコードをエラーに変換するます。
+func (a *account) doWriteRequest(req *http.Request) error {
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s リクエスト失敗: %w", req.Method, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return ErrConflict
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s HTTPエラー: code=%d, body=%s", req.Method, resp.StatusCode, string(body))
+	}
+}
+
+// quoteETag は If-Match 用に ETag を引用符で囲むます。
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, "\"") || strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return "\"" + etag + "\""
+}
+
+// newCalendarObject は1つのコンポーネントを含む VCALENDAR を作るます。
+func newCalendarObject(comp *ical.Component) *ical.Calendar {
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, prodID)
+	cal.Children = append(cal.Children, comp)
+	return cal
+}
+
+// resolveCollectionName は書き込み先のカレンダー／タスクリスト名を決めるます。
+// 省略時は先頭のもの、指定時は設定済みの名前であることを確認するのです。
+func resolveCollectionName(names []string, name, label string) (string, error) {
+	if len(names) == 0 {
+		return "", fmt.Errorf("%s名が設定されていません", label)
+	}
+	if name == "" {
+		return names[0], nil
+	}
+	for _, candidate := range names {
+		if candidate == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s '%s' は設定されていません", ErrInvalidInput, label, name)
+}
+
+// setIntProp は INTEGER 型のプロパティを設定するます。
+// Props.SetText だと既定型が INTEGER のプロパティに VALUE=TEXT が付いてしまうのです。
+func setIntProp(props ical.Props, name string, value int) {
+	prop := ical.NewProp(name)
+	prop.Value = strconv.Itoa(value)
+	props.Set(prop)
+}
+
+// objectPathFor はコレクション内の新規オブジェクトのパスを返すます。
+func objectPathFor(collectionPath, uid string) string {
+	return strings.TrimSuffix(collectionPath, "/") + "/" + uid + ".ics"
+}
+
+// newUID は UUID v4 形式の UID を生成するます。
+func newUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("UID生成失敗: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,228 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/config"
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// アドバイスの重要度なのです。
+const (
+	AdviceLevelInfo    = "info"    // おすすめ
+	AdviceLevelWarning = "warning" // 注意
+)
+
+// adviceSlot は時間ごとの予報1件と、その予報が表す時間帯なのです。
+type adviceSlot struct {
+	start, end time.Time
+	hour       models.HourlyWeather
+}
+
+// adviceRule はルール1件の判定なのです。当てはまる場合は ok=true なのです。
+type adviceRule func(weatherRsp *models.WeatherResponse, slots []adviceSlot, rules config.Advice, loc *Localizer, now time.Time) (models.Advice, bool)
+
+// adviceRules はルール名ごとの判定なのです（表示順は config.AdviceRules）。
+var adviceRules = map[string]adviceRule{
+	config.AdviceUmbrella: umbrellaAdvice,
+	config.AdviceLaundry:  laundryAdvice,
+	config.AdviceHeat:     heatAdvice,
+	config.AdviceCold:     coldAdvice,
+	config.AdviceUV:       uvAdvice,
+	config.AdviceWind:     windAdvice,
+}
+
+// BuildAdvice は天気と判定条件から今日のアドバイスを作るます。
+// 時間ごとの予報は現在の時間帯から今日の終わりまでを見るのです（過ぎた時間帯は見ないのです）。
+// 無効にしたルールは判定せず、結果は config.AdviceRules の順に並ぶます。
+// weatherRsp は変換前（℃・m/s・mm）の値で判定し、文言は loc の表示言語と単位で作るのです（nil なら ja-hiragana・metric）。
+func BuildAdvice(weatherRsp *models.WeatherResponse, rules config.Advice, loc *Localizer, now time.Time) []models.Advice {
+	if loc == nil {
+		loc = defaultLocalizer()
+	}
+	now = now.In(tokyoLocation())
+	slots := todaySlots(weatherRsp.Hourly, now)
+
+	advice := []models.Advice{}
+	for _, name := range config.AdviceRules {
+		if !rules.RuleEnabled(name) {
+			continue
+		}
+		if item, ok := adviceRules[name](weatherRsp, slots, rules, loc, now); ok {
+			advice = append(advice, item)
+		}
+	}
+	return advice
+}
+
+// umbrellaAdvice は通勤・通学の時間帯に降水確率が高ければ傘をすすめるます。
+func umbrellaAdvice(_ *models.WeatherResponse, slots []adviceSlot, rules config.Advice, loc *Localizer, now time.Time) (models.Advice, bool) {
+	threshold := rules.GetUmbrellaPrecipProb()
+	found := false
+	var wettest adviceSlot
+	for _, window := range rules.GetCommuteHours() {
+		for _, slot := range slotsInWindow(slots, window, now) {
+			if slot.hour.PrecipProb >= threshold && (!found || slot.hour.PrecipProb > wettest.hour.PrecipProb) {
+				wettest = slot
+				found = true
+			}
+		}
+	}
+	if !found {
+		return models.Advice{}, false
+	}
+	return models.Advice{
+		Type:    config.AdviceUmbrella,
+		Message: loc.catalog.advice[config.AdviceUmbrella],
+		Reason:  fmt.Sprintf(loc.catalog.reasonPrecipProb, wettest.start.Hour(), wettest.hour.PrecipProb),
+		Level:   AdviceLevelInfo,
+	}, true
+}
+
+// laundryAdvice は洗濯物を干す時間帯に雨が降りそうなら部屋干しをすすめるます。
+func laundryAdvice(_ *models.WeatherResponse, slots []adviceSlot, rules config.Advice, loc *Localizer, now time.Time) (models.Advice, bool) {
+	threshold := rules.GetLaundryPrecipProb()
+	for _, slot := range slotsInWindow(slots, rules.GetLaundryHours(), now) {
+		reason := ""
+		switch {
+		case slot.hour.PrecipAmount > 0:
+			reason = fmt.Sprintf(loc.catalog.reasonPrecipAmount, slot.start.Hour(), loc.formatPrecipitation(slot.hour.PrecipAmount))
+		case slot.hour.PrecipProb >= threshold:
+			reason = fmt.Sprintf(loc.catalog.reasonPrecipProb, slot.start.Hour(), slot.hour.PrecipProb)
+		default:
+			continue
+		}
+		return models.Advice{
+			Type:    config.AdviceLaundry,
+			Message: loc.catalog.advice[config.AdviceLaundry],
+			Reason:  reason,
+			Level:   AdviceLevelInfo,
+		}, true
+	}
+	return models.Advice{}, false
+}
+
+// heatAdvice は最高気温か体感温度が高ければ熱中症に注意をうながすます。
+func heatAdvice(weatherRsp *models.WeatherResponse, slots []adviceSlot, rules config.Advice, loc *Localizer, _ time.Time) (models.Advice, bool) {
+	threshold := rules.GetHeatTemp()
+	maxTemp := weatherRsp.Today.MaxTemp
+	for _, slot := range slots {
+		maxTemp = math.Max(maxTemp, slot.hour.Temperature)
+	}
+
+	reason := ""
+	switch {
+	case maxTemp >= threshold:
+		reason = fmt.Sprintf(loc.catalog.reasonMaxTemp, loc.formatTemperature(maxTemp))
+	case weatherRsp.Current.FeelsLike >= threshold:
+		reason = fmt.Sprintf(loc.catalog.reasonFeelsLike, loc.formatTemperature(weatherRsp.Current.FeelsLike))
+	default:
+		return models.Advice{}, false
+	}
+	return models.Advice{
+		Type:    config.AdviceHeat,
+		Message: loc.catalog.advice[config.AdviceHeat],
+		Reason:  reason,
+		Level:   AdviceLevelWarning,
+	}, true
+}
+
+// coldAdvice は最低気温が低ければ上着をすすめるます。
+func coldAdvice(weatherRsp *models.WeatherResponse, slots []adviceSlot, rules config.Advice, loc *Localizer, _ time.Time) (models.Advice, bool) {
+	minTemp := weatherRsp.Today.MinTemp
+	for _, slot := range slots {
+		minTemp = math.Min(minTemp, slot.hour.Temperature)
+	}
+	if minTemp > rules.GetColdTemp() {
+		return models.Advice{}, false
+	}
+	return models.Advice{
+		Type:    config.AdviceCold,
+		Message: loc.catalog.advice[config.AdviceCold],
+		Reason:  fmt.Sprintf(loc.catalog.reasonMinTemp, loc.formatTemperature(minTemp)),
+		Level:   AdviceLevelInfo,
+	}, true
+}
+
+// uvAdvice は今日の最大UVインデックスが高ければ日焼け止めをすすめるます。
+// UVインデックスを提供しないプロバイダ（0）では判定しないのです。
+func uvAdvice(weatherRsp *models.WeatherResponse, _ []adviceSlot, rules config.Advice, loc *Localizer, _ time.Time) (models.Advice, bool) {
+	uvIndex := math.Max(weatherRsp.Today.UVIndexMax, weatherRsp.Current.UVIndex)
+	if uvIndex == 0 || uvIndex < rules.GetUVIndex() {
+		return models.Advice{}, false
+	}
+	return models.Advice{
+		Type:    config.AdviceUV,
+		Message: loc.catalog.advice[config.AdviceUV],
+		Reason:  fmt.Sprintf(loc.catalog.reasonUVIndex, uvIndex),
+		Level:   AdviceLevelInfo,
+	}, true
+}
+
+// windAdvice は今の風速か今日のこれからの風速が強ければ強風に注意をうながすます。
+func windAdvice(weatherRsp *models.WeatherResponse, slots []adviceSlot, rules config.Advice, loc *Localizer, _ time.Time) (models.Advice, bool) {
+	windSpeed := weatherRsp.Current.WindSpeed
+	for _, slot := range slots {
+		windSpeed = math.Max(windSpeed, slot.hour.WindSpeed)
+	}
+	if windSpeed < rules.GetWindSpeed() {
+		return models.Advice{}, false
+	}
+	return models.Advice{
+		Type:    config.AdviceWind,
+		Message: loc.catalog.advice[config.AdviceWind],
+		Reason:  fmt.Sprintf(loc.catalog.reasonWindSpeed, loc.formatWindSpeed(windSpeed)),
+		Level:   AdviceLevelWarning,
+	}, true
+}
+
+// todaySlots は時間ごとの予報から、現在の時間帯から今日の終わりまでの予報を取り出すます。
+// 予報の時間帯は次の予報の時刻まで（OpenWeatherMap なら3時間、最後の1件は1時間）とするのです。
+func todaySlots(hourly []models.HourlyWeather, now time.Time) []adviceSlot {
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+
+	slots := []adviceSlot{}
+	for _, hour := range hourly {
+		t, err := time.Parse(time.RFC3339, hour.Time)
+		if err != nil {
+			continue
+		}
+		slots = append(slots, adviceSlot{start: t.In(now.Location()), hour: hour})
+	}
+	for i := range slots {
+		if i+1 < len(slots) {
+			slots[i].end = slots[i+1].start
+		} else {
+			slots[i].end = slots[i].start.Add(time.Hour)
+		}
+	}
+
+	result := []adviceSlot{}
+	for _, slot := range slots {
+		if slot.end.After(now) && slot.start.Before(endOfDay) {
+			result = append(result, slot)
+		}
+	}
+	return result
+}
+
+// slotsInWindow は今日の時間帯 window と重なる予報を返すます。
+func slotsInWindow(slots []adviceSlot, window config.TimeWindow, now time.Time) []adviceSlot {
+	startMin, endMin, err := window.Minutes()
+	if err != nil {
+		return nil
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	windowStart := midnight.Add(time.Duration(startMin) * time.Minute)
+	windowEnd := midnight.Add(time.Duration(endMin) * time.Minute)
+
+	result := []adviceSlot{}
+	for _, slot := range slots {
+		if slot.start.Before(windowEnd) && slot.end.After(windowStart) {
+			result = append(result, slot)
+		}
+	}
+	return result
+}
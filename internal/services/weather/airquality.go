@@ -0,0 +1,168 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// defaultAirQualityBaseURL は Open-Meteo Air Quality API のベースURLなのです。
+const defaultAirQualityBaseURL = "https://air-quality-api.open-meteo.com/v1"
+
+// defaultAirQualityTTL は大気質・花粉のキャッシュ有効期限の既定値なのです。
+// Open-Meteo の大気質データは1時間ごとなのです。
+const defaultAirQualityTTL = time.Hour
+
+// pollenTypes は Open-Meteo の花粉の種類なのです（表示順）。
+// 花粉は CAMS のヨーロッパ域のみで、日本ではスギ・ヒノキを含めて提供されないのです。
+var pollenTypes = []string{"grass", "birch", "alder", "mugwort", "ragweed", "olive"}
+
+// AirQualityCacheKey は大気質・花粉のキャッシュキーを返すます。
+// 天気とは別のキーにして、更新間隔も別にするのです。
+func AirQualityCacheKey(cityName, country string) string {
+	return fmt.Sprintf("air_quality:%s:%s", country, cityName)
+}
+
+// OpenMeteoAirQualityResponse は Open-Meteo Air Quality API のレスポンスなのです。
+type OpenMeteoAirQualityResponse struct {
+	Current struct {
+		Time          string   `json:"time"` // Asia/Tokyo（"2006-01-02T15:04"）
+		PM10          float64  `json:"pm10"`
+		PM25          float64  `json:"pm2_5"`
+		USAQI         float64  `json:"us_aqi"`
+		AlderPollen   *float64 `json:"alder_pollen"` // 花粉（個/m³、提供されない地域では null）
+		BirchPollen   *float64 `json:"birch_pollen"`
+		GrassPollen   *float64 `json:"grass_pollen"`
+		MugwortPollen *float64 `json:"mugwort_pollen"`
+		OlivePollen   *float64 `json:"olive_pollen"`
+		RagweedPollen *float64 `json:"ragweed_pollen"`
+	} `json:"current"`
+}
+
+// GetAirQuality は指定都市の大気質・花粉を取得するます。
+// キャッシュが有効な場合はそれを返し、無効な場合は Open-Meteo から取得して保存するます。
+func (c *Client) GetAirQuality(ctx context.Context, cityName, country string) (*models.AirQuality, error) {
+	var cached models.AirQuality
+	_, found, stale, err := c.fc.ReadPayload(AirQualityCacheKey(cityName, country), c.airQualityTTL, &cached)
+	if found && err == nil && !stale {
+		return &cached, nil
+	}
+
+	return c.RefreshAirQuality(ctx, cityName, country)
+}
+
+// RefreshAirQuality はキャッシュを見ずに Open-Meteo から大気質・花粉を取得してキャッシュを更新するます。
+// 取得に失敗した場合は期限切れのキャッシュがあればエラーと一緒に返すのです。
+func (c *Client) RefreshAirQuality(ctx context.Context, cityName, country string) (*models.AirQuality, error) {
+	cacheKey := AirQualityCacheKey(cityName, country)
+
+	airQuality, err := c.fetchAirQuality(ctx, cityName, country)
+	if err != nil {
+		var cached models.AirQuality
+		if _, found, _, readErr := c.fc.ReadPayload(cacheKey, 0, &cached); found && readErr == nil {
+			return &cached, err
+		}
+		return nil, err
+	}
+
+	_, _ = c.fc.Write(cacheKey, airQuality, map[string]string{
+		"city":    cityName,
+		"country": country,
+		"source":  "openmeteo-air-quality",
+	})
+	return airQuality, nil
+}
+
+// fetchAirQuality は座標を解決して Open-Meteo Air Quality API から取得するます。
+func (c *Client) fetchAirQuality(ctx context.Context, cityName, country string) (*models.AirQuality, error) {
+	coords, err := c.getCoordinates(ctx, cityName, country)
+	if err != nil {
+		return nil, fmt.Errorf("緯度経度取得失敗するます: %w", err)
+	}
+
+	requestURL := fmt.Sprintf(
+		"%s/air-quality?latitude=%.2f&longitude=%.2f&current=pm10,pm2_5,us_aqi,alder_pollen,birch_pollen,grass_pollen,mugwort_pollen,olive_pollen,ragweed_pollen&timezone=Asia/Tokyo",
+		c.airQualityURL, coords.Latitude, coords.Longitude,
+	)
+
+	var aqResp OpenMeteoAirQualityResponse
+	if err := getJSON(ctx, c.airQualityHTTP, requestURL, "Open-Meteo Air Quality", &aqResp); err != nil {
+		return nil, err
+	}
+	return convertAirQuality(&aqResp, tokyoLocation()), nil
+}
+
+// convertAirQuality は Open-Meteo Air Quality のレスポンスを models.AirQuality に変換するます。
+// 値が null の花粉（提供されない地域・季節）は含めないのです。
+func convertAirQuality(aqResp *OpenMeteoAirQualityResponse, loc *time.Location) *models.AirQuality {
+	current := aqResp.Current
+	aqi := int(math.Round(current.USAQI))
+	airQuality := &models.AirQuality{
+		PM25:     math.Round(current.PM25*10) / 10,
+		PM10:     math.Round(current.PM10*10) / 10,
+		AQI:      aqi,
+		Category: aqiCategory(aqi),
+		Pollen:   []models.PollenLevel{},
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", current.Time, loc); err == nil {
+		airQuality.Time = t.Format(time.RFC3339)
+	}
+
+	values := map[string]*float64{
+		"grass":   current.GrassPollen,
+		"birch":   current.BirchPollen,
+		"alder":   current.AlderPollen,
+		"mugwort": current.MugwortPollen,
+		"ragweed": current.RagweedPollen,
+		"olive":   current.OlivePollen,
+	}
+	for _, pollenType := range pollenTypes {
+		value := values[pollenType]
+		if value == nil {
+			continue
+		}
+		airQuality.Pollen = append(airQuality.Pollen, models.PollenLevel{
+			Type:  pollenType,
+			Name:  canonicalCatalog.pollenNames[pollenType],
+			Value: math.Round(*value*10) / 10,
+			Level: pollenLevel(*value),
+		})
+	}
+	return airQuality
+}
+
+// aqiCategory は米国 AQI を区分（ja-hiragana）に変換するます（米国環境保護庁の区分）。
+func aqiCategory(aqi int) string {
+	level := 5
+	switch {
+	case aqi <= 50:
+		level = 0
+	case aqi <= 100:
+		level = 1
+	case aqi <= 150:
+		level = 2
+	case aqi <= 200:
+		level = 3
+	case aqi <= 300:
+		level = 4
+	}
+	return canonicalCatalog.aqiCategories[level]
+}
+
+// pollenLevel は花粉の飛散量（個/m³）を区分（ja-hiragana）に変換するます。
+// 環境省の花粉観測の区分（少ない 0〜9 / やや多い 10〜29 / 多い 30〜49 / 非常に多い 50〜）に合わせるのです。
+func pollenLevel(value float64) string {
+	level := 3
+	switch {
+	case value < 10:
+		level = 0
+	case value < 30:
+		level = 1
+	case value < 50:
+		level = 2
+	}
+	return canonicalCatalog.pollenLevels[level]
+}
@@ -0,0 +1,125 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/config"
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+const (
+	// defaultAttemptTimeout はプロバイダ1件あたりの取得タイムアウトなのです。
+	// 超えたら次のプロバイダに切り替えるます。
+	defaultAttemptTimeout = 10 * time.Second
+	// defaultBreakerThreshold は連続で何回失敗したらプロバイダを休ませるかなのです。
+	defaultBreakerThreshold = 3
+	// defaultBreakerCooldown はプロバイダを休ませる時間なのです。
+	// 休み明けの1回で失敗したら、また同じ時間だけ休ませるます。
+	defaultBreakerCooldown = 10 * time.Minute
+)
+
+// providerState はプロバイダ1件とサーキットブレーカーの状態なのです。
+type providerState struct {
+	provider      Provider
+	failures      int       // 連続失敗回数（成功で0に戻る）
+	cooldownUntil time.Time // この時刻までは試さないのです
+}
+
+// NewProviders は設定の順番どおりに天気プロバイダを作成するます。
+// 1件でも設定が不正ならエラーを返すのです。
+func NewProviders(cfg config.Weather) ([]Provider, error) {
+	providers := []Provider{}
+	for i, providerCfg := range cfg.GetProviders() {
+		provider, err := NewProvider(providerCfg)
+		if err != nil {
+			return nil, fmt.Errorf("weather.providers[%d]: %w", i, err)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// SetProviders は天気データの取得元を試す順番で設定するます。
+// NewProviders(cfg.Weather) で作ったプロバイダを渡すのです。
+func (c *Client) SetProviders(providers ...Provider) {
+	states := make([]*providerState, 0, len(providers))
+	for _, provider := range providers {
+		states = append(states, &providerState{provider: provider})
+	}
+
+	c.breakerMu.Lock()
+	defer c.breakerMu.Unlock()
+	c.providers = states
+}
+
+// fetchWithFailover はプロバイダを順番に試して、最初に成功した結果とプロバイダ名を返すます。
+// 失敗・タイムアウトしたら次のプロバイダに切り替え、休み中のプロバイダは飛ばすのです。
+func (c *Client) fetchWithFailover(ctx context.Context, lat, lon float64, cityName string) (*models.WeatherResponse, string, error) {
+	c.breakerMu.Lock()
+	states := c.providers
+	c.breakerMu.Unlock()
+
+	if len(states) == 0 {
+		return nil, "", fmt.Errorf("天気プロバイダが設定されていません")
+	}
+
+	failures := []string{}
+	for _, state := range states {
+		name := state.provider.Name()
+		if until, resting := c.cooldown(state); resting {
+			failures = append(failures, fmt.Sprintf("%s: 休止中（%s まで）", name, until.In(tokyoLocation()).Format("15:04:05")))
+			continue
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, c.attemptTimeout)
+		weatherRsp, err := state.provider.Fetch(attemptCtx, lat, lon, cityName)
+		cancel()
+		if err == nil {
+			c.recordSuccess(state)
+			return weatherRsp, name, nil
+		}
+
+		// 呼び出し元のキャンセル（シャットダウンなど）はプロバイダの失敗として数えないのです
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+
+		c.recordFailure(state)
+		fmt.Printf("⚠️ 天気プロバイダ %s で取得失敗、次のプロバイダを試すます: %v\n", name, err)
+		failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+	}
+
+	return nil, "", fmt.Errorf("すべての天気プロバイダで取得失敗するます: %s", strings.Join(failures, " / "))
+}
+
+// cooldown はプロバイダが休み中かを返すます。
+func (c *Client) cooldown(state *providerState) (time.Time, bool) {
+	c.breakerMu.Lock()
+	defer c.breakerMu.Unlock()
+	return state.cooldownUntil, c.now().Before(state.cooldownUntil)
+}
+
+// recordSuccess は成功したプロバイダのブレーカーを戻すます。
+func (c *Client) recordSuccess(state *providerState) {
+	c.breakerMu.Lock()
+	defer c.breakerMu.Unlock()
+	if state.failures >= c.breakerThreshold {
+		fmt.Printf("✨ 天気プロバイダ %s が復旧しました\n", state.provider.Name())
+	}
+	state.failures = 0
+	state.cooldownUntil = time.Time{}
+}
+
+// recordFailure は失敗を数えて、しきい値に達したプロバイダを休ませるます。
+func (c *Client) recordFailure(state *providerState) {
+	c.breakerMu.Lock()
+	defer c.breakerMu.Unlock()
+	state.failures++
+	if state.failures >= c.breakerThreshold {
+		state.cooldownUntil = c.now().Add(c.breakerCooldown)
+		fmt.Printf("⏸️ 天気プロバイダ %s を %v 休ませるます（%d 回連続失敗）\n", state.provider.Name(), c.breakerCooldown, state.failures)
+	}
+}
@@ -0,0 +1,321 @@
+package weather
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/cache"
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// historyPruneInterval は古い記録を削除する間隔なのです。
+// 追記のたびにファイルを書き直さないように、1日に1回だけ削除するます。
+const historyPruneInterval = 24 * time.Hour
+
+// Observation は天気の観測値1件（取得に成功した時点の現在の天気）なのです。
+// 履歴ファイルに1行1件の JSON で追記するのです。
+type Observation struct {
+	Time         string  `json:"time"`         // 取得時刻（RFC3339）
+	Temperature  float64 `json:"temperature"`  // 気温（℃）
+	Humidity     int     `json:"humidity"`     // 湿度（%）
+	Condition    string  `json:"condition"`    // 天候
+	PrecipHour   string  `json:"precipHour"`   // 降水量の時間帯の開始時刻（RFC3339、不明は空）
+	PrecipAmount float64 `json:"precipAmount"` // その時間帯の降水量（mm）
+	Source       string  `json:"source"`       // 天気データを提供したプロバイダ
+}
+
+// HistoryStore は地点ごとの天気の観測値を data/ 配下のファイルに記録する時系列ストアなのです。
+// キャッシュは最新の1件だけなので、「昨日より暑い？」に答えるために別に残すます。
+// 記録は retentionDays 日より古くなったら削除するのです。
+type HistoryStore struct {
+	dir       string
+	retention time.Duration
+	now       func() time.Time // 時計（テストで差し替えるため）
+
+	mu         sync.Mutex
+	lastPruned map[string]time.Time // ファイルごとの最後に古い記録を削除した時刻
+}
+
+// NewHistoryStore は天気の履歴ストアを作成するます。
+// dir は保存先（例: ./data/history）、retentionDays は記録を残す日数なのです。
+func NewHistoryStore(dir string, retentionDays int) *HistoryStore {
+	return &HistoryStore{
+		dir:        dir,
+		retention:  time.Duration(retentionDays) * 24 * time.Hour,
+		now:        time.Now,
+		lastPruned: map[string]time.Time{},
+	}
+}
+
+// RetentionDays は記録を残す日数を返すます。
+func (s *HistoryStore) RetentionDays() int {
+	return int(s.retention / (24 * time.Hour))
+}
+
+// HistoryKey は地点の履歴のキーを返すます（ファイル名はこのキーから作るのです）。
+func HistoryKey(cityName, country string) string {
+	return fmt.Sprintf("weather_history:%s:%s", country, cityName)
+}
+
+// Append は観測値を地点の履歴に追記するます。
+// 前回の削除から1日以上たっていたら、保持期間より古い記録を削除するのです。
+func (s *HistoryStore) Append(cityName, country string, obs Observation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(obs)
+	if err != nil {
+		return err
+	}
+
+	path := s.filePath(cityName, country)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	now := s.now()
+	if now.Sub(s.lastPruned[path]) >= historyPruneInterval {
+		if err := s.prune(path, now.Add(-s.retention)); err != nil {
+			return fmt.Errorf("古い履歴の削除失敗するます: %w", err)
+		}
+		s.lastPruned[path] = now
+	}
+	return nil
+}
+
+// Observations は地点の履歴から since 以降の観測値を古い順に返すます。
+// 履歴が無い場合は空なのです。
+func (s *HistoryStore) Observations(cityName, country string, since time.Time) ([]Observation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	observations, err := readObservations(s.filePath(cityName, country))
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Observation{}
+	for _, obs := range observations {
+		t, err := time.Parse(time.RFC3339, obs.Time)
+		if err != nil || t.Before(since) {
+			continue
+		}
+		result = append(result, obs)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time < result[j].Time })
+	return result, nil
+}
+
+// Daily は地点の履歴を日ごと（Asia/Tokyo）に集計して、今日を含む直近 days 日分を古い順に返すます。
+// 記録の無い日は含まないのです。
+func (s *HistoryStore) Daily(cityName, country string, days int) ([]models.WeatherHistoryDay, error) {
+	now := s.now().In(tokyoLocation())
+	since := time.Date(now.Year(), now.Month(), now.Day()-(days-1), 0, 0, 0, 0, now.Location())
+	observations, err := s.Observations(cityName, country, since)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeObservations(observations, now.Location()), nil
+}
+
+// prune は before より古い記録を削除してファイルを書き直すます。
+// 書き直しは一時ファイルに書いてから置き換えるので、途中で止まっても履歴は壊れないのです。
+func (s *HistoryStore) prune(path string, before time.Time) error {
+	observations, err := readObservations(path)
+	if err != nil {
+		return err
+	}
+
+	kept := make([]Observation, 0, len(observations))
+	for _, obs := range observations {
+		if t, err := time.Parse(time.RFC3339, obs.Time); err == nil && !t.Before(before) {
+			kept = append(kept, obs)
+		}
+	}
+	if len(kept) == len(observations) {
+		return nil
+	}
+
+	tmpFile, err := os.CreateTemp(s.dir, filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmpFile)
+	encoder := json.NewEncoder(writer)
+	for _, obs := range kept {
+		if err := encoder.Encode(obs); err != nil {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpFile.Name())
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+
+	fmt.Printf("🧹 天気の履歴から古い記録を %d 件削除しました: %s\n", len(observations)-len(kept), filepath.Base(path))
+	return nil
+}
+
+func (s *HistoryStore) filePath(cityName, country string) string {
+	return filepath.Join(s.dir, cache.SafeFileName(HistoryKey(cityName, country))+".jsonl")
+}
+
+// readObservations は履歴ファイルを読み込むます（ファイルが無ければ空）。
+// 書き込み途中で壊れた行は読み飛ばすのです。
+func readObservations(path string) ([]Observation, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Observation{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	observations := []Observation{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var obs Observation
+		if err := json.Unmarshal(scanner.Bytes(), &obs); err != nil {
+			continue
+		}
+		observations = append(observations, obs)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return observations, nil
+}
+
+// observationFrom は取得した天気から記録する観測値を作るます。
+// 降水量は現在の時刻を含む時間ごとの予報の値なのです（同じ時間帯は集計時に最後の記録を使うのです）。
+func observationFrom(weatherRsp *models.WeatherResponse, source string, now time.Time) Observation {
+	obs := Observation{
+		Time:        now.In(tokyoLocation()).Format(time.RFC3339),
+		Temperature: weatherRsp.Current.Temperature,
+		Humidity:    weatherRsp.Current.Humidity,
+		Condition:   weatherRsp.Current.Condition,
+		Source:      source,
+	}
+	for _, hour := range weatherRsp.Hourly {
+		t, err := time.Parse(time.RFC3339, hour.Time)
+		if err != nil || t.After(now) {
+			break
+		}
+		obs.PrecipHour = hour.Time
+		obs.PrecipAmount = hour.PrecipAmount
+	}
+	return obs
+}
+
+// summarizeObservations は観測値を日ごとに集計するます（古い順）。
+func summarizeObservations(observations []Observation, loc *time.Location) []models.WeatherHistoryDay {
+	days := map[string]*models.WeatherHistoryDay{}
+	precip := map[string]map[string]float64{} // 日付 -> 時間帯 -> 降水量（最後の記録）
+	for _, obs := range observations {
+		t, err := time.Parse(time.RFC3339, obs.Time)
+		if err != nil {
+			continue
+		}
+		date := t.In(loc).Format("2006-01-02")
+
+		day, ok := days[date]
+		if !ok {
+			day = &models.WeatherHistoryDay{Date: date, MaxTemp: obs.Temperature, MinTemp: obs.Temperature}
+			days[date] = day
+			precip[date] = map[string]float64{}
+		}
+		day.MaxTemp = math.Max(day.MaxTemp, obs.Temperature)
+		day.MinTemp = math.Min(day.MinTemp, obs.Temperature)
+		day.Samples++
+		if obs.PrecipHour != "" {
+			precip[date][obs.PrecipHour] = obs.PrecipAmount
+		}
+	}
+
+	dates := make([]string, 0, len(days))
+	for date := range days {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	result := []models.WeatherHistoryDay{}
+	for _, date := range dates {
+		day := days[date]
+		total := 0.0
+		for _, amount := range precip[date] {
+			total += amount
+		}
+		day.Precipitation = math.Round(total*10) / 10
+		result = append(result, *day)
+	}
+	return result
+}
+
+// CompareWithYesterday は今日の予報と昨日の記録の気温を比べるます。
+// history と today は変換前（℃）の値で、差と文言は loc の単位と表示言語にするのです（nil なら ja-hiragana・metric）。
+// 昨日の記録が無い場合は nil なのです。
+func CompareWithYesterday(history []models.WeatherHistoryDay, today models.TodayWeather, loc *Localizer, now time.Time) *models.WeatherTrend {
+	if loc == nil {
+		loc = defaultLocalizer()
+	}
+	now = now.In(tokyoLocation())
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
+	for _, day := range history {
+		if day.Date != yesterday {
+			continue
+		}
+		maxDelta := loc.temperatureDelta(round1(today.MaxTemp - day.MaxTemp))
+		return &models.WeatherTrend{
+			MaxTempDelta: maxDelta,
+			MinTempDelta: loc.temperatureDelta(round1(today.MinTemp - day.MinTemp)),
+			Message:      trendMessage(maxDelta, loc),
+		}
+	}
+	return nil
+}
+
+// trendMessage は最高気温の差（表示する単位）を表示用の文にするます（1度未満は「同じくらい」）。
+func trendMessage(delta float64, loc *Localizer) string {
+	rounded := math.Round(delta)
+	unit := loc.Units().Temperature
+	switch {
+	case rounded >= 1:
+		return fmt.Sprintf(loc.catalog.trendWarmer, fmt.Sprintf("%.0f%s", rounded, unit))
+	case rounded <= -1:
+		return fmt.Sprintf(loc.catalog.trendCooler, fmt.Sprintf("%.0f%s", -rounded, unit))
+	default:
+		return loc.catalog.trendSame
+	}
+}
@@ -0,0 +1,422 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rihow/FamilyDashboard/internal/config"
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// 天候のキー（WMO天気コードをまとめた区分）なのです。
+// キャッシュには ja-hiragana の文言で保存し、返すときにこのキーで各言語の文言に置き換えるます。
+const (
+	conditionClear       = "clear"
+	conditionCloudy      = "cloudy"
+	conditionFog         = "fog"
+	conditionDrizzle     = "drizzle"
+	conditionRain        = "rain"
+	conditionSnow        = "snow"
+	conditionBlizzard    = "blizzard"
+	conditionHeavyRain   = "heavyRain"
+	conditionShowers     = "showers"
+	conditionThunder     = "thunder"
+	conditionUnknown     = "unknown"
+	conditionUnavailable = "unavailable"
+)
+
+// ConditionUnavailable は天気を取得できなかったときの天候（ja-hiragana の文言）なのです。
+const ConditionUnavailable = "データ取得失敗"
+
+// catalog は言語ごとの表示用の文言なのです。
+type catalog struct {
+	conditions map[string]string // 天候のキー -> 文言
+	advice     map[string]string // アドバイスのルール名 -> 文言
+
+	// アドバイスの理由の書式（%s には単位つきの値が入るのです）
+	reasonPrecipProb   string // 時, 降水確率
+	reasonPrecipAmount string // 時, 降水量
+	reasonMaxTemp      string
+	reasonFeelsLike    string
+	reasonMinTemp      string
+	reasonUVIndex      string
+	reasonWindSpeed    string
+
+	// 昨日との比較の書式
+	trendWarmer string
+	trendCooler string
+	trendSame   string
+
+	aqiCategories []string          // 米国 AQI の区分（よい〜きけん の6段階）
+	pollenLevels  []string          // 花粉の区分（すくない〜ひじょうにおおい の4段階）
+	pollenNames   map[string]string // 花粉の種類 -> 名前
+}
+
+// catalogs は表示言語ごとの文言なのです。
+var catalogs = map[string]catalog{
+	config.LocaleJaHiragana: {
+		conditions: map[string]string{
+			conditionClear:       "はれ",
+			conditionCloudy:      "くもり",
+			conditionFog:         "きり",
+			conditionDrizzle:     "こさめ",
+			conditionRain:        "あめ",
+			conditionSnow:        "ゆき",
+			conditionBlizzard:    "ふぶき",
+			conditionHeavyRain:   "はげしいあめ",
+			conditionShowers:     "にわかあめ",
+			conditionThunder:     "らいう",
+			conditionUnknown:     "てんこうふめい",
+			conditionUnavailable: ConditionUnavailable,
+		},
+		advice: map[string]string{
+			config.AdviceUmbrella: "かさをもっていこう",
+			config.AdviceLaundry:  "せんたくものはへやぼし",
+			config.AdviceHeat:     "ねっちゅうしょうにちゅうい",
+			config.AdviceCold:     "うわぎをもっていこう",
+			config.AdviceUV:       "ひやけどめをぬろう",
+			config.AdviceWind:     "かぜがつよいよ",
+		},
+		reasonPrecipProb:   "%dじごろ あめのかくりつ%d%%",
+		reasonPrecipAmount: "%dじごろ あめ%s",
+		reasonMaxTemp:      "いちばんたかいきおん%s",
+		reasonFeelsLike:    "たいかんおんど%s",
+		reasonMinTemp:      "いちばんひくいきおん%s",
+		reasonUVIndex:      "UVインデックス%.0f",
+		reasonWindSpeed:    "かぜ%s",
+		trendWarmer:        "きのうより%sたかい",
+		trendCooler:        "きのうより%sひくい",
+		trendSame:          "きのうとおなじくらい",
+		aqiCategories:      []string{"よい", "ふつう", "びんかんなひとはちゅうい", "わるい", "とてもわるい", "きけん"},
+		pollenLevels:       []string{"すくない", "ややおおい", "おおい", "ひじょうにおおい"},
+		pollenNames: map[string]string{
+			"grass":   "イネ科",
+			"birch":   "シラカバ",
+			"alder":   "ハンノキ",
+			"mugwort": "ヨモギ",
+			"ragweed": "ブタクサ",
+			"olive":   "オリーブ",
+		},
+	},
+	config.LocaleJaKanji: {
+		conditions: map[string]string{
+			conditionClear:       "晴れ",
+			conditionCloudy:      "曇り",
+			conditionFog:         "霧",
+			conditionDrizzle:     "小雨",
+			conditionRain:        "雨",
+			conditionSnow:        "雪",
+			conditionBlizzard:    "吹雪",
+			conditionHeavyRain:   "激しい雨",
+			conditionShowers:     "にわか雨",
+			conditionThunder:     "雷雨",
+			conditionUnknown:     "天候不明",
+			conditionUnavailable: ConditionUnavailable,
+		},
+		advice: map[string]string{
+			config.AdviceUmbrella: "傘を持っていこう",
+			config.AdviceLaundry:  "洗濯物は部屋干し",
+			config.AdviceHeat:     "熱中症注意",
+			config.AdviceCold:     "上着を持っていこう",
+			config.AdviceUV:       "日焼け止めをぬろう",
+			config.AdviceWind:     "強風注意",
+		},
+		reasonPrecipProb:   "%d時ごろ 降水確率%d%%",
+		reasonPrecipAmount: "%d時ごろ 雨量%s",
+		reasonMaxTemp:      "最高気温%s",
+		reasonFeelsLike:    "体感温度%s",
+		reasonMinTemp:      "最低気温%s",
+		reasonUVIndex:      "UVインデックス%.0f",
+		reasonWindSpeed:    "風速%s",
+		trendWarmer:        "昨日より%s高い",
+		trendCooler:        "昨日より%s低い",
+		trendSame:          "昨日と同じくらい",
+		aqiCategories:      []string{"良い", "普通", "敏感な人は注意", "悪い", "非常に悪い", "危険"},
+		pollenLevels:       []string{"少ない", "やや多い", "多い", "非常に多い"},
+		pollenNames: map[string]string{
+			"grass":   "イネ科",
+			"birch":   "シラカバ",
+			"alder":   "ハンノキ",
+			"mugwort": "ヨモギ",
+			"ragweed": "ブタクサ",
+			"olive":   "オリーブ",
+		},
+	},
+	config.LocaleEn: {
+		conditions: map[string]string{
+			conditionClear:       "Clear",
+			conditionCloudy:      "Cloudy",
+			conditionFog:         "Fog",
+			conditionDrizzle:     "Drizzle",
+			conditionRain:        "Rain",
+			conditionSnow:        "Snow",
+			conditionBlizzard:    "Blizzard",
+			conditionHeavyRain:   "Heavy rain",
+			conditionShowers:     "Showers",
+			conditionThunder:     "Thunderstorm",
+			conditionUnknown:     "Unknown",
+			conditionUnavailable: "Unavailable",
+		},
+		advice: map[string]string{
+			config.AdviceUmbrella: "Take an umbrella",
+			config.AdviceLaundry:  "Dry the laundry indoors",
+			config.AdviceHeat:     "Watch out for heatstroke",
+			config.AdviceCold:     "Bring a jacket",
+			config.AdviceUV:       "Put on sunscreen",
+			config.AdviceWind:     "Strong winds",
+		},
+		reasonPrecipProb:   "Around %d:00, %d%% chance of rain",
+		reasonPrecipAmount: "Around %d:00, %s of rain",
+		reasonMaxTemp:      "High of %s",
+		reasonFeelsLike:    "Feels like %s",
+		reasonMinTemp:      "Low of %s",
+		reasonUVIndex:      "UV index %.0f",
+		reasonWindSpeed:    "Wind %s",
+		trendWarmer:        "%s warmer than yesterday",
+		trendCooler:        "%s cooler than yesterday",
+		trendSame:          "About the same as yesterday",
+		aqiCategories:      []string{"Good", "Moderate", "Unhealthy for sensitive groups", "Unhealthy", "Very unhealthy", "Hazardous"},
+		pollenLevels:       []string{"Low", "Moderate", "High", "Very high"},
+		pollenNames: map[string]string{
+			"grass":   "Grass",
+			"birch":   "Birch",
+			"alder":   "Alder",
+			"mugwort": "Mugwort",
+			"ragweed": "Ragweed",
+			"olive":   "Olive",
+		},
+	},
+}
+
+// canonicalCatalog はキャッシュに保存する文言（ja-hiragana）なのです。
+var canonicalCatalog = catalogs[config.LocaleJaHiragana]
+
+// conditionKeys は ja-hiragana の天候の文言から天候のキーを引く表なのです。
+var conditionKeys = reverseMap(canonicalCatalog.conditions)
+
+// Localizer は天気のレスポンスを表示言語と単位に合わせて書き換えるます。
+// プロバイダからは℃・m/s・mm で取得してキャッシュするので、返す直前に変換するのです。
+type Localizer struct {
+	lang    string
+	catalog catalog
+	system  string
+	wind    string
+}
+
+// NewLocalizer は表示言語と単位の Localizer を作成するます。
+// 対応していない言語の場合は ja-hiragana を使うのです（?lang= の検証は config.IsLocale で先に行うます）。
+func NewLocalizer(lang string, units config.Units) *Localizer {
+	if !config.IsLocale(lang) {
+		lang = config.LocaleJaHiragana
+	}
+	return &Localizer{
+		lang:    lang,
+		catalog: catalogs[lang],
+		system:  units.GetSystem(),
+		wind:    units.GetWindSpeed(),
+	}
+}
+
+// defaultLocalizer は ja-hiragana・metric（m/s）の Localizer なのです。
+func defaultLocalizer() *Localizer {
+	return NewLocalizer(config.LocaleJaHiragana, config.Units{})
+}
+
+// Lang は表示言語を返すます。
+func (l *Localizer) Lang() string {
+	return l.lang
+}
+
+// Units は数値の単位（表示用の記号）を返すます。
+func (l *Localizer) Units() models.WeatherUnits {
+	units := models.WeatherUnits{Temperature: "℃", Precipitation: "mm"}
+	if l.system == config.UnitsImperial {
+		units.Temperature = "℉"
+		units.Precipitation = "in"
+	}
+	switch l.wind {
+	case config.WindSpeedKMH:
+		units.WindSpeed = "km/h"
+	case config.WindSpeedMPH:
+		units.WindSpeed = "mph"
+	default:
+		units.WindSpeed = "m/s"
+	}
+	return units
+}
+
+// Localize は天気のレスポンスの数値を単位に合わせて変換し、天候などの文言を表示言語に置き換えるます。
+// アドバイスは BuildAdvice で作るときに表示言語と単位を使うので、ここでは変換しないのです。
+func (l *Localizer) Localize(weatherRsp *models.WeatherResponse) {
+	weatherRsp.Lang = l.lang
+	weatherRsp.Units = l.Units()
+
+	current := &weatherRsp.Current
+	current.Temperature = l.Temperature(current.Temperature)
+	current.FeelsLike = l.Temperature(current.FeelsLike)
+	current.WindSpeed = l.WindSpeed(current.WindSpeed)
+	current.WindGust = l.WindSpeed(current.WindGust)
+	current.Condition = l.Condition(current.Condition)
+
+	weatherRsp.Today.MaxTemp = l.Temperature(weatherRsp.Today.MaxTemp)
+	weatherRsp.Today.MinTemp = l.Temperature(weatherRsp.Today.MinTemp)
+	weatherRsp.Today.Summary = l.Condition(weatherRsp.Today.Summary)
+
+	l.LocalizeHourly(weatherRsp.Hourly)
+	for i := range weatherRsp.Weekly {
+		day := &weatherRsp.Weekly[i]
+		day.MaxTemp = l.Temperature(day.MaxTemp)
+		day.MinTemp = l.Temperature(day.MinTemp)
+		day.Condition = l.Condition(day.Condition)
+	}
+
+	if weatherRsp.AirQuality != nil {
+		airQuality := *weatherRsp.AirQuality
+		airQuality.Category = l.lookup(canonicalCatalog.aqiCategories, l.catalog.aqiCategories, airQuality.Category)
+		airQuality.Pollen = make([]models.PollenLevel, len(weatherRsp.AirQuality.Pollen))
+		for i, pollen := range weatherRsp.AirQuality.Pollen {
+			if name, ok := l.catalog.pollenNames[pollen.Type]; ok {
+				pollen.Name = name
+			}
+			pollen.Level = l.lookup(canonicalCatalog.pollenLevels, l.catalog.pollenLevels, pollen.Level)
+			airQuality.Pollen[i] = pollen
+		}
+		weatherRsp.AirQuality = &airQuality
+	}
+}
+
+// LocalizeHourly は時間ごとの予報を単位と表示言語に合わせて書き換えるます。
+func (l *Localizer) LocalizeHourly(hourly []models.HourlyWeather) {
+	for i := range hourly {
+		hour := &hourly[i]
+		hour.Temperature = l.Temperature(hour.Temperature)
+		hour.PrecipAmount = l.Precipitation(hour.PrecipAmount)
+		hour.WindSpeed = l.WindSpeed(hour.WindSpeed)
+		hour.Condition = l.Condition(hour.Condition)
+	}
+}
+
+// LocalizeHistory は日ごとの履歴の気温と降水量を単位に合わせて変換するます。
+func (l *Localizer) LocalizeHistory(days []models.WeatherHistoryDay) {
+	for i := range days {
+		days[i].MaxTemp = l.Temperature(days[i].MaxTemp)
+		days[i].MinTemp = l.Temperature(days[i].MinTemp)
+		days[i].Precipitation = l.Precipitation(days[i].Precipitation)
+	}
+}
+
+// Temperature は気温（℃）を単位に合わせて変換するます。
+func (l *Localizer) Temperature(celsius float64) float64 {
+	if l.system != config.UnitsImperial {
+		return celsius
+	}
+	return round1(celsius*1.8 + 32)
+}
+
+// temperatureDelta は気温の差（℃）を単位に合わせて変換するます（0℃のずれは足さないのです）。
+func (l *Localizer) temperatureDelta(celsius float64) float64 {
+	if l.system != config.UnitsImperial {
+		return celsius
+	}
+	return round1(celsius * 1.8)
+}
+
+// WindSpeed は風速（m/s）を単位に合わせて変換するます。
+func (l *Localizer) WindSpeed(ms float64) float64 {
+	switch l.wind {
+	case config.WindSpeedKMH:
+		return round1(ms * 3.6)
+	case config.WindSpeedMPH:
+		return round1(ms * 2.23694)
+	default:
+		return ms
+	}
+}
+
+// Precipitation は降水量（mm）を単位に合わせて変換するます（インチは小数2桁）。
+func (l *Localizer) Precipitation(mm float64) float64 {
+	if l.system != config.UnitsImperial {
+		return mm
+	}
+	return math.Round(mm/25.4*100) / 100
+}
+
+// Condition は ja-hiragana の天候の文言を表示言語の文言に置き換えるます。
+// 知らない文言（古いキャッシュなど）はそのまま返すのです。
+func (l *Localizer) Condition(text string) string {
+	key, ok := conditionKeys[text]
+	if !ok {
+		return text
+	}
+	return l.catalog.conditions[key]
+}
+
+// formatTemperature は気温（℃）を単位つきの文字列にするます（例: "35℃" "95℉"）。
+func (l *Localizer) formatTemperature(celsius float64) string {
+	return fmt.Sprintf("%.0f%s", l.Temperature(celsius), l.Units().Temperature)
+}
+
+// formatWindSpeed は風速（m/s）を単位つきの文字列にするます（例: "12m/s" "27mph"）。
+func (l *Localizer) formatWindSpeed(ms float64) string {
+	return fmt.Sprintf("%.0f%s", l.WindSpeed(ms), l.Units().WindSpeed)
+}
+
+// formatPrecipitation は降水量（mm）を単位つきの文字列にするます（例: "1.5mm" "0.06in"）。
+func (l *Localizer) formatPrecipitation(mm float64) string {
+	if l.system == config.UnitsImperial {
+		return fmt.Sprintf("%.2fin", l.Precipitation(mm))
+	}
+	return fmt.Sprintf("%.1fmm", mm)
+}
+
+// lookup は ja-hiragana の区分の文言を、同じ段階の表示言語の文言に置き換えるます。
+func (l *Localizer) lookup(canonical, localized []string, text string) string {
+	for i, candidate := range canonical {
+		if candidate == text && i < len(localized) {
+			return localized[i]
+		}
+	}
+	return text
+}
+
+// conditionKey は WMO天気コードを天候のキーに変換するます。
+func conditionKey(code int) string {
+	switch code {
+	case 0:
+		return conditionClear
+	case 1, 2, 3:
+		return conditionCloudy
+	case 45, 48:
+		return conditionFog
+	case 51, 53, 55:
+		return conditionDrizzle
+	case 61, 63, 65:
+		return conditionRain
+	case 71, 73, 75:
+		return conditionSnow
+	case 77:
+		return conditionBlizzard
+	case 80, 81, 82:
+		return conditionHeavyRain
+	case 85, 86:
+		return conditionShowers
+	case 95, 96, 99:
+		return conditionThunder
+	default:
+		return conditionUnknown
+	}
+}
+
+// reverseMap は文言からキーを引く表を作るます。
+func reverseMap(m map[string]string) map[string]string {
+	reversed := make(map[string]string, len(m))
+	for key, value := range m {
+		reversed[value] = key
+	}
+	return reversed
+}
+
+// round1 は小数1桁に四捨五入するます。
+func round1(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,280 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// defaultMetNorwayBaseURL は MET Norway Locationforecast API のベースURLなのです。
+const defaultMetNorwayBaseURL = "https://api.met.no/weatherapi/locationforecast/2.0"
+
+// MetNorwayProvider は MET Norway（ノルウェー気象研究所）の Locationforecast API から
+// 天気を取得するプロバイダなのです。登録不要ですが、連絡先を含む User-Agent が必須なのです。
+type MetNorwayProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewMetNorwayProvider は MET Norway プロバイダを作成するます。
+// baseURL が空の場合は既定の URL を使うのです。
+func NewMetNorwayProvider(baseURL string) *MetNorwayProvider {
+	if baseURL == "" {
+		baseURL = defaultMetNorwayBaseURL
+	}
+	return &MetNorwayProvider{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: newHTTPClient(),
+	}
+}
+
+// Name はプロバイダ名を返すます。
+func (p *MetNorwayProvider) Name() string {
+	return ProviderMetNorway
+}
+
+// MetNorwayResponse は Locationforecast の GeoJSON レスポンスなのです。
+type MetNorwayResponse struct {
+	Geometry struct {
+		Coordinates []float64 `json:"coordinates"` // [経度, 緯度, 標高]
+	} `json:"geometry"`
+	Properties struct {
+		Timeseries []MetNorwayTimeseries `json:"timeseries"`
+	} `json:"properties"`
+}
+
+// MetNorwayTimeseries は時刻ごとの予報なのです。
+// 数日先からは6時間ごとになり、next_1_hours が無くなるのです。
+type MetNorwayTimeseries struct {
+	Time string `json:"time"` // UTC（RFC3339）
+	Data struct {
+		Instant struct {
+			Details struct {
+				AirTemperature           float64 `json:"air_temperature"`
+				RelativeHumidity         float64 `json:"relative_humidity"`
+				WindSpeed                float64 `json:"wind_speed"`
+				WindFromDirection        float64 `json:"wind_from_direction"`         // 風向（度）
+				WindSpeedOfGust          float64 `json:"wind_speed_of_gust"`          // 最大瞬間風速（m/s）
+				AirPressureAtSeaLevel    float64 `json:"air_pressure_at_sea_level"`   // 海面気圧（hPa）
+				UltravioletIndexClearSky float64 `json:"ultraviolet_index_clear_sky"` // 晴天時の UV インデックス
+			} `json:"details"`
+		} `json:"instant"`
+		Next1Hours *MetNorwayPeriod `json:"next_1_hours"`
+		Next6Hours *MetNorwayPeriod `json:"next_6_hours"`
+	} `json:"data"`
+}
+
+// MetNorwayPeriod は次の1時間・6時間の予報なのです。
+type MetNorwayPeriod struct {
+	Summary struct {
+		SymbolCode string `json:"symbol_code"` // 例: "partlycloudy_day"
+	} `json:"summary"`
+	Details struct {
+		PrecipitationAmount        float64  `json:"precipitation_amount"`         // 降水量（mm）
+		ProbabilityOfPrecipitation *float64 `json:"probability_of_precipitation"` // 降水確率（%、地域によっては無い）
+	} `json:"details"`
+}
+
+// Fetch は MET Norway から天気を取得するます。
+func (p *MetNorwayProvider) Fetch(ctx context.Context, lat, lon float64, cityName string) (*models.WeatherResponse, error) {
+	// 座標は小数2桁に丸めるます（利用規約で4桁以下が求められ、API側のキャッシュも効きやすいのです）
+	url := fmt.Sprintf("%s/complete?lat=%.2f&lon=%.2f", p.baseURL, lat, lon)
+
+	var metResp MetNorwayResponse
+	if err := getJSON(ctx, p.httpClient, url, "MET Norway", &metResp); err != nil {
+		return nil, err
+	}
+	if len(metResp.Properties.Timeseries) == 0 {
+		return nil, fmt.Errorf("MET Norway レスポンスに予報がありません")
+	}
+
+	return convertMetNorway(&metResp, cityName, time.Now().In(tokyoLocation())), nil
+}
+
+// convertMetNorway は MET Norway のレスポンスを models.WeatherResponse に変換するます。
+// 体感温度と日の出・日の入りは提供されないので、気温・湿度・風速とレスポンスの座標から計算するのです。
+func convertMetNorway(metResp *MetNorwayResponse, cityName string, now time.Time) *models.WeatherResponse {
+	points := []forecastPoint{}
+	hourlyPoints := []hourlyPoint{}
+	var currentEntry *MetNorwayTimeseries
+	uvIndexMax := 0.0
+	todayDate := now.Format("2006-01-02")
+
+	for i := range metResp.Properties.Timeseries {
+		entry := &metResp.Properties.Timeseries[i]
+		t, err := time.Parse(time.RFC3339, entry.Time)
+		if err != nil {
+			continue
+		}
+		t = t.In(now.Location())
+		if t.Format("2006-01-02") == todayDate {
+			uvIndexMax = math.Max(uvIndexMax, entry.Data.Instant.Details.UltravioletIndexClearSky)
+		}
+
+		// 現在時刻以前で最も新しい予報を「現在」として使うます（無ければ先頭）
+		if currentEntry == nil || !t.After(now) {
+			currentEntry = entry
+		}
+
+		period := entry.Data.Next1Hours
+		if period == nil {
+			period = entry.Data.Next6Hours
+		}
+		code := -1
+		if period != nil {
+			code = metSymbolToWMO(period.Summary.SymbolCode)
+		}
+		points = append(points, forecastPoint{
+			Time:        t,
+			Temperature: entry.Data.Instant.Details.AirTemperature,
+			WeatherCode: code,
+		})
+		// 時間ごとの予報は1時間予報がある時刻だけ使うのです（数日先の6時間ごとの予報は使わない）
+		if next := entry.Data.Next1Hours; next != nil {
+			details := entry.Data.Instant.Details
+			hourlyPoints = append(hourlyPoints, hourlyPoint{
+				Time:          t,
+				Temperature:   details.AirTemperature,
+				PrecipProb:    metPrecipProbability(next),
+				PrecipAmount:  next.Details.PrecipitationAmount,
+				WeatherCode:   code,
+				IsDay:         metIsDay(next.Summary.SymbolCode, t, metResp),
+				WindSpeed:     details.WindSpeed,
+				WindDirection: details.WindFromDirection,
+			})
+		}
+	}
+
+	sunrise, sunset := metSunTimes(now, metResp)
+	isDay := isDaytime(now, sunrise, sunset)
+
+	currentWeather := models.CurrentWeather{Condition: weatherCodeToCondition(-1), Icon: weatherCodeToIcon(-1), IsDay: isDay}
+	if currentEntry != nil {
+		details := currentEntry.Data.Instant.Details
+		code := -1
+		if period := currentEntry.Data.Next1Hours; period != nil {
+			code = metSymbolToWMO(period.Summary.SymbolCode)
+		} else if period := currentEntry.Data.Next6Hours; period != nil {
+			code = metSymbolToWMO(period.Summary.SymbolCode)
+		}
+		currentWeather = models.CurrentWeather{
+			Temperature:   details.AirTemperature,
+			FeelsLike:     apparentTemperature(details.AirTemperature, details.RelativeHumidity, details.WindSpeed),
+			Condition:     weatherCodeToCondition(code),
+			Icon:          dayNightIcon(weatherCodeToIcon(code), isDay),
+			IsDay:         isDay,
+			Humidity:      int(details.RelativeHumidity + 0.5),
+			WindSpeed:     details.WindSpeed,
+			WindDirection: int(math.Round(details.WindFromDirection)) % 360,
+			WindGust:      details.WindSpeedOfGust,
+			Pressure:      details.AirPressureAtSeaLevel,
+			UVIndex:       details.UltravioletIndexClearSky,
+		}
+	}
+
+	hourly := buildHourly(hourlyPoints, now)
+	weekly := summarizeDays(points, now.Location(), 7)
+	today := todayFromWeekly(weekly, currentWeather, now)
+	today.Sunrise = formatSunTime(sunrise)
+	today.Sunset = formatSunTime(sunset)
+	today.UVIndexMax = uvIndexMax
+	return &models.WeatherResponse{
+		Location:    cityName,
+		Current:     currentWeather,
+		Today:       today,
+		PrecipSlots: buildPrecipSlots(hourly, now),
+		Hourly:      hourly,
+		Weekly:      weekly,
+		Alerts:      []models.WeatherAlert{},
+	}
+}
+
+// metSunTimes はレスポンスの座標から t の日の日の出・日の入りを計算するます（分からなければゼロ値）。
+func metSunTimes(t time.Time, metResp *MetNorwayResponse) (time.Time, time.Time) {
+	coords := metResp.Geometry.Coordinates
+	if len(coords) < 2 {
+		return time.Time{}, time.Time{}
+	}
+	sunrise, sunset, ok := sunTimes(t, coords[1], coords[0])
+	if !ok {
+		return time.Time{}, time.Time{}
+	}
+	return sunrise, sunset
+}
+
+// metIsDay はシンボルコードの末尾（_day / _night）から昼かどうかを判定するます。
+// 末尾が無いシンボル（cloudy など）は日の出・日の入りから判定するのです。
+func metIsDay(symbol string, t time.Time, metResp *MetNorwayResponse) bool {
+	switch {
+	case strings.HasSuffix(symbol, "_day"):
+		return true
+	case strings.HasSuffix(symbol, "_night"):
+		return false
+	}
+	sunrise, sunset := metSunTimes(t, metResp)
+	return isDaytime(t, sunrise, sunset)
+}
+
+// metPrecipProbability は1時間予報の降水確率を返すます。
+// 降水確率が提供されない地域（日本など）では降水量からの目安にするのです。
+func metPrecipProbability(period *MetNorwayPeriod) int {
+	if p := period.Details.ProbabilityOfPrecipitation; p != nil {
+		return int(*p + 0.5)
+	}
+	amount := period.Details.PrecipitationAmount
+	switch {
+	case amount <= 0:
+		return 0
+	case amount < 0.5:
+		return 30
+	case amount < 2:
+		return 60
+	default:
+		return 90
+	}
+}
+
+// metSymbolToWMO は MET Norway のシンボルコードを WMO天気コードに変換するます。
+// 末尾の _day / _night / _polartwilight は無視するのです。
+func metSymbolToWMO(symbol string) int {
+	if i := strings.Index(symbol, "_"); i >= 0 {
+		symbol = symbol[:i]
+	}
+	if strings.Contains(symbol, "thunder") {
+		return 95 // 雷雨
+	}
+
+	switch symbol {
+	case "clearsky":
+		return 0
+	case "fair":
+		return 1
+	case "partlycloudy":
+		return 2
+	case "cloudy":
+		return 3
+	case "fog":
+		return 45
+	case "lightrain":
+		return 51
+	case "rain":
+		return 63
+	case "heavyrain":
+		return 65
+	case "lightrainshowers", "rainshowers", "heavyrainshowers":
+		return 80
+	case "lightsnow", "lightsleet", "lightsnowshowers", "lightsleetshowers":
+		return 71
+	case "snow", "sleet", "snowshowers", "sleetshowers":
+		return 73
+	case "heavysnow", "heavysleet", "heavysnowshowers", "heavysleetshowers":
+		return 75
+	default:
+		return -1 // 不明
+	}
+}
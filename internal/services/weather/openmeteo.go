@@ -0,0 +1,220 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// defaultOpenMeteoBaseURL は Open-Meteo API のベースURLなのです。
+const defaultOpenMeteoBaseURL = "https://api.open-meteo.com/v1"
+
+// OpenMeteoProvider は Open-Meteo API から天気を取得するプロバイダなのです。
+// 登録不要・無料で、気象庁のモデルも含まれているのです。
+type OpenMeteoProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOpenMeteoProvider は Open-Meteo プロバイダを作成するます。
+// baseURL が空の場合は既定の URL を使うのです。
+// "https://api.open-meteo.com" のようにバージョンが無い場合は "/v1" を補うます。
+func NewOpenMeteoProvider(baseURL string) *OpenMeteoProvider {
+	if baseURL == "" {
+		baseURL = defaultOpenMeteoBaseURL
+	}
+	baseURL = strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/forecast")
+	if parsed, err := url.Parse(baseURL); err == nil && parsed.Host != "" && strings.Trim(parsed.Path, "/") == "" {
+		baseURL += "/v1"
+	}
+	return &OpenMeteoProvider{
+		baseURL:    baseURL,
+		httpClient: newHTTPClient(),
+	}
+}
+
+// Name はプロバイダ名を返すます。
+func (p *OpenMeteoProvider) Name() string {
+	return ProviderOpenMeteo
+}
+
+// OpenMeteoWeatherResponse は Open-Meteo API のレスポンス構造体なのです。
+// 気象庁データをラップしているため、日本の天気予報データを取得できるます。
+type OpenMeteoWeatherResponse struct {
+	Latitude  float64              `json:"latitude"`
+	Longitude float64              `json:"longitude"`
+	Current   OpenMeteoWeatherData `json:"current"`
+	Daily     OpenMeteoDailyData   `json:"daily"`
+	Hourly    OpenMeteoHourlyData  `json:"hourly"`
+}
+
+// OpenMeteoWeatherData は Open-Meteo API の現在データなのです。
+type OpenMeteoWeatherData struct {
+	Temperature         float64 `json:"temperature_2m"`
+	ApparentTemperature float64 `json:"apparent_temperature"`
+	RelativeHumidity    int     `json:"relative_humidity_2m"`
+	WindSpeed           float64 `json:"wind_speed_10m"`
+	WindDirection       float64 `json:"wind_direction_10m"`
+	WindGusts           float64 `json:"wind_gusts_10m"`
+	PressureMSL         float64 `json:"pressure_msl"`
+	UVIndex             float64 `json:"uv_index"`
+	WeatherCode         int     `json:"weather_code"`
+	Time                string  `json:"time"`
+}
+
+// OpenMeteoDailyData は Open-Meteo API の日別予報なのです。
+type OpenMeteoDailyData struct {
+	Time              []string  `json:"time"`
+	MaxTemperature    []float64 `json:"temperature_2m_max"`
+	MinTemperature    []float64 `json:"temperature_2m_min"`
+	PrecipitationProb []int     `json:"precipitation_probability_max"`
+	WeatherCode       []int     `json:"weather_code"`
+	Sunrise           []string  `json:"sunrise"`      // 日の出（Asia/Tokyo、"2006-01-02T15:04"）
+	Sunset            []string  `json:"sunset"`       // 日の入り（Asia/Tokyo、"2006-01-02T15:04"）
+	UVIndexMax        []float64 `json:"uv_index_max"` // 最大UVインデックス
+}
+
+// OpenMeteoHourlyData は Open-Meteo API の時間別予報なのです。
+type OpenMeteoHourlyData struct {
+	Time              []string  `json:"time"`
+	Temperature       []float64 `json:"temperature_2m"`
+	PrecipitationProb []int     `json:"precipitation_probability"`
+	Precipitation     []float64 `json:"precipitation"` // 前の1時間の降水量（mm）
+	WeatherCode       []int     `json:"weather_code"`
+	WindSpeed         []float64 `json:"wind_speed_10m"`
+	WindDirection     []float64 `json:"wind_direction_10m"`
+	IsDay             []int     `json:"is_day"` // 1: 昼, 0: 夜
+}
+
+// Fetch は Open-Meteo API から天気データを取得するます。
+// 気象庁データベースが統合されているため、日本の天気データも取得できるます。
+func (p *OpenMeteoProvider) Fetch(ctx context.Context, lat, lon float64, cityName string) (*models.WeatherResponse, error) {
+	// Open-Meteo API リクエストを構築するます（単位は他のプロバイダに合わせて℃・m/s・mm を明示するのです）
+	// 表示する単位への変換は返すときに Localizer で行うます
+	requestURL := fmt.Sprintf(
+		"%s/forecast?latitude=%.2f&longitude=%.2f&current=temperature_2m,apparent_temperature,relative_humidity_2m,weather_code,wind_speed_10m,wind_direction_10m,wind_gusts_10m,pressure_msl,uv_index&daily=weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max,sunrise,sunset,uv_index_max&hourly=temperature_2m,precipitation_probability,precipitation,weather_code,wind_speed_10m,wind_direction_10m,is_day&temperature_unit=celsius&wind_speed_unit=ms&precipitation_unit=mm&timezone=Asia/Tokyo&forecast_days=7",
+		p.baseURL, lat, lon,
+	)
+
+	var omResp OpenMeteoWeatherResponse
+	if err := getJSON(ctx, p.httpClient, requestURL, "Open-Meteo", &omResp); err != nil {
+		return nil, err
+	}
+
+	// Open-Meteo のレスポンスを models.WeatherResponse に変換するます
+	return convertOpenMeteo(&omResp, cityName, time.Now().In(tokyoLocation())), nil
+}
+
+// convertOpenMeteo は Open-Meteo レスポンスを models.WeatherResponse に変換するます。
+func convertOpenMeteo(omResp *OpenMeteoWeatherResponse, cityName string, now time.Time) *models.WeatherResponse {
+	condition := weatherCodeToCondition(omResp.Current.WeatherCode)
+	icon := weatherCodeToIcon(omResp.Current.WeatherCode)
+
+	// 現在の天候
+	current := models.CurrentWeather{
+		Temperature:   omResp.Current.Temperature,
+		FeelsLike:     omResp.Current.ApparentTemperature,
+		Condition:     condition,
+		Icon:          icon,
+		IsDay:         true,
+		Humidity:      omResp.Current.RelativeHumidity,
+		WindSpeed:     omResp.Current.WindSpeed,
+		WindDirection: int(math.Round(omResp.Current.WindDirection)) % 360,
+		WindGust:      omResp.Current.WindGusts,
+		Pressure:      omResp.Current.PressureMSL,
+		UVIndex:       omResp.Current.UVIndex,
+	}
+
+	// 週間天気予報を取得するます（7日分）
+	weekly := []models.WeeklyWeather{}
+	for i := 0; i < len(omResp.Daily.Time) && i < 7; i++ {
+		if i >= len(omResp.Daily.MaxTemperature) || i >= len(omResp.Daily.MinTemperature) || i >= len(omResp.Daily.WeatherCode) {
+			break
+		}
+		weekly = append(weekly, models.WeeklyWeather{
+			Date:      omResp.Daily.Time[i],
+			MaxTemp:   omResp.Daily.MaxTemperature[i],
+			MinTemp:   omResp.Daily.MinTemperature[i],
+			Condition: weatherCodeToCondition(omResp.Daily.WeatherCode[i]),
+			Icon:      weatherCodeToIcon(omResp.Daily.WeatherCode[i]),
+		})
+	}
+
+	// 今日の天況（日別予報の先頭が今日なのです）
+	today := models.TodayWeather{Summary: condition}
+	if len(weekly) > 0 {
+		today.MaxTemp = weekly[0].MaxTemp
+		today.MinTemp = weekly[0].MinTemp
+	}
+	if len(omResp.Daily.UVIndexMax) > 0 {
+		today.UVIndexMax = omResp.Daily.UVIndexMax[0]
+	}
+
+	// 日の出・日の入りから昼夜を判定して、アイコンを切り替えるます
+	sunrise := parseOpenMeteoTime(omResp.Daily.Sunrise, now.Location())
+	sunset := parseOpenMeteoTime(omResp.Daily.Sunset, now.Location())
+	today.Sunrise = formatSunTime(sunrise)
+	today.Sunset = formatSunTime(sunset)
+	current.IsDay = isDaytime(now, sunrise, sunset)
+	current.Icon = dayNightIcon(current.Icon, current.IsDay)
+
+	// 時間ごとの予報を取得するます（降水確率のスロットはここから3時間区切りで抜き出すのです）
+	hourly := omResp.Hourly
+	points := []hourlyPoint{}
+	for i, timeRaw := range hourly.Time {
+		// 時刻文字列をパースするます（Asia/Tokyoとして扱う）
+		t, err := time.ParseInLocation("2006-01-02T15:04", timeRaw, now.Location())
+		if err != nil {
+			continue
+		}
+		points = append(points, hourlyPoint{
+			Time:          t,
+			Temperature:   valueAt(hourly.Temperature, i, 0),
+			PrecipProb:    valueAt(hourly.PrecipitationProb, i, 0),
+			PrecipAmount:  valueAt(hourly.Precipitation, i+1, 0), // 次の時刻の値がこの1時間の降水量なのです
+			WeatherCode:   valueAt(hourly.WeatherCode, i, -1),
+			IsDay:         valueAt(hourly.IsDay, i, 1) == 1,
+			WindSpeed:     valueAt(hourly.WindSpeed, i, 0),
+			WindDirection: valueAt(hourly.WindDirection, i, 0),
+		})
+	}
+	hourlyWeather := buildHourly(points, now)
+
+	// 注意報・警報はここでは空にするます
+	// （Open-Meteo では警報提供がないため、気象庁の注意報・警報をハンドラーで合成するます）
+	return &models.WeatherResponse{
+		Location:    cityName,
+		Current:     current,
+		Today:       today,
+		PrecipSlots: buildPrecipSlots(hourlyWeather, now),
+		Hourly:      hourlyWeather,
+		Weekly:      weekly,
+		Alerts:      []models.WeatherAlert{},
+	}
+}
+
+// valueAt は時間別の配列の i 番目を返すます（足りない場合は fallback）。
+func valueAt[T any](values []T, i int, fallback T) T {
+	if i < 0 || i >= len(values) {
+		return fallback
+	}
+	return values[i]
+}
+
+// parseOpenMeteoTime は日別の時刻リストの先頭（今日）をパースするます（無ければゼロ値）。
+func parseOpenMeteoTime(values []string, loc *time.Location) time.Time {
+	if len(values) == 0 {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04", values[0], loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,222 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// defaultOpenWeatherMapBaseURL は OpenWeatherMap API のベースURLなのです。
+const defaultOpenWeatherMapBaseURL = "https://api.openweathermap.org/data/2.5"
+
+// OpenWeatherMapProvider は OpenWeatherMap API（無料プランの現在の天気＋5日間3時間予報）から
+// 天気を取得するプロバイダなのです。APIキーが必要なのです。
+type OpenWeatherMapProvider struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOpenWeatherMapProvider は OpenWeatherMap プロバイダを作成するます。
+// baseURL が空の場合は既定の URL を使うのです。
+func NewOpenWeatherMapProvider(apiKey, baseURL string) *OpenWeatherMapProvider {
+	if baseURL == "" {
+		baseURL = defaultOpenWeatherMapBaseURL
+	}
+	return &OpenWeatherMapProvider{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: newHTTPClient(),
+	}
+}
+
+// Name はプロバイダ名を返すます。
+func (p *OpenWeatherMapProvider) Name() string {
+	return ProviderOpenWeatherMap
+}
+
+// OpenWeatherMapCondition は OpenWeatherMap の天候なのです。
+type OpenWeatherMapCondition struct {
+	ID int `json:"id"` // 天候コード（2xx 雷雨、5xx 雨、800 晴 など）
+}
+
+// OpenWeatherMapMain は OpenWeatherMap の気温・湿度なのです。
+type OpenWeatherMapMain struct {
+	Temperature float64 `json:"temp"`
+	FeelsLike   float64 `json:"feels_like"`
+	Pressure    float64 `json:"pressure"` // 海面気圧（hPa）
+	Humidity    int     `json:"humidity"`
+}
+
+// OpenWeatherMapCurrentResponse は /weather のレスポンスなのです。
+type OpenWeatherMapCurrentResponse struct {
+	Weather []OpenWeatherMapCondition `json:"weather"`
+	Main    OpenWeatherMapMain        `json:"main"`
+	Wind    struct {
+		Speed float64 `json:"speed"`
+		Deg   float64 `json:"deg"`
+		Gust  float64 `json:"gust"`
+	} `json:"wind"`
+	Sys struct {
+		Sunrise int64 `json:"sunrise"` // UNIX時刻
+		Sunset  int64 `json:"sunset"`  // UNIX時刻
+	} `json:"sys"`
+	Dt int64 `json:"dt"`
+}
+
+// OpenWeatherMapForecastResponse は /forecast（5日間3時間予報）のレスポンスなのです。
+type OpenWeatherMapForecastResponse struct {
+	List []OpenWeatherMapForecastItem `json:"list"`
+}
+
+// OpenWeatherMapForecastItem は3時間予報の1件なのです。
+type OpenWeatherMapForecastItem struct {
+	Dt      int64                     `json:"dt"`
+	Main    OpenWeatherMapMain        `json:"main"`
+	Weather []OpenWeatherMapCondition `json:"weather"`
+	Wind    struct {
+		Speed float64 `json:"speed"`
+		Deg   float64 `json:"deg"`
+	} `json:"wind"`
+	Rain struct {
+		ThreeHours float64 `json:"3h"` // 3時間の降水量（mm）
+	} `json:"rain"`
+	Snow struct {
+		ThreeHours float64 `json:"3h"` // 3時間の降雪量（mm、水換算）
+	} `json:"snow"`
+	Sys struct {
+		Pod string `json:"pod"` // "d": 昼, "n": 夜
+	} `json:"sys"`
+	Pop float64 `json:"pop"` // 降水確率（0〜1）
+}
+
+// Fetch は OpenWeatherMap から現在の天気と予報を取得するます。
+func (p *OpenWeatherMapProvider) Fetch(ctx context.Context, lat, lon float64, cityName string) (*models.WeatherResponse, error) {
+	query := url.Values{}
+	query.Set("lat", fmt.Sprintf("%.2f", lat))
+	query.Set("lon", fmt.Sprintf("%.2f", lon))
+	query.Set("appid", p.apiKey)
+	query.Set("units", "metric") // ℃・m/s（表示する単位への変換は返すときに行うのです）
+
+	var current OpenWeatherMapCurrentResponse
+	if err := getJSON(ctx, p.httpClient, p.baseURL+"/weather?"+query.Encode(), "OpenWeatherMap", &current); err != nil {
+		return nil, err
+	}
+
+	var forecast OpenWeatherMapForecastResponse
+	if err := getJSON(ctx, p.httpClient, p.baseURL+"/forecast?"+query.Encode(), "OpenWeatherMap", &forecast); err != nil {
+		return nil, err
+	}
+
+	return convertOpenWeatherMap(&current, &forecast, cityName, time.Now().In(tokyoLocation())), nil
+}
+
+// convertOpenWeatherMap は OpenWeatherMap のレスポンスを models.WeatherResponse に変換するます。
+// 週間予報は5日間3時間予報を日別に集計するため、最大5〜6日分になるのです。
+// 時間ごとの予報も3時間ごとになるのです。
+// 無料プランには UV インデックスが無いので 0 のままなのです。
+func convertOpenWeatherMap(current *OpenWeatherMapCurrentResponse, forecast *OpenWeatherMapForecastResponse, cityName string, now time.Time) *models.WeatherResponse {
+	code := owmConditionToWMO(firstConditionID(current.Weather))
+	var sunrise, sunset time.Time
+	if current.Sys.Sunrise > 0 && current.Sys.Sunset > 0 {
+		sunrise = time.Unix(current.Sys.Sunrise, 0).In(now.Location())
+		sunset = time.Unix(current.Sys.Sunset, 0).In(now.Location())
+	}
+	isDay := isDaytime(now, sunrise, sunset)
+	currentWeather := models.CurrentWeather{
+		Temperature:   current.Main.Temperature,
+		FeelsLike:     current.Main.FeelsLike,
+		Condition:     weatherCodeToCondition(code),
+		Icon:          dayNightIcon(weatherCodeToIcon(code), isDay),
+		IsDay:         isDay,
+		Humidity:      current.Main.Humidity,
+		WindSpeed:     current.Wind.Speed,
+		WindDirection: int(math.Round(current.Wind.Deg)) % 360,
+		WindGust:      current.Wind.Gust,
+		Pressure:      current.Main.Pressure,
+	}
+
+	points := make([]forecastPoint, 0, len(forecast.List))
+	hourlyPoints := make([]hourlyPoint, 0, len(forecast.List))
+	for _, item := range forecast.List {
+		t := time.Unix(item.Dt, 0).In(now.Location())
+		code := owmConditionToWMO(firstConditionID(item.Weather))
+		points = append(points, forecastPoint{
+			Time:        t,
+			Temperature: item.Main.Temperature,
+			WeatherCode: code,
+		})
+		hourlyPoints = append(hourlyPoints, hourlyPoint{
+			Time:          t,
+			Temperature:   item.Main.Temperature,
+			PrecipProb:    int(math.Round(item.Pop * 100)),
+			PrecipAmount:  item.Rain.ThreeHours + item.Snow.ThreeHours,
+			WeatherCode:   code,
+			IsDay:         item.Sys.Pod != "n",
+			WindSpeed:     item.Wind.Speed,
+			WindDirection: item.Wind.Deg,
+		})
+	}
+	hourly := buildHourly(hourlyPoints, now)
+
+	weekly := summarizeDays(points, now.Location(), 7)
+	today := todayFromWeekly(weekly, currentWeather, now)
+	today.Sunrise = formatSunTime(sunrise)
+	today.Sunset = formatSunTime(sunset)
+	return &models.WeatherResponse{
+		Location:    cityName,
+		Current:     currentWeather,
+		Today:       today,
+		PrecipSlots: buildPrecipSlots(hourly, now),
+		Hourly:      hourly,
+		Weekly:      weekly,
+		Alerts:      []models.WeatherAlert{},
+	}
+}
+
+// firstConditionID は天候リストの先頭のコードを返すます（空なら0）。
+func firstConditionID(conditions []OpenWeatherMapCondition) int {
+	if len(conditions) == 0 {
+		return 0
+	}
+	return conditions[0].ID
+}
+
+// owmConditionToWMO は OpenWeatherMap の天候コードを WMO天気コードに変換するます。
+// 日本語の天候・アイコンへの変換を Open-Meteo と共通にするためなのです。
+func owmConditionToWMO(id int) int {
+	switch {
+	case id >= 200 && id < 300:
+		return 95 // 雷雨
+	case id >= 300 && id < 400:
+		return 51 // 霧雨
+	case id == 500 || id == 501 || id == 511:
+		return 61 // 雨
+	case id >= 502 && id <= 504:
+		return 65 // 強い雨
+	case id >= 520 && id < 600:
+		return 80 // にわか雨
+	case id >= 600 && id < 700:
+		return 73 // 雪・みぞれ
+	case id == 701 || id == 721 || id == 741:
+		return 45 // 霧・もや
+	case id >= 700 && id < 800:
+		return 3 // 煙・砂塵など
+	case id == 800:
+		return 0 // 快晴
+	case id == 801:
+		return 1 // 晴れ時々曇り
+	case id == 802:
+		return 2 // 曇りがち
+	case id == 803 || id == 804:
+		return 3 // 曇り
+	default:
+		return -1 // 不明
+	}
+}
@@ -0,0 +1,254 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rihow/FamilyDashboard/internal/config"
+	"github.com/rihow/FamilyDashboard/internal/models"
+)
+
+// プロバイダ名（weather.provider / weather.providers[].provider に指定する値）なのです。
+const (
+	ProviderOpenMeteo      = "openmeteo"
+	ProviderOpenWeatherMap = "openweathermap"
+	ProviderMetNorway      = "metno"
+)
+
+// providerTimeout は天気APIリクエストのタイムアウトなのです。
+const providerTimeout = 10 * time.Second
+
+// userAgent は天気APIに送る User-Agent なのです。
+// MET Norway は連絡先を含む User-Agent を必須にしているのです。
+const userAgent = "FamilyDashboard/1.0 (https://github.com/rihow/FamilyDashboard; personal-use)"
+
+// Provider は天気データの取得元なのです。
+// どのプロバイダも同じ models.WeatherResponse に変換して返すので、
+// 利用規約の変更や障害時に設定だけで切り替えられるます。
+type Provider interface {
+	// Name はプロバイダ名（キャッシュの Meta に記録する値）を返すます。
+	Name() string
+	// Fetch は緯度経度の天気を取得して models.WeatherResponse に変換するます。
+	Fetch(ctx context.Context, lat, lon float64, cityName string) (*models.WeatherResponse, error)
+}
+
+// NewProvider は設定に応じた天気プロバイダを作成するます。
+// provider が空の場合は Open-Meteo を使うのです。
+func NewProvider(cfg config.WeatherProvider) (Provider, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case "", ProviderOpenMeteo, "open-meteo":
+		return NewOpenMeteoProvider(cfg.BaseUrl), nil
+	case ProviderOpenWeatherMap:
+		if cfg.ApiKey == "" {
+			return nil, fmt.Errorf("OpenWeatherMap には weather.apiKey が必要です")
+		}
+		return NewOpenWeatherMapProvider(cfg.ApiKey, cfg.BaseUrl), nil
+	case ProviderMetNorway, "met-norway":
+		return NewMetNorwayProvider(cfg.BaseUrl), nil
+	default:
+		return nil, fmt.Errorf("未対応の天気プロバイダです: %s（%s / %s / %s のいずれかを指定してください）",
+			cfg.Provider, ProviderOpenMeteo, ProviderOpenWeatherMap, ProviderMetNorway)
+	}
+}
+
+// newHTTPClient はプロバイダ共通の HTTP クライアントを作るます。
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: providerTimeout}
+}
+
+// getJSON は url を GET して JSON を out に詰めるます。
+// label はエラーメッセージ用のプロバイダ名なのです。
+func getJSON(ctx context.Context, httpClient *http.Client, url, label string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("リクエスト作成失敗するます: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s APIリクエスト失敗するます: %w", label, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s APIエラー: code=%d, body=%s", label, resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s レスポンスパース失敗するます: %w", label, err)
+	}
+	return nil
+}
+
+// tokyoLocation は Asia/Tokyo のロケーションを返すます。
+func tokyoLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		return time.FixedZone("Asia/Tokyo", 9*3600)
+	}
+	return loc
+}
+
+// MaxHourlyHours は時間ごとの予報を現在の時間帯から何時間先まで持つかなのです。
+// /api/weather/hourly の hours の上限でもあるのです。
+const MaxHourlyHours = 48
+
+// hourlyPoint は時刻ごとの予報なのです（プロバイダ共通の中間形式）。
+type hourlyPoint struct {
+	Time          time.Time
+	Temperature   float64
+	PrecipProb    int     // 降水確率（%）
+	PrecipAmount  float64 // 降水量（mm）
+	WeatherCode   int     // WMO天気コード
+	IsDay         bool
+	WindSpeed     float64 // m/s
+	WindDirection float64 // 度
+}
+
+// buildHourly は現在の時間帯から48時間先までの時間ごとの予報を作るます。
+func buildHourly(points []hourlyPoint, now time.Time) []models.HourlyWeather {
+	start := now.Truncate(time.Hour)
+	end := start.Add(MaxHourlyHours * time.Hour)
+	hourly := []models.HourlyWeather{}
+	for _, point := range points {
+		t := point.Time.In(now.Location())
+		if t.Before(start) || !t.Before(end) {
+			continue
+		}
+		hourly = append(hourly, models.HourlyWeather{
+			Time:          t.Format(time.RFC3339),
+			Temperature:   point.Temperature,
+			PrecipProb:    point.PrecipProb,
+			PrecipAmount:  math.Round(point.PrecipAmount*10) / 10,
+			WeatherCode:   point.WeatherCode,
+			Condition:     weatherCodeToCondition(point.WeatherCode),
+			Icon:          dayNightIcon(weatherCodeToIcon(point.WeatherCode), point.IsDay),
+			WindSpeed:     point.WindSpeed,
+			WindDirection: int(math.Round(point.WindDirection)) % 360,
+		})
+	}
+	return hourly
+}
+
+// buildPrecipSlots は時間ごとの予報から、現在時刻より後の3時間区切りの降水確率を最大8スロット返すます。
+// 降水確率は10の倍数に四捨五入するのです（例: 8% -> 10%, 35% -> 40%, 23% -> 20%）。
+func buildPrecipSlots(hourly []models.HourlyWeather, now time.Time) []models.PrecipSlot {
+	slots := []models.PrecipSlot{}
+	for _, hour := range hourly {
+		if len(slots) >= 8 {
+			break
+		}
+		t, err := time.Parse(time.RFC3339, hour.Time)
+		if err != nil {
+			continue
+		}
+		t = t.In(now.Location())
+		if !t.After(now) || t.Hour()%3 != 0 {
+			continue
+		}
+		slots = append(slots, models.PrecipSlot{
+			Time:   fmt.Sprintf("%02d:00", t.Hour()),
+			Precip: roundPrecip(hour.PrecipProb),
+		})
+	}
+	return slots
+}
+
+// roundPrecip は降水確率を10の倍数に四捨五入するます。
+func roundPrecip(precip int) int {
+	return int(math.Round(float64(precip)/10.0) * 10.0)
+}
+
+// forecastPoint は時刻ごとの予報なのです（日別集計用の中間形式）。
+type forecastPoint struct {
+	Time        time.Time
+	Temperature float64
+	WeatherCode int // WMO天気コード
+}
+
+// summarizeDays は時刻ごとの予報を日別（Asia/Tokyo）に集計するます。
+// 最高・最低気温はその日の予報の最大・最小、天候は正午に最も近い時刻の予報を使うのです。
+func summarizeDays(points []forecastPoint, loc *time.Location, maxDays int) []models.WeeklyWeather {
+	type daySummary struct {
+		date     string
+		max, min float64
+		code     int
+		noonDiff time.Duration
+	}
+
+	days := map[string]*daySummary{}
+	for _, point := range points {
+		t := point.Time.In(loc)
+		date := t.Format("2006-01-02")
+		noon := time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, loc)
+		diff := t.Sub(noon)
+		if diff < 0 {
+			diff = -diff
+		}
+
+		day, ok := days[date]
+		if !ok {
+			days[date] = &daySummary{date: date, max: point.Temperature, min: point.Temperature, code: point.WeatherCode, noonDiff: diff}
+			continue
+		}
+		day.max = math.Max(day.max, point.Temperature)
+		day.min = math.Min(day.min, point.Temperature)
+		if diff < day.noonDiff {
+			day.code = point.WeatherCode
+			day.noonDiff = diff
+		}
+	}
+
+	dates := make([]string, 0, len(days))
+	for date := range days {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	if len(dates) > maxDays {
+		dates = dates[:maxDays]
+	}
+
+	weekly := []models.WeeklyWeather{}
+	for _, date := range dates {
+		day := days[date]
+		weekly = append(weekly, models.WeeklyWeather{
+			Date:      day.date,
+			MaxTemp:   day.max,
+			MinTemp:   day.min,
+			Condition: weatherCodeToCondition(day.code),
+			Icon:      weatherCodeToIcon(day.code),
+		})
+	}
+	return weekly
+}
+
+// todayFromWeekly は週間予報から今日の最高・最低気温を求めるます。
+// 今日の予報が残っていない場合（深夜など）は現在の気温を使うのです。
+func todayFromWeekly(weekly []models.WeeklyWeather, current models.CurrentWeather, now time.Time) models.TodayWeather {
+	today := models.TodayWeather{
+		MaxTemp: current.Temperature,
+		MinTemp: current.Temperature,
+		Summary: current.Condition,
+	}
+	if len(weekly) > 0 && weekly[0].Date == now.Format("2006-01-02") {
+		today.MaxTemp = math.Max(weekly[0].MaxTemp, current.Temperature)
+		today.MinTemp = math.Min(weekly[0].MinTemp, current.Temperature)
+	}
+	return today
+}
+
+// apparentTemperature は気温（℃）・湿度（%）・風速（m/s）から体感温度を計算するます。
+// 体感温度を提供しないプロバイダ向けで、Open-Meteo と同じオーストラリア気象局の式なのです。
+func apparentTemperature(temp, humidity, windSpeed float64) float64 {
+	vaporPressure := humidity / 100 * 6.105 * math.Exp(17.27*temp/(237.7+temp))
+	return math.Round((temp+0.33*vaporPressure-0.70*windSpeed-4.00)*10) / 10
+}
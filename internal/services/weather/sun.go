@@ -0,0 +1,95 @@
+package weather
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// julianUnixEpoch は 1970-01-01T00:00:00Z のユリウス日なのです。
+const julianUnixEpoch = 2440587.5
+
+// julian2000 は 2000-01-01T12:00:00Z（J2000.0）のユリウス日なのです。
+const julian2000 = 2451545.0
+
+// sunTimes は緯度経度での date の日（date のロケーション基準）の日の出・日の入りを計算するます。
+// 日の出・日の入りを提供しないプロバイダ向けで、誤差は1〜2分程度なのです（日の出の式 / NOAA の簡略式）。
+// 白夜・極夜で日の出・日の入りが無い日は ok=false なのです。
+func sunTimes(date time.Time, lat, lon float64) (sunrise, sunset time.Time, ok bool) {
+	loc := date.Location()
+	y, m, d := date.Date()
+
+	// その日の正午（UTC）から J2000.0 までの日数なのです
+	noon := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+	n := math.Round(toJulian(noon) - julian2000)
+
+	// 平均太陽時・平均近点角・中心差・黄経
+	meanSolarTime := n - lon/360
+	meanAnomaly := math.Mod(357.5291+0.98560028*meanSolarTime, 360)
+	mRad := degToRad(meanAnomaly)
+	center := 1.9148*math.Sin(mRad) + 0.0200*math.Sin(2*mRad) + 0.0003*math.Sin(3*mRad)
+	eclipticLongitude := math.Mod(meanAnomaly+center+180+102.9372, 360)
+	lambda := degToRad(eclipticLongitude)
+
+	// 南中時刻と赤緯
+	transit := julian2000 + meanSolarTime + 0.0053*math.Sin(mRad) - 0.0069*math.Sin(2*lambda)
+	sinDeclination := math.Sin(lambda) * math.Sin(degToRad(23.4397))
+	cosDeclination := math.Cos(math.Asin(sinDeclination))
+
+	// 日の出・日の入りの時角（大気差と太陽の視半径で -0.833°）
+	phi := degToRad(lat)
+	cosHourAngle := (math.Sin(degToRad(-0.833)) - math.Sin(phi)*sinDeclination) / (math.Cos(phi) * cosDeclination)
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	hourAngle := radToDeg(math.Acos(cosHourAngle))
+
+	sunrise = fromJulian(transit - hourAngle/360).In(loc)
+	sunset = fromJulian(transit + hourAngle/360).In(loc)
+	return sunrise, sunset, true
+}
+
+// isDaytime は t が日の出から日の入りまでの間かを判定するます。
+// 日の出・日の入りが分からない場合は昼として扱うのです。
+func isDaytime(t, sunrise, sunset time.Time) bool {
+	if sunrise.IsZero() || sunset.IsZero() {
+		return true
+	}
+	return !t.Before(sunrise) && t.Before(sunset)
+}
+
+// dayNightIcon はアイコンコードの末尾を昼 "d" / 夜 "n" にそろえるます。
+// 不明（"04u"）はそのままなのです。
+func dayNightIcon(icon string, isDay bool) string {
+	if !strings.HasSuffix(icon, "d") && !strings.HasSuffix(icon, "n") {
+		return icon
+	}
+	if isDay {
+		return icon[:len(icon)-1] + "d"
+	}
+	return icon[:len(icon)-1] + "n"
+}
+
+// formatSunTime は日の出・日の入りを RFC3339 にするます（不明は空）。
+func formatSunTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func toJulian(t time.Time) float64 {
+	return float64(t.Unix())/86400 + julianUnixEpoch
+}
+
+func fromJulian(j float64) time.Time {
+	return time.Unix(int64(math.Round((j-julianUnixEpoch)*86400)), 0).UTC()
+}
+
+func degToRad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func radToDeg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
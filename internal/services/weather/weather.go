@@ -2,77 +2,133 @@ package weather
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"math"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/rihow/FamilyDashboard/internal/cache"
 	"github.com/rihow/FamilyDashboard/internal/models"
+	"github.com/rihow/FamilyDashboard/internal/services/geocode"
 )
 
+// Geocoder は都市名から緯度経度を解決するのです。
+// 通常は geocode.Client（Nominatim、結果は FileCache に長期保存）を使うます。
+type Geocoder interface {
+	GetCoordinates(ctx context.Context, cityName, country string) (*geocode.Location, error)
+}
+
+// defaultWeatherTTL は天気のキャッシュ有効期限の既定値なのです。
+const defaultWeatherTTL = 5 * time.Minute
+
+// Options は天気APIクライアントの設定なのです。
+// ゼロ値の項目は既定値を使うます。
+type Options struct {
+	// WeatherTTL は天気のキャッシュ有効期限なのです（既定5分。refreshIntervals.weatherSec に合わせるのです）。
+	WeatherTTL time.Duration
+	// WeatherStaleWhileRevalidate は有効期限が切れたあと、古い天気を返しつつ裏で取得し直す期間なのです（既定は WeatherTTL と同じ）。
+	WeatherStaleWhileRevalidate time.Duration
+	// AirQualityTTL は大気質・花粉のキャッシュ有効期限なのです（既定1時間。refreshIntervals.airQualitySec に合わせるのです）。
+	AirQualityTTL time.Duration
+	// Geocode は未知の都市の座標を解決するジオコーディングクライアントの設定なのです。
+	Geocode geocode.Options
+}
+
 // Client は天気APIクライアントなのです。
-// Open-Meteo APIを使用して天気情報を取得するます。
+// 座標解決とキャッシュを担当し、天気データの取得は Provider（既定は Open-Meteo）に任せるます。
+// 複数のプロバイダを設定した場合は順番に試し、失敗が続くプロバイダはしばらく休ませるのです。
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
-	fc         *cache.FileCache // キャッシュ機能
+	fc       *cache.FileCache // キャッシュ機能
+	geocoder Geocoder         // 未知の都市の座標解決
 
-	// 都市ごとの座標マップ（ジオコーディング結果をキャッシしたもの）
-	// 形式: "城市名" -> {lat, lon}
-	cityCoords map[string]*geocodeResult
-}
+	breakerMu        sync.Mutex
+	providers        []*providerState // 試す順番のプロバイダ
+	attemptTimeout   time.Duration    // プロバイダ1件あたりのタイムアウト
+	breakerThreshold int              // 休ませるまでの連続失敗回数
+	breakerCooldown  time.Duration    // 休ませる時間
+	now              func() time.Time // 時計（テストで差し替えるため）
 
-// OpenMeteoWeatherResponse は Open-Meteo API のレスポンス構造体なのです。
-// 気象庁データをラップしているため、日本の天気予報データを取得できるます。
-type OpenMeteoWeatherResponse struct {
-	Latitude  float64              `json:"latitude"`
-	Longitude float64              `json:"longitude"`
-	Current   OpenMeteoWeatherData `json:"current"`
-	Daily     OpenMeteoDailyData   `json:"daily"`
-	Hourly    OpenMeteoHourlyData  `json:"hourly"`
-}
+	airQualityURL  string        // Open-Meteo Air Quality API のベースURL
+	airQualityHTTP *http.Client  // 大気質・花粉の取得用
+	airQualityTTL  time.Duration // 大気質・花粉のキャッシュ有効期限
 
-// OpenMeteoWeatherData は Open-Meteo API の現在データなのです。
-type OpenMeteoWeatherData struct {
-	Temperature      float64 `json:"temperature_2m"`
-	RelativeHumidity int     `json:"relative_humidity_2m"`
-	WindSpeed        float64 `json:"wind_speed_10m"`
-	WeatherCode      int     `json:"weather_code"`
-	Time             string  `json:"time"`
-}
+	weatherTTL time.Duration // 天気のキャッシュ有効期限
+	weatherSWR time.Duration // 有効期限が切れたあと古い天気を返しつつ裏で取得し直す期間
 
-// OpenMeteoDailyData は Open-Meteo API の日別予報なのです。
-type OpenMeteoDailyData struct {
-	Time              []string  `json:"time"`
-	MaxTemperature    []float64 `json:"temperature_2m_max"`
-	MinTemperature    []float64 `json:"temperature_2m_min"`
-	PrecipitationProb []int     `json:"precipitation_probability_max"`
-	WeatherCode       []int     `json:"weather_code"`
-}
+	revalidateMu sync.Mutex
+	revalidating map[string]bool // 裏で取得し直している天気のキャッシュキー
+	revalidateWG sync.WaitGroup  // 裏の取得（テストで終わりを待つため）
 
-// OpenMeteoHourlyData は Open-Meteo API の時間別降水確率なのです。
-type OpenMeteoHourlyData struct {
-	Time              []string `json:"time"`
-	PrecipitationProb []int    `json:"precipitation_probability"`
+	history *HistoryStore // 観測値の記録（nil なら記録しないのです）
+
+	mu sync.RWMutex
+	// 都市ごとの座標マップ（オフラインでも使える初期データ）
+	// 形式: "城市名" -> {lat, lon}
+	cityCoords map[string]*geocodeResult
+	// 設定で明示された座標（"国:都市名" -> {lat, lon}）。他より優先するのです
+	overrides map[string]*geocodeResult
+	// ジオコーディングで解決した座標（"国:都市名" -> {lat, lon}）
+	resolved map[string]*geocodeResult
 }
 
 // NewClient は天気APIクライアントを作成するます。
 // geocodeURL はこのサーバー自身の URL (例: http://localhost:8080) です。
-// 緯度経度を取得するために使用するます。
-func NewClient(fc *cache.FileCache, geocodeURL string) *Client {
+// 未知の都市の緯度経度は geocode パッケージ（Nominatim）で解決するます。
+// プロバイダは Open-Meteo で、設定に応じて SetProviders で切り替えるのです。
+// キャッシュの有効期限などは opts で指定するのです（ゼロ値なら既定値）。
+func NewClient(fc *cache.FileCache, geocodeURL string, opts Options) *Client {
+	if opts.WeatherTTL <= 0 {
+		opts.WeatherTTL = defaultWeatherTTL
+	}
+	if opts.WeatherStaleWhileRevalidate <= 0 {
+		opts.WeatherStaleWhileRevalidate = opts.WeatherTTL
+	}
+	if opts.AirQualityTTL <= 0 {
+		opts.AirQualityTTL = defaultAirQualityTTL
+	}
 	return &Client{
-		baseURL: "https://api.open-meteo.com/v1/forecast",
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		fc:         fc,
-		cityCoords: initCityCoordinates(),
+		fc:               fc,
+		geocoder:         geocode.NewClient(fc, opts.Geocode),
+		providers:        []*providerState{{provider: NewOpenMeteoProvider("")}},
+		attemptTimeout:   defaultAttemptTimeout,
+		breakerThreshold: defaultBreakerThreshold,
+		breakerCooldown:  defaultBreakerCooldown,
+		now:              time.Now,
+		airQualityURL:    defaultAirQualityBaseURL,
+		airQualityHTTP:   newHTTPClient(),
+		airQualityTTL:    opts.AirQualityTTL,
+		weatherTTL:       opts.WeatherTTL,
+		weatherSWR:       opts.WeatherStaleWhileRevalidate,
+		revalidating:     map[string]bool{},
+		cityCoords:       initCityCoordinates(),
+		overrides:        map[string]*geocodeResult{},
+		resolved:         map[string]*geocodeResult{},
 	}
 }
 
+// SetCoordinates は都市の緯度経度を明示的に設定するます。
+// 設定ファイルの location.latitude/longitude を反映するために使い、
+// ハードコードの座標やジオコーディングより優先されるのです。
+func (c *Client) SetCoordinates(cityName, country string, lat, lon float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.overrides[coordsKey(cityName, country)] = &geocodeResult{Latitude: lat, Longitude: lon}
+}
+
+// SetHistory は取得に成功した天気を記録する履歴ストアを設定するます。
+func (c *Client) SetHistory(history *HistoryStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history = history
+}
+
+// History は履歴ストアを返すます（設定していなければ nil）。
+func (c *Client) History() *HistoryStore {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.history
+}
+
 // initCityCoordinates は 都市名 -> 座標 のマップを初期化するます。
 // 主要城市の座標データをハードコードするます。
 func initCityCoordinates() map[string]*geocodeResult {
@@ -88,21 +144,67 @@ func initCityCoordinates() map[string]*geocodeResult {
 	}
 }
 
+// CacheKey は都市の天気キャッシュキーを返すます。
+// 地点ごと（国・都市名ごと）に別々にキャッシュするのです。
+func CacheKey(cityName, country string) string {
+	return fmt.Sprintf("weather:%s:%s", country, cityName)
+}
+
 // GetWeather は 指定都市の天気情報を取得するます。
 // キャッシュをリスク判定して、有効な場合はそれを返します。
-// 無効な場合は Open-Meteo API から取得して保存するます。
+// 有効期限が切れていても stale-while-revalidate の期間内なら古いキャッシュを返し、裏で取得し直すのです。
+// それより古い場合はプロバイダから取得して保存するます。
 func (c *Client) GetWeather(ctx context.Context, cityName, country string) (*models.WeatherResponse, error) {
-	cacheKey := fmt.Sprintf("weather:%s:%s", country, cityName)
-	ttl := 5 * time.Minute // デフォルト5分
+	cacheKey := CacheKey(cityName, country)
 
 	// キャッシュを読み込もうするます
 	var cachedWeather models.WeatherResponse
-	_, found, stale, err := c.fc.ReadPayload(cacheKey, ttl, &cachedWeather)
+	entry, found, stale, err := c.fc.ReadPayload(cacheKey, c.weatherTTL, &cachedWeather)
 	cachedAvailable := found && err == nil
 	if cachedAvailable && !stale {
 		// キャッシュが有効な場合は返すます
 		return &cachedWeather, nil
 	}
+	if cachedAvailable {
+		if age, ok := c.fc.Age(entry); ok && age <= c.weatherTTL+c.weatherSWR {
+			c.revalidateWeather(cityName, country)
+			return &cachedWeather, nil
+		}
+	}
+
+	return c.RefreshWeather(ctx, cityName, country)
+}
+
+// revalidateWeather は裏でプロバイダから天気を取得し直してキャッシュを更新するます。
+// 同じ地点を取得中なら何もしないのです（リクエストが終わっても取得は続けるのです）。
+func (c *Client) revalidateWeather(cityName, country string) {
+	cacheKey := CacheKey(cityName, country)
+	c.revalidateMu.Lock()
+	if c.revalidating[cacheKey] {
+		c.revalidateMu.Unlock()
+		return
+	}
+	c.revalidating[cacheKey] = true
+	c.revalidateMu.Unlock()
+
+	c.revalidateWG.Add(1)
+	go func() {
+		defer c.revalidateWG.Done()
+		defer func() {
+			c.revalidateMu.Lock()
+			delete(c.revalidating, cacheKey)
+			c.revalidateMu.Unlock()
+		}()
+		if _, err := c.RefreshWeather(context.Background(), cityName, country); err != nil {
+			fmt.Printf("⚠️ 天気の再取得失敗するます（%s）: %v\n", cityName, err)
+		}
+	}()
+}
+
+// RefreshWeather はキャッシュを見ずにプロバイダから天気を取得してキャッシュを更新するます。
+// 取得に失敗した場合は期限切れのキャッシュがあればエラーと一緒に返すのです。
+func (c *Client) RefreshWeather(ctx context.Context, cityName, country string) (*models.WeatherResponse, error) {
+	cacheKey := CacheKey(cityName, country)
 
 	// 緯度経度を取得するます（キャッシュ済み含む）
 	coords, err := c.getCoordinates(ctx, cityName, country)
@@ -110,174 +212,78 @@ func (c *Client) GetWeather(ctx context.Context, cityName, country string) (*mod
 		return nil, fmt.Errorf("緯度経度取得失敗するます: %w", err)
 	}
 
-	// Open-Meteo APIから天気データを取得するます
-	weatherRsp, err := c.fetchFromOpenMeteo(ctx, coords.Latitude, coords.Longitude, cityName)
+	// プロバイダから天気データを取得するます（失敗したら次のプロバイダに切り替えるのです）
+	weatherRsp, providerName, err := c.fetchWithFailover(ctx, coords.Latitude, coords.Longitude, cityName)
 	if err != nil {
-		if cachedAvailable {
+		var cachedWeather models.WeatherResponse
+		if _, found, _, readErr := c.fc.ReadPayload(cacheKey, 0, &cachedWeather); found && readErr == nil {
 			return &cachedWeather, err
 		}
 		return nil, err
 	}
 
-	// キャッシュに保存するます
+	// キャッシュに保存するます（どのプロバイダのデータかを source に記録するのです）
 	_, _ = c.fc.Write(cacheKey, weatherRsp, map[string]string{
 		"city":    cityName,
 		"country": country,
-		"source":  "open-meteo",
+		"source":  providerName,
 	})
 
+	// 観測値を履歴に追記するます（失敗しても天気は返すのです）
+	if history := c.History(); history != nil {
+		if err := history.Append(cityName, country, observationFrom(weatherRsp, providerName, c.now())); err != nil {
+			fmt.Printf("⚠️ 天気の履歴の記録失敗するます: %v\n", err)
+		}
+	}
+
 	return weatherRsp, nil
 }
 
 // getCoordinates は都市の座標情報を取得するます。
-// 内部マップから取得するため、外部APIに依存しません。
+// 明示設定 → ハードコードの初期データ → ジオコーディング（FileCache に長期保存）の順で解決するのです。
 func (c *Client) getCoordinates(ctx context.Context, cityName, country string) (*geocodeResult, error) {
-	// 都市名から座標を検索するます
-	coords, ok := c.cityCoords[cityName]
-	if !ok {
-		return nil, fmt.Errorf("都市 '%s' は設定に登録されていません", cityName)
-	}
+	key := coordsKey(cityName, country)
 
-	return coords, nil
-}
-
-// fetchFromOpenMeteo は Open-Meteo API から天気データを取得するます。
-// 気象庁データベースが統合されているため、日本の天気データも取得できるます。
-func (c *Client) fetchFromOpenMeteo(ctx context.Context, lat, lon float64, cityName string) (*models.WeatherResponse, error) {
-	// Open-Meteo API リクエストを構築するます
-	url := fmt.Sprintf(
-		"%s?latitude=%.2f&longitude=%.2f&current=temperature_2m,relative_humidity_2m,weather_code,wind_speed_10m&daily=weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max&hourly=precipitation_probability&timezone=Asia/Tokyo&forecast_days=7",
-		c.baseURL, lat, lon,
-	)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("リクエスト作成失敗するます: %w", err)
-	}
-
-	// User-Agent を設定するます
-	req.Header.Set("User-Agent", "FamilyDashboard/1.0 (https://github.com/rihow/FamilyDashboard; personal-use)")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("Open-Meteo APIリクエスト失敗するます: %w", err)
+	c.mu.RLock()
+	coords, ok := c.overrides[key]
+	if !ok {
+		coords, ok = c.cityCoords[cityName]
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("APIエラー: code=%d, body=%s", resp.StatusCode, string(body))
+	if !ok {
+		coords, ok = c.resolved[key]
 	}
-
-	var omResp OpenMeteoWeatherResponse
-	if err := json.NewDecoder(resp.Body).Decode(&omResp); err != nil {
-		return nil, fmt.Errorf("レスポンスパース失敗するます: %w", err)
+	c.mu.RUnlock()
+	if ok {
+		return coords, nil
 	}
 
-	// Open-Meteo のレスポンスを models.WeatherResponse に変換するます
-	weatherRsp := c.convertToWeatherResponse(&omResp, cityName)
-	return weatherRsp, nil
-}
-
-// convertToWeatherResponse は Open-Meteo レスポンスを models.WeatherResponse に変換するます。
-func (c *Client) convertToWeatherResponse(omResp *OpenMeteoWeatherResponse, cityName string) *models.WeatherResponse {
-	condition := weatherCodeToCondition(omResp.Current.WeatherCode)
-	icon := weatherCodeToIcon(omResp.Current.WeatherCode)
-
-	// 現在の天候
-	current := models.CurrentWeather{
-		Temperature: omResp.Current.Temperature,
-		Condition:   condition,
-		Icon:        icon,
-		Humidity:    omResp.Current.RelativeHumidity,
-		WindSpeed:   omResp.Current.WindSpeed,
+	if c.geocoder == nil {
+		return nil, fmt.Errorf("都市 '%s' は設定に登録されていません", cityName)
 	}
 
-	// 今日の天況
-	today := models.TodayWeather{
-		MaxTemp: omResp.Daily.MaxTemperature[0],
-		MinTemp: omResp.Daily.MinTemperature[0],
-		Summary: condition,
+	location, err := c.geocoder.GetCoordinates(ctx, cityName, country)
+	if err != nil {
+		return nil, fmt.Errorf("都市 '%s' のジオコーディング失敗: %w", cityName, err)
 	}
 
-	// 時間帯ごとの降水確率を取得するます（現在時刻から次の3時間区切りから8スロット分）
-	precipSlots := []models.PrecipSlot{}
-	jst := time.FixedZone("Asia/Tokyo", 9*3600)
-	now := time.Now().In(jst)
-
-	// hourly データから現在時刻以降の3時間区切りのものを8個取得するます
-	for i := 0; i < len(omResp.Hourly.Time) && len(precipSlots) < 8; i++ {
-		// 時刻文字列をパースして時間を取得するます（Asia/Tokyoとして扱う）
-		t, err := time.ParseInLocation("2006-01-02T15:04", omResp.Hourly.Time[i], jst)
-		if err != nil {
-			continue
-		}
-
-		// 現在時刻より未来で、かつ3時間区切りの時刻のみを取得するます
-		if t.After(now) && t.Hour()%3 == 0 {
-			// 降水確率を10の倍数に四捨五入するます（例: 8% -> 10%, 35% -> 40%, 23% -> 20%）
-			precipRounded := int(math.Round(float64(omResp.Hourly.PrecipitationProb[i])/10.0) * 10.0)
-			precipSlots = append(precipSlots, models.PrecipSlot{
-				Time:   fmt.Sprintf("%02d:00", t.Hour()),
-				Precip: precipRounded,
-			})
-		}
-	}
+	coords = &geocodeResult{Latitude: location.Latitude, Longitude: location.Longitude}
+	c.mu.Lock()
+	c.resolved[key] = coords
+	c.mu.Unlock()
 
-	// 週間天気予報を取得するます（7日分）
-	weekly := []models.WeeklyWeather{}
-	for i := 0; i < len(omResp.Daily.Time) && i < 7; i++ {
-		weekly = append(weekly, models.WeeklyWeather{
-			Date:      omResp.Daily.Time[i],
-			MaxTemp:   omResp.Daily.MaxTemperature[i],
-			MinTemp:   omResp.Daily.MinTemperature[i],
-			Condition: weatherCodeToCondition(omResp.Daily.WeatherCode[i]),
-			Icon:      weatherCodeToIcon(omResp.Daily.WeatherCode[i]),
-		})
-	}
+	fmt.Printf("📍 都市 '%s' の座標を解決しました: %.4f, %.4f\n", cityName, coords.Latitude, coords.Longitude)
+	return coords, nil
+}
 
-	// 注意報・警報はここでは空にするます
-	// （Open-Meteo では警報提供がないため、後で別途実装するます）
-	alerts := []models.WeatherAlert{}
-
-	return &models.WeatherResponse{
-		Location:    cityName,
-		Current:     current,
-		Today:       today,
-		PrecipSlots: precipSlots,
-		Weekly:      weekly,
-		Alerts:      alerts,
-	}
+// coordsKey は座標マップのキーを返すます。
+func coordsKey(cityName, country string) string {
+	return country + ":" + cityName
 }
 
-// weatherCodeToCondition は WMO天気コードを日本語の気象情報に変換するます。
+// weatherCodeToCondition は WMO天気コードを日本語（ja-hiragana）の気象情報に変換するます。
+// キャッシュにはこの文言で保存し、返すときに Localizer で表示言語に置き換えるのです。
 func weatherCodeToCondition(code int) string {
-	switch code {
-	case 0:
-		return "はれ"
-	case 1, 2:
-		return "くもり"
-	case 3:
-		return "くもり"
-	case 45, 48:
-		return "きり"
-	case 51, 53, 55:
-		return "こさめ"
-	case 61, 63, 65:
-		return "あめ"
-	case 71, 73, 75:
-		return "ゆき"
-	case 77:
-		return "ふぶき"
-	case 80, 81, 82:
-		return "はげしいあめ"
-	case 85, 86:
-		return "にわかあめ"
-	case 95, 96, 99:
-		return "らいう"
-	default:
-		return "てんこうふめい"
-	}
+	return canonicalCatalog.conditions[conditionKey(code)]
 }
 
 // weatherCodeToIcon は WMO天気コードをアイコンコードに変換するます。
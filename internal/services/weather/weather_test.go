@@ -1,55 +1,65 @@
 package weather
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/rihow/FamilyDashboard/internal/cache"
+	"github.com/rihow/FamilyDashboard/internal/config"
+	"github.com/rihow/FamilyDashboard/internal/models"
+	"github.com/rihow/FamilyDashboard/internal/services/geocode"
 )
 
-// TestConvertToWeatherResponse は気象コード変換テストなのです。
-func TestConvertToWeatherResponse(t *testing.T) {
-	fc := cache.New("./data/cache")
-	c := NewClient(fc, "http://localhost:8080")
-
-	jst := time.FixedZone("Asia/Tokyo", 9*3600)
-	now := time.Now().In(jst)
-	today := now.Format("2006-01-02")
-
-	hourlyTimes := make([]string, 0, 8)
-	hourlyPrecip := make([]int, 0, 8)
-	for i := 1; i <= 8; i++ {
-		t := now.Add(time.Duration(i*3) * time.Hour)
-		hourlyTimes = append(hourlyTimes, t.Format("2006-01-02T15:04"))
-		hourlyPrecip = append(hourlyPrecip, 10)
-	}
-
-	// ダミー Open-Meteo レスポンスを作成するます
-	dummyResp := &OpenMeteoWeatherResponse{
-		Latitude:  34.815,
-		Longitude: 134.685,
-		Current: OpenMeteoWeatherData{
-			Temperature:      15.5,
-			RelativeHumidity: 60,
-			WindSpeed:        3.2,
-			WeatherCode:      2,
-			Time:             time.Now().Format(time.RFC3339),
-		},
-		Daily: OpenMeteoDailyData{
-			Time:              []string{today},
-			MaxTemperature:    []float64{18.0},
-			MinTemperature:    []float64{10.0},
-			PrecipitationProb: []int{15},
-			WeatherCode:       []int{2},
-		},
-		Hourly: OpenMeteoHourlyData{
-			Time:              hourlyTimes,
-			PrecipitationProb: hourlyPrecip,
-		},
+// fixtureNow はテスト用 fixture の基準時刻（2025-07-15 16:30 JST）なのです。
+func fixtureNow() time.Time {
+	return time.Date(2025, 7, 15, 16, 30, 0, 0, tokyoLocation())
+}
+
+// loadFixture は testdata の JSON を読み込んで out に詰めるます。
+func loadFixture(t *testing.T, name string, out any) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("fixture 読み込み失敗: %v", err)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("fixture パース失敗: %v", err)
+		}
+	}
+	return data
+}
+
+// assertPrecipSlots は降水確率スロットを比較するます。
+func assertPrecipSlots(t *testing.T, got []models.PrecipSlot, want []models.PrecipSlot) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("PrecipSlots: 期待: %v, 実際: %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("PrecipSlots[%d]: 期待: %v, 実際: %v", i, want[i], got[i])
+		}
 	}
+}
+
+// TestConvertToWeatherResponse は Open-Meteo レスポンスの変換テストなのです。
+func TestConvertToWeatherResponse(t *testing.T) {
+	var omResp OpenMeteoWeatherResponse
+	loadFixture(t, "openmeteo_forecast.json", &omResp)
 
 	// 変換を実行するます
-	result := c.convertToWeatherResponse(dummyResp, "姫路市")
+	result := convertOpenMeteo(&omResp, "姫路市", fixtureNow())
 
 	// アサーションをするます
 	if result.Location != "姫路市" {
@@ -64,6 +74,24 @@ func TestConvertToWeatherResponse(t *testing.T) {
 		t.Errorf("Condition: 期待: くもり, 実際: %s", result.Current.Condition)
 	}
 
+	if c := result.Current; c.FeelsLike != 14.2 || c.WindDirection != 225 || c.WindGust != 6.8 || c.Pressure != 1012.4 || c.UVIndex != 3.15 {
+		t.Errorf("Current: 実際: %+v", c)
+	}
+
+	if !result.Current.IsDay || result.Current.Icon != "03d" {
+		t.Errorf("昼のアイコン: 期待: 03d, 実際: %s (isDay=%v)", result.Current.Icon, result.Current.IsDay)
+	}
+
+	if result.Today.Sunrise != "2025-07-15T04:59:00+09:00" || result.Today.Sunset != "2025-07-15T19:17:00+09:00" || result.Today.UVIndexMax != 7.45 {
+		t.Errorf("Today: 実際: %+v", result.Today)
+	}
+
+	// 日の入り後は夜のアイコンになる
+	night := convertOpenMeteo(&omResp, "姫路市", fixtureNow().Add(3*time.Hour))
+	if night.Current.IsDay || night.Current.Icon != "03n" {
+		t.Errorf("夜のアイコン: 期待: 03n, 実際: %s (isDay=%v)", night.Current.Icon, night.Current.IsDay)
+	}
+
 	if result.Today.MaxTemp != 18.0 {
 		t.Errorf("MaxTemp: 期待: 18.0, 実際: %f", result.Today.MaxTemp)
 	}
@@ -72,8 +100,852 @@ func TestConvertToWeatherResponse(t *testing.T) {
 		t.Errorf("MinTemp: 期待: 10.0, 実際: %f", result.Today.MinTemp)
 	}
 
-	if len(result.PrecipSlots) == 0 {
-		t.Errorf("PrecipSlots: 期待: > 0, 実際: %d", len(result.PrecipSlots))
+	if len(result.Weekly) != 2 || result.Weekly[1].Condition != "あめ" {
+		t.Errorf("Weekly: 実際: %+v", result.Weekly)
+	}
+
+	// 現在時刻より後の3時間区切りだけ、10の倍数に丸める
+	assertPrecipSlots(t, result.PrecipSlots, []models.PrecipSlot{
+		{Time: "18:00", Precip: 10},
+		{Time: "21:00", Precip: 20},
+	})
+
+	// 時間ごとの予報は現在の時間帯（16時）から。降水量は次の時刻の「前1時間の降水量」を使う
+	if len(result.Hourly) != 6 {
+		t.Fatalf("Hourly: 期待: 6件, 実際: %+v", result.Hourly)
+	}
+	want := models.HourlyWeather{
+		Time: "2025-07-15T16:00:00+09:00", Temperature: 15.8, PrecipProb: 80, PrecipAmount: 0.3,
+		WeatherCode: 61, Condition: "あめ", Icon: "10d", WindSpeed: 3.6, WindDirection: 210,
+	}
+	if result.Hourly[0] != want {
+		t.Errorf("Hourly[0]: 期待: %+v, 実際: %+v", want, result.Hourly[0])
+	}
+	if h := result.Hourly[4]; h.Time != "2025-07-15T20:00:00+09:00" || h.Icon != "09n" || h.PrecipAmount != 0 {
+		t.Errorf("Hourly[4]: 実際: %+v", h)
+	}
+	if h := result.Hourly[5]; h.WindDirection != 0 || h.PrecipAmount != 0 {
+		t.Errorf("Hourly[5]: 実際: %+v", h)
+	}
+}
+
+// TestConvertOpenWeatherMap は OpenWeatherMap レスポンスの変換テストなのです。
+func TestConvertOpenWeatherMap(t *testing.T) {
+	var current OpenWeatherMapCurrentResponse
+	var forecast OpenWeatherMapForecastResponse
+	loadFixture(t, "openweathermap_current.json", &current)
+	loadFixture(t, "openweathermap_forecast.json", &forecast)
+
+	result := convertOpenWeatherMap(&current, &forecast, "姫路市", fixtureNow())
+
+	if result.Current.Temperature != 18.4 || result.Current.Humidity != 82 || result.Current.WindSpeed != 4.1 {
+		t.Errorf("Current: 実際: %+v", result.Current)
+	}
+	if result.Current.Condition != "あめ" || result.Current.Icon != "10d" {
+		t.Errorf("Current condition: 実際: %s %s", result.Current.Condition, result.Current.Icon)
+	}
+	if c := result.Current; c.FeelsLike != 18.1 || c.WindDirection != 200 || c.WindGust != 7.3 || c.Pressure != 1008 || !c.IsDay {
+		t.Errorf("Current: 実際: %+v", c)
+	}
+	if result.Today.Sunrise != "2025-07-15T05:02:00+09:00" || result.Today.Sunset != "2025-07-15T19:15:00+09:00" {
+		t.Errorf("Today sun: 実際: %s - %s", result.Today.Sunrise, result.Today.Sunset)
+	}
+	night := convertOpenWeatherMap(&current, &forecast, "姫路市", fixtureNow().Add(3*time.Hour))
+	if night.Current.IsDay || night.Current.Icon != "10n" {
+		t.Errorf("夜のアイコン: 期待: 10n, 実際: %s", night.Current.Icon)
+	}
+	// 今日の最高・最低は今日の予報と現在の気温から求める
+	if result.Today.MaxTemp != 22.0 || result.Today.MinTemp != 18.4 {
+		t.Errorf("Today: 実際: %+v", result.Today)
+	}
+	if len(result.Weekly) != 2 {
+		t.Fatalf("Weekly: 実際: %+v", result.Weekly)
+	}
+	if w := result.Weekly[1]; w.Date != "2025-07-16" || w.MaxTemp != 25.3 || w.MinTemp != 15.8 || w.Condition != "はれ" {
+		t.Errorf("Weekly[1]: 実際: %+v", w)
+	}
+	assertPrecipSlots(t, result.PrecipSlots, []models.PrecipSlot{
+		{Time: "18:00", Precip: 60},
+		{Time: "21:00", Precip: 30},
+		{Time: "00:00", Precip: 10},
+		{Time: "03:00", Precip: 0},
+		{Time: "06:00", Precip: 0},
+		{Time: "09:00", Precip: 10},
+		{Time: "12:00", Precip: 0},
+		{Time: "15:00", Precip: 0},
+	})
+
+	// 時間ごとの予報は3時間ごとで、昼夜は sys.pod で判定する（fixture の9件すべてが48時間以内）
+	if len(result.Hourly) != 9 {
+		t.Fatalf("Hourly: 期待: 9件, 実際: %d件", len(result.Hourly))
+	}
+	if h := result.Hourly[0]; h.Time != "2025-07-15T18:00:00+09:00" || h.Temperature != 19.5 || h.PrecipProb != 62 || h.PrecipAmount != 1.9 || h.Icon != "10d" || h.WindDirection != 180 {
+		t.Errorf("Hourly[0]: 実際: %+v", h)
+	}
+	if h := result.Hourly[1]; h.Time != "2025-07-15T21:00:00+09:00" || h.Icon != "03n" || h.WindSpeed != 2.3 {
+		t.Errorf("Hourly[1]: 実際: %+v", h)
+	}
+}
+
+// TestConvertMetNorway は MET Norway レスポンスの変換テストなのです。
+func TestConvertMetNorway(t *testing.T) {
+	var metResp MetNorwayResponse
+	loadFixture(t, "metno_complete.json", &metResp)
+
+	result := convertMetNorway(&metResp, "姫路市", fixtureNow())
+
+	if result.Current.Temperature != 27.3 || result.Current.Humidity != 72 || result.Current.WindSpeed != 3.4 {
+		t.Errorf("Current: 実際: %+v", result.Current)
+	}
+	if result.Current.Icon != "03d" {
+		t.Errorf("Current icon: 期待: 03d, 実際: %s", result.Current.Icon)
+	}
+	// 体感温度は気温・湿度・風速から計算する
+	if c := result.Current; c.FeelsLike != 29.5 || c.WindDirection != 210 || c.WindGust != 6.2 || c.Pressure != 1009.8 || c.UVIndex != 4.1 {
+		t.Errorf("Current: 実際: %+v", c)
+	}
+	// 日の出・日の入りはレスポンスの座標から計算する（姫路: 4:59 / 19:17 ごろ）
+	assertSunTime(t, "Sunrise", result.Today.Sunrise, time.Date(2025, 7, 15, 4, 59, 0, 0, tokyoLocation()))
+	assertSunTime(t, "Sunset", result.Today.Sunset, time.Date(2025, 7, 15, 19, 17, 0, 0, tokyoLocation()))
+	if result.Today.UVIndexMax != 4.1 {
+		t.Errorf("UVIndexMax: 期待: 4.1, 実際: %v", result.Today.UVIndexMax)
+	}
+	if result.Today.MaxTemp != 27.3 || result.Today.MinTemp != 23.2 {
+		t.Errorf("Today: 実際: %+v", result.Today)
+	}
+	if len(result.Weekly) != 2 || result.Weekly[1].MaxTemp != 30.5 {
+		t.Errorf("Weekly: 実際: %+v", result.Weekly)
+	}
+	// 降水確率があればそれを、無ければ降水量からの目安を使う
+	assertPrecipSlots(t, result.PrecipSlots, []models.PrecipSlot{
+		{Time: "18:00", Precip: 60},
+		{Time: "21:00", Precip: 0},
+	})
+
+	// 時間ごとの予報は1時間予報がある時刻だけ。末尾の無いシンボルは日の入りで昼夜を判定する
+	if len(result.Hourly) != 4 {
+		t.Fatalf("Hourly: 期待: 4件, 実際: %+v", result.Hourly)
+	}
+	if h := result.Hourly[0]; h.Icon != "03d" || h.WindDirection != 210 {
+		t.Errorf("Hourly[0]: 実際: %+v", h)
+	}
+	if h := result.Hourly[2]; h.Time != "2025-07-15T18:00:00+09:00" || h.Icon != "10d" || h.PrecipProb != 64 || h.PrecipAmount != 1.4 {
+		t.Errorf("Hourly[2]: 実際: %+v", h)
+	}
+	if h := result.Hourly[3]; h.Time != "2025-07-15T21:00:00+09:00" || h.Icon != "03n" {
+		t.Errorf("Hourly[3]: 実際: %+v", h)
+	}
+
+	if got := metSymbolToWMO("heavyrainandthunder"); got != 95 {
+		t.Errorf("heavyrainandthunder: 期待: 95, 実際: %d", got)
+	}
+	if got := metSymbolToWMO("clearsky_night"); got != 0 {
+		t.Errorf("clearsky_night: 期待: 0, 実際: %d", got)
+	}
+}
+
+// TestBuildHourly は時間ごとの予報の範囲（現在の時間帯から48時間）と降水確率スロットのテストなのです。
+func TestBuildHourly(t *testing.T) {
+	now := fixtureNow()
+	start := time.Date(2025, 7, 15, 15, 0, 0, 0, now.Location())
+	points := []hourlyPoint{}
+	for i := 0; i < 60; i++ {
+		points = append(points, hourlyPoint{Time: start.Add(time.Duration(i) * time.Hour), PrecipProb: i, WeatherCode: 0, IsDay: true})
+	}
+
+	hourly := buildHourly(points, now)
+	if len(hourly) != MaxHourlyHours {
+		t.Fatalf("Hourly: 期待: %d件, 実際: %d件", MaxHourlyHours, len(hourly))
+	}
+	if hourly[0].Time != "2025-07-15T16:00:00+09:00" || hourly[len(hourly)-1].Time != "2025-07-17T15:00:00+09:00" {
+		t.Errorf("Hourly の範囲: 実際: %s 〜 %s", hourly[0].Time, hourly[len(hourly)-1].Time)
+	}
+
+	// スロットは Hourly から3時間区切りで最大8件
+	slots := buildPrecipSlots(hourly, now)
+	if len(slots) != 8 || slots[0] != (models.PrecipSlot{Time: "18:00", Precip: 0}) || slots[7] != (models.PrecipSlot{Time: "15:00", Precip: 20}) {
+		t.Errorf("PrecipSlots: 実際: %+v", slots)
+	}
+}
+
+// assertSunTime は日の出・日の入りが期待時刻の前後3分以内かを確認するます。
+func assertSunTime(t *testing.T, label, got string, want time.Time) {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, got)
+	if err != nil {
+		t.Fatalf("%s: パース失敗: %q", label, got)
+	}
+	if diff := parsed.Sub(want); diff < -3*time.Minute || diff > 3*time.Minute {
+		t.Errorf("%s: 期待: %s 前後, 実際: %s", label, want.Format("15:04"), parsed.In(want.Location()).Format("15:04"))
+	}
+}
+
+// TestSunTimes は日の出・日の入り計算と昼夜アイコンのテストなのです。
+func TestSunTimes(t *testing.T) {
+	loc := tokyoLocation()
+	sunrise, sunset, ok := sunTimes(time.Date(2025, 7, 15, 12, 0, 0, 0, loc), 35.6895, 139.6917)
+	if !ok {
+		t.Fatal("東京の日の出・日の入りが計算できません")
+	}
+	assertSunTime(t, "東京 夏 日の出", formatSunTime(sunrise), time.Date(2025, 7, 15, 4, 38, 0, 0, loc))
+	assertSunTime(t, "東京 夏 日の入り", formatSunTime(sunset), time.Date(2025, 7, 15, 18, 59, 0, 0, loc))
+
+	sunrise, sunset, _ = sunTimes(time.Date(2025, 12, 22, 0, 0, 0, 0, loc), 35.6895, 139.6917)
+	assertSunTime(t, "東京 冬 日の出", formatSunTime(sunrise), time.Date(2025, 12, 22, 6, 47, 0, 0, loc))
+	assertSunTime(t, "東京 冬 日の入り", formatSunTime(sunset), time.Date(2025, 12, 22, 16, 32, 0, 0, loc))
+
+	// 白夜（夏至ごろの北極圏）は計算できない
+	if _, _, ok := sunTimes(time.Date(2025, 6, 21, 12, 0, 0, 0, time.UTC), 78.22, 15.65); ok {
+		t.Error("白夜は ok=false になるはずです")
+	}
+
+	noon := time.Date(2025, 7, 15, 12, 0, 0, 0, loc)
+	if !isDaytime(noon, time.Time{}, time.Time{}) {
+		t.Error("日の出・日の入りが不明なら昼として扱うはずです")
+	}
+
+	tests := []struct {
+		icon  string
+		isDay bool
+		want  string
+	}{
+		{"01d", true, "01d"},
+		{"01d", false, "01n"},
+		{"10n", true, "10d"},
+		{"04u", false, "04u"},
+	}
+	for _, tt := range tests {
+		if got := dayNightIcon(tt.icon, tt.isDay); got != tt.want {
+			t.Errorf("dayNightIcon(%s, %v): 期待: %s, 実際: %s", tt.icon, tt.isDay, tt.want, got)
+		}
+	}
+}
+
+// adviceWeather は 2025-07-15 の1時間ごとの穏やかな天気（アドバイス無し）を作るます。
+// modify で時刻（時）ごとの予報を変えられるのです。
+func adviceWeather(modify func(hour int, h *models.HourlyWeather)) *models.WeatherResponse {
+	loc := tokyoLocation()
+	weatherRsp := &models.WeatherResponse{
+		Current: models.CurrentWeather{Temperature: 25, FeelsLike: 26, WindSpeed: 3, UVIndex: 3},
+		Today:   models.TodayWeather{MaxTemp: 29, MinTemp: 22, UVIndexMax: 5},
+	}
+	for hour := 0; hour < 24; hour++ {
+		h := models.HourlyWeather{
+			Time:        time.Date(2025, 7, 15, hour, 0, 0, 0, loc).Format(time.RFC3339),
+			Temperature: 25,
+			PrecipProb:  10,
+			WindSpeed:   3,
+		}
+		if modify != nil {
+			modify(hour, &h)
+		}
+		weatherRsp.Hourly = append(weatherRsp.Hourly, h)
+	}
+	return weatherRsp
+}
+
+// TestBuildAdvice はアドバイスのルール判定のテストなのです。
+func TestBuildAdvice(t *testing.T) {
+	morning := time.Date(2025, 7, 15, 6, 30, 0, 0, tokyoLocation())
+	rainAt := func(rainHour, prob int, amount float64) func(int, *models.HourlyWeather) {
+		return func(hour int, h *models.HourlyWeather) {
+			if hour == rainHour {
+				h.PrecipProb = prob
+				h.PrecipAmount = amount
+			}
+		}
+	}
+	coldTemp := 0.0
+
+	tests := []struct {
+		name   string
+		rsp    *models.WeatherResponse
+		rules  config.Advice
+		now    time.Time
+		want   []models.Advice
+		modify func(*models.WeatherResponse)
+	}{
+		{
+			name: "穏やかな日はアドバイス無し",
+			rsp:  adviceWeather(nil),
+			now:  morning,
+			want: []models.Advice{},
+		},
+		{
+			name: "帰りの時間帯に雨なら傘",
+			rsp:  adviceWeather(rainAt(18, 60, 0)),
+			now:  morning,
+			want: []models.Advice{{Type: config.AdviceUmbrella, Message: "傘を持っていこう", Reason: "18時ごろ 降水確率60%", Level: AdviceLevelInfo}},
+		},
+		{
+			name: "日中に雨量があれば部屋干し",
+			rsp:  adviceWeather(rainAt(12, 20, 0.5)),
+			now:  morning,
+			want: []models.Advice{{Type: config.AdviceLaundry, Message: "洗濯物は部屋干し", Reason: "12時ごろ 雨量0.5mm", Level: AdviceLevelInfo}},
+		},
+		{
+			name: "過ぎた時間帯の雨は見ない",
+			rsp:  adviceWeather(rainAt(8, 80, 2)),
+			now:  time.Date(2025, 7, 15, 20, 0, 0, 0, tokyoLocation()),
+			want: []models.Advice{},
+		},
+		{
+			name:  "無効にしたルールは判定しない",
+			rsp:   adviceWeather(rainAt(8, 80, 0)),
+			rules: config.Advice{Disabled: []string{config.AdviceUmbrella}},
+			now:   morning,
+			want:  []models.Advice{},
+		},
+		{
+			name:  "通勤時間帯と降水確率は設定できる",
+			rsp:   adviceWeather(rainAt(22, 40, 0)),
+			rules: config.Advice{CommuteHours: []config.TimeWindow{{Start: "21:30", End: "23:00"}}, UmbrellaPrecipProb: 30},
+			now:   morning,
+			want:  []models.Advice{{Type: config.AdviceUmbrella, Message: "傘を持っていこう", Reason: "22時ごろ 降水確率40%", Level: AdviceLevelInfo}},
+		},
+		{
+			name: "暑さ・UV・強風はルールの順に並ぶ",
+			rsp: adviceWeather(func(hour int, h *models.HourlyWeather) {
+				if hour == 14 {
+					h.Temperature = 34
+				}
+				if hour == 20 {
+					h.WindSpeed = 12
+				}
+			}),
+			now: morning,
+			modify: func(rsp *models.WeatherResponse) {
+				rsp.Today.UVIndexMax = 8.2
+			},
+			want: []models.Advice{
+				{Type: config.AdviceHeat, Message: "熱中症注意", Reason: "最高気温34℃", Level: AdviceLevelWarning},
+				{Type: config.AdviceUV, Message: "日焼け止めをぬろう", Reason: "UVインデックス8", Level: AdviceLevelInfo},
+				{Type: config.AdviceWind, Message: "強風注意", Reason: "風速12m/s", Level: AdviceLevelWarning},
+			},
+		},
+		{
+			name:  "防寒の気温は0℃も指定できる",
+			rsp:   adviceWeather(nil),
+			rules: config.Advice{ColdTemp: &coldTemp},
+			now:   morning,
+			modify: func(rsp *models.WeatherResponse) {
+				rsp.Today.MinTemp = -1
+			},
+			want: []models.Advice{{Type: config.AdviceCold, Message: "上着を持っていこう", Reason: "最低気温-1℃", Level: AdviceLevelInfo}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.modify != nil {
+				tt.modify(tt.rsp)
+			}
+			got := BuildAdvice(tt.rsp, tt.rules, NewLocalizer(config.LocaleJaKanji, config.Units{}), tt.now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("期待: %+v, 実際: %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestBuildAdviceThreeHourly は3時間ごとの予報（OpenWeatherMap）を次の予報までの時間帯として判定するテストなのです。
+func TestBuildAdviceThreeHourly(t *testing.T) {
+	loc := tokyoLocation()
+	weatherRsp := &models.WeatherResponse{Today: models.TodayWeather{MaxTemp: 25, MinTemp: 20}}
+	for _, hour := range []int{6, 9, 12, 15, 18} {
+		prob := 0
+		if hour == 6 {
+			prob = 70 // 06:00〜09:00 は朝の通勤時間帯（07:00〜09:00）と重なる
+		}
+		weatherRsp.Hourly = append(weatherRsp.Hourly, models.HourlyWeather{
+			Time:        time.Date(2025, 7, 15, hour, 0, 0, 0, loc).Format(time.RFC3339),
+			Temperature: 22,
+			PrecipProb:  prob,
+		})
+	}
+
+	got := BuildAdvice(weatherRsp, config.Advice{}, NewLocalizer(config.LocaleJaKanji, config.Units{}), time.Date(2025, 7, 15, 5, 0, 0, 0, loc))
+	if len(got) != 1 || got[0].Type != config.AdviceUmbrella || got[0].Reason != "6時ごろ 降水確率70%" {
+		t.Errorf("実際: %+v", got)
+	}
+}
+
+// TestNewProvider は設定によるプロバイダ選択テストなのです。
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		cfg      config.WeatherProvider
+		expected string
+		wantErr  bool
+	}{
+		{cfg: config.WeatherProvider{}, expected: ProviderOpenMeteo},
+		{cfg: config.WeatherProvider{Provider: "open-meteo"}, expected: ProviderOpenMeteo},
+		{cfg: config.WeatherProvider{Provider: "OpenWeatherMap", ApiKey: "key"}, expected: ProviderOpenWeatherMap},
+		{cfg: config.WeatherProvider{Provider: "openweathermap"}, wantErr: true},
+		{cfg: config.WeatherProvider{Provider: "metno"}, expected: ProviderMetNorway},
+		{cfg: config.WeatherProvider{Provider: "tenki.jp"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		provider, err := NewProvider(test.cfg)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("%+v: 期待エラー: %v, 実際: %v", test.cfg, test.wantErr, err)
+		}
+		if err == nil && provider.Name() != test.expected {
+			t.Errorf("%+v: 期待: %s, 実際: %s", test.cfg, test.expected, provider.Name())
+		}
+	}
+
+	// バージョンの無い Open-Meteo の URL には /v1 を補う
+	if p := NewOpenMeteoProvider("https://api.open-meteo.com"); p.baseURL != "https://api.open-meteo.com/v1" {
+		t.Errorf("baseURL: 実際: %s", p.baseURL)
+	}
+}
+
+// fakeProvider はテスト用の天気プロバイダなのです。
+type fakeProvider struct {
+	name  string
+	err   error
+	delay time.Duration
+	calls int
+	rsp   *models.WeatherResponse // 返す天気（nil なら Condition にプロバイダ名を入れた天気）
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Fetch(ctx context.Context, lat, lon float64, cityName string) (*models.WeatherResponse, error) {
+	p.calls++
+	if p.delay > 0 {
+		select {
+		case <-time.After(p.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.rsp != nil {
+		return p.rsp, nil
+	}
+	return &models.WeatherResponse{Location: cityName, Current: models.CurrentWeather{Condition: p.name}, Alerts: []models.WeatherAlert{}}, nil
+}
+
+// TestFailover はプロバイダの切り替えとサーキットブレーカーのテストなのです。
+func TestFailover(t *testing.T) {
+	fc := cache.New(t.TempDir())
+	c := NewClient(fc, "http://localhost:8080", Options{})
+	now := fixtureNow()
+	c.now = func() time.Time { return now }
+	c.attemptTimeout = 50 * time.Millisecond
+
+	primary := &fakeProvider{name: "primary", err: errors.New("503")}
+	slow := &fakeProvider{name: "slow", delay: time.Second}
+	backup := &fakeProvider{name: "backup"}
+	c.SetProviders(primary, slow, backup)
+	ctx := context.Background()
+
+	// 失敗・タイムアウトしたプロバイダを飛ばして次を使い、使ったプロバイダを記録する
+	for i := 0; i < defaultBreakerThreshold; i++ {
+		weatherRsp, err := c.RefreshWeather(ctx, "姫路市", "JP")
+		if err != nil || weatherRsp.Current.Condition != "backup" {
+			t.Fatalf("refresh %d: rsp=%+v err=%v", i, weatherRsp, err)
+		}
+	}
+	entry, _, _, err := fc.Read("weather:JP:姫路市", 0)
+	if err != nil || entry.Meta["source"] != "backup" {
+		t.Fatalf("meta source = %v (err=%v)", entry.Meta, err)
+	}
+
+	// しきい値に達したプロバイダは休ませる
+	if _, err := c.RefreshWeather(ctx, "姫路市", "JP"); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if primary.calls != defaultBreakerThreshold || slow.calls != defaultBreakerThreshold {
+		t.Fatalf("休止中に呼ばれました: primary=%d slow=%d", primary.calls, slow.calls)
+	}
+
+	// 休み明けに1回試し、成功すれば元に戻る
+	now = now.Add(defaultBreakerCooldown + time.Second)
+	primary.err = nil
+	weatherRsp, err := c.RefreshWeather(ctx, "姫路市", "JP")
+	if err != nil || weatherRsp.Current.Condition != "primary" {
+		t.Fatalf("recovered: rsp=%+v err=%v", weatherRsp, err)
+	}
+
+	// すべて失敗したら期限切れのキャッシュとエラーを返す
+	primary.err = errors.New("503")
+	backup.err = errors.New("429")
+	slow.delay = 0
+	slow.err = errors.New("500")
+	weatherRsp, err = c.RefreshWeather(ctx, "姫路市", "JP")
+	if err == nil || !strings.Contains(err.Error(), "backup: 429") {
+		t.Fatalf("err = %v", err)
+	}
+	if weatherRsp == nil || weatherRsp.Current.Condition != "primary" {
+		t.Fatalf("stale = %+v", weatherRsp)
+	}
+}
+
+// TestNewProviders は設定順のプロバイダ一覧の作成テストなのです。
+func TestNewProviders(t *testing.T) {
+	providers, err := NewProviders(config.Weather{Providers: []config.WeatherProvider{
+		{Provider: "metno"},
+		{Provider: "openmeteo"},
+	}})
+	if err != nil || len(providers) != 2 || providers[0].Name() != ProviderMetNorway || providers[1].Name() != ProviderOpenMeteo {
+		t.Fatalf("providers = %v, err = %v", providers, err)
+	}
+
+	if _, err := NewProviders(config.Weather{Providers: []config.WeatherProvider{{Provider: "metno"}, {Provider: "unknown"}}}); err == nil {
+		t.Fatalf("不正なプロバイダでエラーになりません")
+	}
+}
+
+// TestProviderFetch は各プロバイダが HTTP で取得してキャッシュに記録されるかのテストなのです。
+func TestProviderFetch(t *testing.T) {
+	fixtures := map[string]string{
+		"/v1/forecast":                   "openmeteo_forecast.json",
+		"/data/2.5/weather":              "openweathermap_current.json",
+		"/data/2.5/forecast":             "openweathermap_forecast.json",
+		"/locationforecast/2.0/complete": "metno_complete.json",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/data/2.5/") && r.URL.Query().Get("appid") != "secret" {
+			http.Error(w, `{"cod":401}`, http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("User-Agent") == "" {
+			http.Error(w, "User-Agent required", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write(loadFixture(t, name, nil))
+	}))
+	defer server.Close()
+
+	providers := []Provider{
+		NewOpenMeteoProvider(server.URL + "/v1"),
+		NewOpenWeatherMapProvider("secret", server.URL+"/data/2.5"),
+		NewMetNorwayProvider(server.URL + "/locationforecast/2.0"),
+	}
+	for _, provider := range providers {
+		fc := cache.New(t.TempDir())
+		c := NewClient(fc, "http://localhost:8080", Options{})
+		c.SetProviders(provider)
+
+		weatherRsp, err := c.RefreshWeather(context.Background(), "姫路市", "JP")
+		if err != nil {
+			t.Fatalf("%s: %v", provider.Name(), err)
+		}
+		if weatherRsp.Location != "姫路市" || weatherRsp.Current.Temperature == 0 || weatherRsp.Alerts == nil {
+			t.Errorf("%s: 実際: %+v", provider.Name(), weatherRsp)
+		}
+
+		entry, found, _, err := fc.Read("weather:JP:姫路市", 0)
+		if err != nil || !found || entry.Meta["source"] != provider.Name() {
+			t.Errorf("%s: キャッシュの source: %v (found=%v err=%v)", provider.Name(), entry.Meta, found, err)
+		}
+	}
+
+	// APIキーが違えばエラー
+	c := NewClient(cache.New(t.TempDir()), "http://localhost:8080", Options{})
+	c.SetProviders(NewOpenWeatherMapProvider("wrong", server.URL+"/data/2.5"))
+	if _, err := c.RefreshWeather(context.Background(), "姫路市", "JP"); err == nil {
+		t.Errorf("不正なAPIキーでエラーになりません")
+	}
+}
+
+// TestConvertAirQuality は大気質・花粉の変換テストなのです。
+func TestConvertAirQuality(t *testing.T) {
+	var aqResp OpenMeteoAirQualityResponse
+	loadFixture(t, "openmeteo_air_quality.json", &aqResp)
+
+	result := convertAirQuality(&aqResp, tokyoLocation())
+	if result.Time != "2025-07-15T16:00:00+09:00" || result.PM25 != 18.4 || result.PM10 != 27.2 || result.AQI != 64 || result.Category != "ふつう" {
+		t.Errorf("AirQuality: 実際: %+v", result)
+	}
+
+	// null の花粉は含めず、表示順に並べる
+	want := []models.PollenLevel{
+		{Type: "grass", Name: "イネ科", Value: 12.3, Level: "ややおおい"},
+		{Type: "birch", Name: "シラカバ", Value: 0, Level: "すくない"},
+		{Type: "mugwort", Name: "ヨモギ", Value: 55, Level: "ひじょうにおおい"},
+	}
+	if len(result.Pollen) != len(want) {
+		t.Fatalf("Pollen: 期待: %+v, 実際: %+v", want, result.Pollen)
+	}
+	for i := range want {
+		if result.Pollen[i] != want[i] {
+			t.Errorf("Pollen[%d]: 期待: %+v, 実際: %+v", i, want[i], result.Pollen[i])
+		}
+	}
+
+	tests := []struct {
+		aqi  int
+		want string
+	}{
+		{0, "よい"}, {50, "よい"}, {51, "ふつう"}, {101, "びんかんなひとはちゅうい"},
+		{151, "わるい"}, {201, "とてもわるい"}, {301, "きけん"},
+	}
+	for _, tt := range tests {
+		if got := aqiCategory(tt.aqi); got != tt.want {
+			t.Errorf("aqiCategory(%d): 期待: %s, 実際: %s", tt.aqi, tt.want, got)
+		}
+	}
+}
+
+// TestGetWeatherStaleWhileRevalidate は天気のキャッシュ有効期限と stale-while-revalidate の期間のテストなのです。
+func TestGetWeatherStaleWhileRevalidate(t *testing.T) {
+	// 省略時は5分、期限切れのあとも同じ時間だけ古い天気を返す
+	defaults := NewClient(cache.New(t.TempDir()), "http://localhost:8080", Options{})
+	if defaults.weatherTTL != 5*time.Minute || defaults.weatherSWR != 5*time.Minute || defaults.airQualityTTL != time.Hour {
+		t.Fatalf("既定値: %v %v %v", defaults.weatherTTL, defaults.weatherSWR, defaults.airQualityTTL)
+	}
+
+	fc := cache.New(t.TempDir())
+	now := fixtureNow()
+	fc.SetClock(func() time.Time { return now })
+	c := NewClient(fc, "http://localhost:8080", Options{WeatherTTL: 10 * time.Minute, WeatherStaleWhileRevalidate: 30 * time.Minute})
+	provider := &fakeProvider{name: "fake", rsp: &models.WeatherResponse{Current: models.CurrentWeather{Temperature: 20}}}
+	c.SetProviders(provider)
+
+	ctx := context.Background()
+	get := func() float64 {
+		t.Helper()
+		weatherRsp, err := c.GetWeather(ctx, "姫路市", "JP")
+		if err != nil {
+			t.Fatalf("GetWeather: %v", err)
+		}
+		return weatherRsp.Current.Temperature
+	}
+
+	get()
+	now = now.Add(10 * time.Minute)
+	if get(); provider.calls != 1 {
+		t.Fatalf("有効期限内なのに取得しています: %d", provider.calls)
+	}
+
+	// 期限切れでも期間内は古い天気をすぐ返し、裏で取得し直す
+	provider.rsp = &models.WeatherResponse{Current: models.CurrentWeather{Temperature: 25}}
+	now = now.Add(time.Minute)
+	if got := get(); got != 20 {
+		t.Fatalf("古い天気を返しません: %.1f", got)
+	}
+	c.revalidateWG.Wait()
+	if provider.calls != 2 {
+		t.Fatalf("裏で取得していません: %d", provider.calls)
+	}
+	if got := get(); got != 25 || provider.calls != 2 {
+		t.Fatalf("更新後: %.1f (calls=%d)", got, provider.calls)
+	}
+
+	// 期間（有効期限＋30分）より古ければその場で取得する
+	provider.rsp = &models.WeatherResponse{Current: models.CurrentWeather{Temperature: 30}}
+	now = now.Add(41 * time.Minute)
+	if got := get(); got != 30 || provider.calls != 3 {
+		t.Fatalf("期間より古い: %.1f (calls=%d)", got, provider.calls)
+	}
+}
+
+// TestAirQualityCache は大気質・花粉を天気とは別のキー・有効期限でキャッシュするテストなのです。
+func TestAirQualityCache(t *testing.T) {
+	var calls atomic.Int32
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if fail.Load() || r.URL.Path != "/v1/air-quality" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(loadFixture(t, "openmeteo_air_quality.json", nil))
+	}))
+	defer server.Close()
+
+	fc := cache.New(t.TempDir())
+	c := NewClient(fc, "http://localhost:8080", Options{AirQualityTTL: time.Hour})
+	c.airQualityURL = server.URL + "/v1"
+
+	ctx := context.Background()
+	first, err := c.GetAirQuality(ctx, "姫路市", "JP")
+	if err != nil || first.AQI != 64 {
+		t.Fatalf("GetAirQuality: %+v, %v", first, err)
+	}
+	// 有効期限内はキャッシュを返す
+	if _, err := c.GetAirQuality(ctx, "姫路市", "JP"); err != nil || calls.Load() != 1 {
+		t.Fatalf("キャッシュが使われません: calls=%d err=%v", calls.Load(), err)
+	}
+	entry, found, _, err := fc.Read(AirQualityCacheKey("姫路市", "JP"), 0)
+	if err != nil || !found || entry.Meta["source"] != "openmeteo-air-quality" {
+		t.Errorf("キャッシュ: %v (found=%v err=%v)", entry.Meta, found, err)
+	}
+	if _, found, _, _ := fc.Read("weather:JP:姫路市", 0); found {
+		t.Errorf("天気のキャッシュに書き込まれています")
+	}
+
+	// 取得に失敗したら期限切れのキャッシュをエラーと一緒に返す
+	fail.Store(true)
+	stale, err := c.RefreshAirQuality(ctx, "姫路市", "JP")
+	if err == nil || stale == nil || stale.AQI != 64 {
+		t.Errorf("RefreshAirQuality 失敗時: %+v, %v", stale, err)
+	}
+}
+
+// TestHistoryStore は観測値の追記・日ごとの集計・古い記録の削除のテストなのです。
+func TestHistoryStore(t *testing.T) {
+	loc := tokyoLocation()
+	dir := t.TempDir()
+	store := NewHistoryStore(dir, 3)
+	now := time.Date(2025, 7, 15, 16, 30, 0, 0, loc)
+	store.now = func() time.Time { return now }
+
+	at := func(day, hour, minute int) string {
+		return time.Date(2025, 7, day, hour, minute, 0, 0, loc).Format(time.RFC3339)
+	}
+	observations := []Observation{
+		{Time: at(11, 12, 0), Temperature: 30}, // 保持期間（3日）より古い
+		{Time: at(14, 6, 0), Temperature: 22.5, PrecipHour: at(14, 6, 0), PrecipAmount: 1.0},
+		{Time: at(14, 6, 30), Temperature: 23.0, PrecipHour: at(14, 6, 0), PrecipAmount: 1.5}, // 同じ時間帯は最後の記録を使う
+		{Time: at(14, 14, 0), Temperature: 31.2, PrecipHour: at(14, 14, 0), PrecipAmount: 0.4},
+		{Time: at(15, 9, 0), Temperature: 27.0},
+	}
+	for _, obs := range observations {
+		if err := store.Append("姫路市", "JP", obs); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	// 最初の追記で古い記録を削除しているので、7日分を求めても3日以内だけ
+	daily, err := store.Daily("姫路市", "JP", 7)
+	if err != nil {
+		t.Fatalf("Daily: %v", err)
+	}
+	want := []models.WeatherHistoryDay{
+		{Date: "2025-07-14", MaxTemp: 31.2, MinTemp: 22.5, Precipitation: 1.9, Samples: 3},
+		{Date: "2025-07-15", MaxTemp: 27.0, MinTemp: 27.0, Precipitation: 0, Samples: 1},
+	}
+	if !reflect.DeepEqual(daily, want) {
+		t.Errorf("Daily: 期待: %+v, 実際: %+v", want, daily)
+	}
+	if today, _ := store.Daily("姫路市", "JP", 1); len(today) != 1 || today[0].Date != "2025-07-15" {
+		t.Errorf("Daily(1): %+v", today)
+	}
+
+	// 別の地点は別のファイル
+	if other, _ := store.Daily("松江市", "JP", 7); len(other) != 0 {
+		t.Errorf("別の地点の履歴: %+v", other)
+	}
+
+	// 1日たったら追記のついでに古い記録を削除する（壊れた行は読み飛ばす）
+	path := store.filePath("姫路市", "JP")
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, _ = file.WriteString("{broken\n")
+	_ = file.Close()
+
+	now = now.Add(2 * 24 * time.Hour)
+	if err := store.Append("姫路市", "JP", Observation{Time: at(17, 16, 30), Temperature: 29}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("削除後の行数: 期待: 2, 実際: %d\n%s", lines, data)
+	}
+}
+
+// TestRefreshWeatherRecordsHistory は取得に成功した天気だけを履歴に記録するテストなのです。
+func TestRefreshWeatherRecordsHistory(t *testing.T) {
+	now := fixtureNow()
+	c := NewClient(cache.New(t.TempDir()), "http://localhost:8080", Options{})
+	c.now = func() time.Time { return now }
+	store := NewHistoryStore(t.TempDir(), 90)
+	store.now = c.now
+	c.SetHistory(store)
+
+	hourly := []models.HourlyWeather{
+		{Time: now.Truncate(time.Hour).Format(time.RFC3339), PrecipAmount: 0.8},
+		{Time: now.Truncate(time.Hour).Add(time.Hour).Format(time.RFC3339), PrecipAmount: 3.0},
+	}
+	provider := &fakeProvider{name: "fake", rsp: &models.WeatherResponse{
+		Current: models.CurrentWeather{Temperature: 28.4, Humidity: 70, Condition: "あめ"},
+		Hourly:  hourly,
+	}}
+	c.SetProviders(provider)
+
+	ctx := context.Background()
+	if _, err := c.RefreshWeather(ctx, "姫路市", "JP"); err != nil {
+		t.Fatalf("RefreshWeather: %v", err)
+	}
+	provider.err = errors.New("unavailable")
+	if _, err := c.RefreshWeather(ctx, "姫路市", "JP"); err == nil {
+		t.Fatalf("失敗したのにエラーになりません")
+	}
+
+	observations, err := store.Observations("姫路市", "JP", time.Time{})
+	if err != nil {
+		t.Fatalf("Observations: %v", err)
+	}
+	want := Observation{
+		Time:         now.Format(time.RFC3339),
+		Temperature:  28.4,
+		Humidity:     70,
+		Condition:    "あめ",
+		PrecipHour:   hourly[0].Time,
+		PrecipAmount: 0.8,
+		Source:       "fake",
+	}
+	if len(observations) != 1 || observations[0] != want {
+		t.Errorf("期待: [%+v], 実際: %+v", want, observations)
+	}
+}
+
+// TestCompareWithYesterday は今日の予報と昨日の記録の比較のテストなのです。
+func TestCompareWithYesterday(t *testing.T) {
+	now := fixtureNow()
+	history := []models.WeatherHistoryDay{
+		{Date: "2025-07-13", MaxTemp: 35, MinTemp: 26},
+		{Date: "2025-07-14", MaxTemp: 30.5, MinTemp: 24},
+	}
+
+	tests := []struct {
+		name    string
+		today   models.TodayWeather
+		message string
+		delta   float64
+	}{
+		{name: "高い", today: models.TodayWeather{MaxTemp: 33.6, MinTemp: 25}, message: "昨日より3℃高い", delta: 3.1},
+		{name: "低い", today: models.TodayWeather{MaxTemp: 28.4, MinTemp: 22}, message: "昨日より2℃低い", delta: -2.1},
+		{name: "同じくらい", today: models.TodayWeather{MaxTemp: 30.9, MinTemp: 24}, message: "昨日と同じくらい", delta: 0.4},
+	}
+	kanji := NewLocalizer(config.LocaleJaKanji, config.Units{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trend := CompareWithYesterday(history, tt.today, kanji, now)
+			if trend == nil || trend.Message != tt.message || trend.MaxTempDelta != tt.delta {
+				t.Errorf("期待: %s (%.1f), 実際: %+v", tt.message, tt.delta, trend)
+			}
+		})
+	}
+
+	// 昨日の記録が無ければ比較しない
+	if trend := CompareWithYesterday(history[:1], models.TodayWeather{MaxTemp: 30}, kanji, now); trend != nil {
+		t.Errorf("昨日の記録が無いのに比較しています: %+v", trend)
+	}
+
+	// 差と文言は表示言語と単位に合わせる（3.1℃ の差は 5.6℉）
+	trend := CompareWithYesterday(history, tests[0].today, NewLocalizer(config.LocaleEn, config.Units{System: config.UnitsImperial}), now)
+	if trend == nil || trend.Message != "6℉ warmer than yesterday" || trend.MaxTempDelta != 5.6 {
+		t.Errorf("imperial・en の比較: %+v", trend)
 	}
 }
 
@@ -125,3 +997,146 @@ func TestWeatherCodeToIcon(t *testing.T) {
 		}
 	}
 }
+
+// TestLocalizer は単位の変換と表示言語への置き換えのテストなのです。
+func TestLocalizer(t *testing.T) {
+	newResponse := func() *models.WeatherResponse {
+		return &models.WeatherResponse{
+			Current: models.CurrentWeather{Temperature: 30, FeelsLike: 35, Condition: "はれ", WindSpeed: 10, WindGust: 15},
+			Today:   models.TodayWeather{MaxTemp: 33, MinTemp: -5, Summary: "あめ"},
+			Hourly:  []models.HourlyWeather{{Temperature: 20, PrecipAmount: 25.4, WindSpeed: 5, Condition: "らいう"}},
+			Weekly:  []models.WeeklyWeather{{MaxTemp: 0, MinTemp: 100, Condition: "くもり"}},
+			AirQuality: &models.AirQuality{
+				AQI:      120,
+				Category: "びんかんなひとはちゅうい",
+				Pollen:   []models.PollenLevel{{Type: "grass", Name: "イネ科", Value: 12.3, Level: "ややおおい"}},
+			},
+		}
+	}
+
+	t.Run("既定はひらがな・メートル法で値を変えない", func(t *testing.T) {
+		weatherRsp := newResponse()
+		NewLocalizer("", config.Units{}).Localize(weatherRsp)
+		if weatherRsp.Lang != config.LocaleJaHiragana || weatherRsp.Units != (models.WeatherUnits{Temperature: "℃", WindSpeed: "m/s", Precipitation: "mm"}) {
+			t.Errorf("言語・単位: %s %+v", weatherRsp.Lang, weatherRsp.Units)
+		}
+		if weatherRsp.Current.Temperature != 30 || weatherRsp.Current.WindSpeed != 10 || weatherRsp.Hourly[0].PrecipAmount != 25.4 {
+			t.Errorf("値が変わっています: %+v", weatherRsp)
+		}
+		if weatherRsp.Current.Condition != "はれ" || weatherRsp.AirQuality.Category != "びんかんなひとはちゅうい" {
+			t.Errorf("文言が変わっています: %+v", weatherRsp)
+		}
+	})
+
+	t.Run("英語・ヤードポンド法", func(t *testing.T) {
+		weatherRsp := newResponse()
+		original := weatherRsp.AirQuality
+		NewLocalizer(config.LocaleEn, config.Units{System: config.UnitsImperial}).Localize(weatherRsp)
+		if weatherRsp.Units != (models.WeatherUnits{Temperature: "℉", WindSpeed: "mph", Precipitation: "in"}) {
+			t.Errorf("単位: %+v", weatherRsp.Units)
+		}
+		current := weatherRsp.Current
+		if current.Temperature != 86 || current.FeelsLike != 95 || current.WindSpeed != 22.4 || current.WindGust != 33.6 || current.Condition != "Clear" {
+			t.Errorf("現在の天気: %+v", current)
+		}
+		if weatherRsp.Today.MaxTemp != 91.4 || weatherRsp.Today.MinTemp != 23 || weatherRsp.Today.Summary != "Rain" {
+			t.Errorf("今日: %+v", weatherRsp.Today)
+		}
+		if hour := weatherRsp.Hourly[0]; hour.Temperature != 68 || hour.PrecipAmount != 1 || hour.WindSpeed != 11.2 || hour.Condition != "Thunderstorm" {
+			t.Errorf("時間ごと: %+v", hour)
+		}
+		if day := weatherRsp.Weekly[0]; day.MaxTemp != 32 || day.MinTemp != 212 || day.Condition != "Cloudy" {
+			t.Errorf("週間: %+v", day)
+		}
+		pollen := weatherRsp.AirQuality.Pollen[0]
+		if weatherRsp.AirQuality.Category != "Unhealthy for sensitive groups" || pollen.Name != "Grass" || pollen.Level != "Moderate" {
+			t.Errorf("大気質: %+v", weatherRsp.AirQuality)
+		}
+		if original.Category != "びんかんなひとはちゅうい" {
+			t.Errorf("元の大気質を書き換えています: %+v", original)
+		}
+	})
+
+	t.Run("漢字・km/h・知らない文言はそのまま", func(t *testing.T) {
+		weatherRsp := newResponse()
+		weatherRsp.Current.Condition = "晴"
+		weatherRsp.Today.Summary = ConditionUnavailable
+		NewLocalizer(config.LocaleJaKanji, config.Units{WindSpeed: config.WindSpeedKMH}).Localize(weatherRsp)
+		if weatherRsp.Units.WindSpeed != "km/h" || weatherRsp.Current.WindSpeed != 36 || weatherRsp.Current.Temperature != 30 {
+			t.Errorf("単位: %+v %+v", weatherRsp.Units, weatherRsp.Current)
+		}
+		if weatherRsp.Current.Condition != "晴" || weatherRsp.Today.Summary != ConditionUnavailable || weatherRsp.Hourly[0].Condition != "雷雨" {
+			t.Errorf("文言: %+v", weatherRsp)
+		}
+	})
+
+	t.Run("アドバイスの理由は表示する単位", func(t *testing.T) {
+		weatherRsp := &models.WeatherResponse{
+			Current: models.CurrentWeather{Temperature: 20, FeelsLike: 20, WindSpeed: 12},
+			Today:   models.TodayWeather{MaxTemp: 35, MinTemp: 25},
+		}
+		got := BuildAdvice(weatherRsp, config.Advice{}, NewLocalizer(config.LocaleEn, config.Units{System: config.UnitsImperial}), fixtureNow())
+		want := []models.Advice{
+			{Type: config.AdviceHeat, Message: "Watch out for heatstroke", Reason: "High of 95℉", Level: AdviceLevelWarning},
+			{Type: config.AdviceWind, Message: "Strong winds", Reason: "Wind 27mph", Level: AdviceLevelWarning},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("期待: %+v, 実際: %+v", want, got)
+		}
+	})
+}
+
+// fakeGeocoder はテスト用のジオコーダーなのです。
+type fakeGeocoder struct {
+	calls     int
+	locations map[string]*geocode.Location
+}
+
+func (g *fakeGeocoder) GetCoordinates(ctx context.Context, cityName, country string) (*geocode.Location, error) {
+	g.calls++
+	if location, ok := g.locations[cityName]; ok {
+		return location, nil
+	}
+	return nil, errors.New("no results")
+}
+
+// TestGetCoordinates は座標解決の優先順位テストなのです。
+func TestGetCoordinates(t *testing.T) {
+	geocoder := &fakeGeocoder{locations: map[string]*geocode.Location{
+		"松江市": {Latitude: 35.4681, Longitude: 133.0484, CityName: "松江市", Country: "JP"},
+	}}
+	c := NewClient(cache.New(t.TempDir()), "http://localhost:8080", Options{})
+	c.geocoder = geocoder
+	ctx := context.Background()
+
+	// ハードコードの都市はジオコーディングしない
+	coords, err := c.getCoordinates(ctx, "姫路市", "JP")
+	if err != nil || coords.Latitude != 34.815353 {
+		t.Fatalf("姫路市: coords=%v err=%v", coords, err)
+	}
+	if geocoder.calls != 0 {
+		t.Fatalf("geocoder calls = %d, want 0", geocoder.calls)
+	}
+
+	// 未知の都市はジオコーディングし、結果を覚えておく
+	for i := 0; i < 2; i++ {
+		coords, err = c.getCoordinates(ctx, "松江市", "JP")
+		if err != nil || coords.Latitude != 35.4681 || coords.Longitude != 133.0484 {
+			t.Fatalf("松江市: coords=%v err=%v", coords, err)
+		}
+	}
+	if geocoder.calls != 1 {
+		t.Fatalf("geocoder calls = %d, want 1", geocoder.calls)
+	}
+
+	// 明示設定はハードコードより優先する
+	c.SetCoordinates("姫路市", "JP", 34.9, 134.6)
+	coords, err = c.getCoordinates(ctx, "姫路市", "JP")
+	if err != nil || coords.Latitude != 34.9 || coords.Longitude != 134.6 {
+		t.Fatalf("override: coords=%v err=%v", coords, err)
+	}
+
+	if _, err := c.getCoordinates(ctx, "ぜったいないまち", "JP"); err == nil {
+		t.Fatalf("未知の都市でエラーになりません")
+	}
+}